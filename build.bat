@@ -1,10 +1,4 @@
 echo on
 
 
-go build cmd/dataPrep/dataPrep.go
-
-go build cmd/drive/drive.go
-
-go build cmd/youtube/youtube.go
-
-
+go build ./cmd/ytmgr
@@ -1,7 +1,3 @@
 #!/bin/bash
 
-go build cmd/dataPrep/dataPrep.go
-
-go build cmd/drive/drive.go
-
-go build cmd/youtube/youtube.go
+go build ./cmd/ytmgr
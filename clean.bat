@@ -1,15 +1,6 @@
 echo on
 
 
-IF EXIST dataPrep.exe (
-	del dataPrep.exe 
+IF EXIST ytmgr.exe (
+	del ytmgr.exe 
 )
-
-IF EXIST drive.exe (
-	del drive.exe 
-)
-
-IF EXIST youtube.exe (
-	del youtube.exe 
-)
-
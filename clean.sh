@@ -1,3 +1,3 @@
 #!/bin/bash
 
-rm dataPrep drive youtube
+rm ytmgr
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	drapi "twsati/internal/google/drive"
+	ytapi "twsati/internal/google/youtube"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// errNotConfirmed is returned for deletes that were not confirmed.
+var errNotConfirmed = errors.New("delete not confirmed")
+
+// confirmInput is where answers to confirmations are read; tests replace
+// it.
+var confirmInput = bufio.NewReader(os.Stdin)
+
+// confirmMu keeps concurrent batch workers from asking at the same time.
+var confirmMu sync.Mutex
+
+// confirm asks a yes/no question, taking anything but y or yes, including
+// the end of the input, as no.
+func confirm(question string) (bool, error) {
+	confirmMu.Lock()
+	defer confirmMu.Unlock()
+	fmt.Printf("%s [y/N] ", question)
+	answer, err := confirmInput.ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Println()
+		return false, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
+
+// snapshot is what the archive keeps of a deleted video.
+type snapshot struct {
+	ArchivedAt time.Time
+	Video      *youtube.Video
+	Captions   []*youtube.Caption
+	// NotArchived maps the ids of the tracks whose content could not be
+	// downloaded, like ones the channel does not own, to the reason.
+	NotArchived map[string]string `json:",omitempty"`
+}
+
+// snapshotFiles fetches the video resource, its statistics and every
+// caption track, named after the video id. Tracks generated by speech
+// recognition cannot be downloaded and are skipped; other tracks that
+// fail are recorded in the snapshot unless the quota is exhausted.
+func snapshotFiles(videoId string) ([]drapi.ArchiveFile, *snapshot, error) {
+	video, err := yt.VideoSnapshot(videoId)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := yt.ListCaption(videoId)
+	if err != nil {
+		return nil, nil, err
+	}
+	snap := &snapshot{ArchivedAt: time.Now().UTC(), Video: video, Captions: resp.Items}
+	var files []drapi.ArchiveFile
+	for _, c := range resp.Items {
+		if c.Snippet.TrackKind == "asr" {
+			continue
+		}
+		data, err := yt.DownloadCaption(c.Id)
+		if errors.Is(err, ytapi.ErrQuotaExceeded) {
+			return nil, nil, err
+		} else if err != nil {
+			if snap.NotArchived == nil {
+				snap.NotArchived = make(map[string]string)
+			}
+			snap.NotArchived[c.Id] = err.Error()
+			continue
+		}
+		files = append(files, drapi.ArchiveFile{Name: fmt.Sprintf("%s.%s.%s.srt", videoId, c.Snippet.Language, c.Id), Content: data})
+	}
+	data, err := json.MarshalIndent(snap, "", "   ")
+	if err != nil {
+		return nil, nil, err
+	}
+	files = append([]drapi.ArchiveFile{{Name: videoId + ".json", Content: data}}, files...)
+	return files, snap, nil
+}
+
+func describeSnapshot(snap *snapshot) string {
+	desc := fmt.Sprintf("%d caption track(s)", len(snap.Captions))
+	if n := len(snap.NotArchived); n > 0 {
+		desc += fmt.Sprintf(", %d not archived", n)
+	}
+	if stats := snap.Video.Statistics; stats != nil {
+		desc = fmt.Sprintf("%d views, %d likes, %d comments, ", stats.ViewCount, stats.LikeCount, stats.CommentCount) + desc
+	}
+	return desc
+}
+
+// deleteVideo archives a snapshot of the folder's video in its archive
+// subfolder, asks before deleting the video unless yes is set, and keeps
+// the old id on the folder as a tombstone.
+func deleteVideo(vmeta *drapi.VideoMeta, yes bool, rec *auditRun) error {
+	videoId := *vmeta.VideoId
+	files, snap, err := snapshotFiles(videoId)
+	if err != nil {
+		return fmt.Errorf("%s: snapshot video %s: %w", vmeta.FolderName(), videoId, err)
+	}
+	if !yes && !activePlan.Active() {
+		title := ""
+		if snap.Video.Snippet != nil {
+			title = snap.Video.Snippet.Title
+		}
+		question := fmt.Sprintf("delete video %s %q of %s (%s)?", videoId, title, vmeta.FolderName(), describeSnapshot(snap))
+		ok, err := confirm(question)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%s: %w, pass -yes to delete without asking", vmeta.FolderName(), errNotConfirmed)
+		}
+	}
+	if err := drv.Archive(vmeta, files); err != nil {
+		return err
+	}
+	if err := yt.DeleteVideo(videoId); err != nil {
+		return err
+	}
+	rec.id("deletedVideoId", videoId)
+
+	vmeta.Tombstone(videoId)
+	setSptr(&vmeta.VideoId, "")
+	setSptr(&vmeta.CaptionId, "")
+	vmeta.ResetCaptions()
+	setSptr(&vmeta.Privacy, "")
+	setSptr(&vmeta.Scheduled, "")
+	vmeta.ResetRelease()
+	vmeta.ResetPushed()
+	return drv.UpdateVideoMeta(vmeta)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"twsati/internal/audit"
+	drapi "twsati/internal/google/drive"
+)
+
+// auditRun collects the audit entry of one operation on a folder.
+type auditRun struct {
+	vmeta *drapi.VideoMeta
+	entry *audit.Entry
+}
+
+// beginAudit snapshots the folder metadata before op, as app properties
+// and as a whole; defer end with the operation's error to append the
+// entry.
+func beginAudit(vmeta *drapi.VideoMeta, op string) *auditRun {
+	entry := audit.New(op)
+	entry.Before = vmeta.Properties()
+	entry.BeforeMeta = audit.Snapshot(vmeta)
+	return &auditRun{vmeta: vmeta, entry: entry}
+}
+
+// id records an id the API returned or acted on.
+func (r *auditRun) id(key, value string) {
+	r.entry.Ids[key] = value
+}
+
+// end appends the entry. The operation already happened, so a log that
+// cannot be written only earns a warning.
+func (r *auditRun) end(errp *error) {
+	if *errp != nil {
+		r.entry.Error = (*errp).Error()
+	}
+	r.entry.After = r.vmeta.Properties()
+	r.entry.AfterMeta = audit.Snapshot(r.vmeta)
+	if err := drv.AppendAudit(r.vmeta, r.entry); err != nil {
+		fmt.Fprintf(os.Stderr, "audit log of %s: %v\n", r.vmeta.FolderName(), err)
+	}
+}
+
+// showAudit prints the folder's audit log, oldest first. Each folder is
+// printed in one piece so parallel workers do not interleave.
+func showAudit(name string) error {
+	vmeta, err := drv.GetVideoMeta(name)
+	if err != nil {
+		return err
+	}
+	defer vmeta.CleanUp()
+	entries, err := drv.ReadAudit(vmeta)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Printf("%s: no audit entries\n", name)
+		return nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s:\n", name)
+	for _, e := range entries {
+		who := e.User
+		if e.Host != "" {
+			who += "@" + e.Host
+		}
+		fmt.Fprintf(&b, "  %s  %s  %s\n", e.Time.Local().Format(time.RFC3339), who, e.Op)
+		for _, c := range e.Changes() {
+			fmt.Fprintf(&b, "      %s: %s -> %s\n", c.Key, orNone(c.Before), orNone(c.After))
+		}
+		var keys []string
+		for k := range e.Ids {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(&b, "      %s %s\n", k, e.Ids[k])
+		}
+		if e.Error != "" {
+			fmt.Fprintf(&b, "      failed: %s\n", strings.TrimSpace(e.Error))
+		}
+	}
+	fmt.Print(b.String())
+	return nil
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	drapi "twsati/internal/google/drive"
+	ytapi "twsati/internal/google/youtube"
+)
+
+// errSkipped marks the names a batch did not get to.
+var errSkipped = errors.New("skipped")
+
+// batchError reports the failures of a batch; it unwraps to the first one
+// so the exit code follows it.
+type batchError struct {
+	failed, total int
+	first         error
+}
+
+func (e *batchError) Error() string {
+	return fmt.Sprintf("%d of %d failed, first: %v", e.failed, e.total, e.first)
+}
+
+func (e *batchError) Unwrap() error {
+	return e.first
+}
+
+// forEach runs fn for every name on up to workers goroutines. A failure
+// does not stop the other names, except that once the YouTube quota is
+// exhausted the names not yet started are skipped. With more than one
+// name a per-name summary is printed.
+func forEach(names []string, fn func(name string) error) error {
+	if len(names) == 1 {
+		return fn(names[0])
+	}
+	n := workers
+	if n < 1 {
+		n = 1
+	}
+	results := make([]error, len(names))
+	jobs := make(chan int)
+	var mu sync.Mutex
+	quotaGone := false
+	var wg sync.WaitGroup
+	for w := 0; w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				mu.Lock()
+				skip := quotaGone
+				mu.Unlock()
+				if skip {
+					results[i] = errSkipped
+					continue
+				}
+				err := fn(names[i])
+				if errors.Is(err, ytapi.ErrQuotaExceeded) {
+					mu.Lock()
+					quotaGone = true
+					mu.Unlock()
+				}
+				results[i] = err
+			}
+		}()
+	}
+	for i := range names {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return summarize(os.Stdout, names, results)
+}
+
+// summarize prints one line per name and returns the batch error, if any.
+func summarize(w io.Writer, names []string, results []error) error {
+	var berr *batchError
+	ok, skipped := 0, 0
+	for _, err := range results {
+		switch {
+		case err == nil:
+			ok++
+		case errors.Is(err, errSkipped):
+			skipped++
+		default:
+			if berr == nil {
+				berr = &batchError{total: len(names), first: err}
+			}
+			berr.failed++
+		}
+	}
+	fmt.Fprintf(w, "\nsummary: %d ok, %d failed, %d skipped\n", ok, len(names)-ok-skipped, skipped)
+	for i, err := range results {
+		switch {
+		case err == nil:
+			fmt.Fprintf(w, "  ok    %s\n", names[i])
+		case errors.Is(err, errSkipped):
+			fmt.Fprintf(w, "  skip  %s\n", names[i])
+		default:
+			fmt.Fprintf(w, "  FAIL  %s: %v\n", names[i], err)
+		}
+	}
+	if berr == nil {
+		return nil
+	}
+	return berr
+}
+
+// expandNames replaces the name patterns in args by the matching Drive
+// folder names and drops repeated names.
+func expandNames(args []string) ([]string, error) {
+	var names []string
+	seen := map[string]bool{}
+	for _, arg := range args {
+		matches := []string{arg}
+		if drapi.IsPattern(arg) {
+			var err error
+			if matches, err = findFolders(arg); err != nil {
+				return nil, err
+			}
+			if len(matches) == 0 {
+				return nil, &drapi.FolderError{Name: arg, Err: drapi.ErrFolderNotFound}
+			}
+		}
+		for _, name := range matches {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names, nil
+}
+
+// readManifest reads one name per line from path, or standard input for
+// "-", skipping blank lines and # comments.
+func readManifest(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errUsage, err)
+		}
+		defer f.Close()
+		r = f
+	}
+	var names []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			names = append(names, line)
+		}
+	}
+	return names, scanner.Err()
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"twsati/internal/bigfive"
+	drapi "twsati/internal/google/drive"
+	ytapi "twsati/internal/google/youtube"
+	"twsati/internal/subtitle"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// extraTracks lists the languages uploaded besides the main track: one
+// per <name>.<lang> caption file and the simplified track converted from
+// the main one.
+func extraTracks(vmeta *drapi.VideoMeta) []string {
+	var langs []string
+	for _, lang := range vmeta.Tracks() {
+		if lang != cfg.Caption.Language {
+			langs = append(langs, lang)
+		}
+	}
+	simplified := cfg.Caption.Simplified
+	if simplified != "" && vmeta.HasCaption() && !hasLang(langs, simplified) {
+		langs = append(langs, simplified)
+		sort.Strings(langs)
+	}
+	return langs
+}
+
+func hasLang(langs []string, lang string) bool {
+	for _, l := range langs {
+		if l == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// converted reports whether the track in lang is converted from the main
+// track rather than uploaded from a file of its own.
+func converted(vmeta *drapi.VideoMeta, lang string) bool {
+	return lang == cfg.Caption.Simplified && !hasLang(vmeta.Tracks(), lang)
+}
+
+// trackChecksum is the checksum of the file the track in lang is made
+// from.
+func trackChecksum(vmeta *drapi.VideoMeta, lang string) string {
+	if converted(vmeta, lang) {
+		return vmeta.Checksum(drapi.CAPTION_FILE)
+	}
+	return vmeta.Checksum(drapi.TrackKind(lang))
+}
+
+// trackId is the caption id recorded for the track in lang.
+func trackId(vmeta *drapi.VideoMeta, lang string) string {
+	if lang == cfg.Caption.Language {
+		return deref(vmeta.CaptionId)
+	}
+	return vmeta.Captions[lang]
+}
+
+// uploadCaptions uploads the main track, linted first, and the extra
+// tracks; a non-empty lang uploads only that one. Tracks whose file did
+// not change since it was pushed are skipped unless force is set.
+func uploadCaptions(vmeta *drapi.VideoMeta, lang string, force bool) error {
+	found := false
+	if (lang == "" || lang == cfg.Caption.Language) && vmeta.HasCaption() {
+		found = true
+		if err := uploadMainCaption(vmeta, force); err != nil {
+			return err
+		}
+	}
+	for _, l := range extraTracks(vmeta) {
+		if lang != "" && l != lang {
+			continue
+		}
+		found = true
+		sum := trackChecksum(vmeta, l)
+		if !force && trackId(vmeta, l) != "" && sum != "" && vmeta.Pushed[drapi.TrackKind(l)] == sum {
+			fmt.Printf("%s: %s caption unchanged since it was pushed, skipped (-force pushes it anyway)\n", vmeta.FolderName(), l)
+			continue
+		}
+		if err := captionTrack(vmeta, l); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return fmt.Errorf("%s: no %s caption file", vmeta.FolderName(), orAll(lang))
+	}
+	return nil
+}
+
+func orAll(lang string) string {
+	if lang == "" {
+		return "main or language"
+	}
+	return lang
+}
+
+func uploadMainCaption(vmeta *drapi.VideoMeta, force bool) error {
+	if !force && deref(vmeta.CaptionId) != "" && skipUnchanged(vmeta, drapi.CAPTION_FILE) {
+		return nil
+	}
+	if err := checkLint(vmeta, force); err != nil {
+		return err
+	}
+	return uploadCaption(vmeta)
+}
+
+// captionTrack uploads the extra track in lang, replacing the one
+// recorded on the folder.
+func captionTrack(vmeta *drapi.VideoMeta, lang string) (err error) {
+	rec := beginAudit(vmeta, "caption "+lang)
+	defer rec.end(&err)
+	if !hasVideo(vmeta) {
+		return fmt.Errorf("%s: %w", vmeta.FolderName(), errNoVideo)
+	}
+	var path string
+	if converted(vmeta, lang) {
+		path, err = simplifiedCaption(vmeta, lang)
+	} else {
+		path, err = vmeta.TrackPath(lang)
+	}
+	if err != nil {
+		return err
+	}
+	captionId, err := yt.UploadCaption(vmeta.Captions[lang], *vmeta.VideoId, lang, cfg.Caption.TrackName(lang), path)
+	if err != nil {
+		return err
+	}
+	rec.id("captionId "+lang, captionId)
+	if vmeta.Captions == nil {
+		vmeta.Captions = make(map[string]string)
+	}
+	vmeta.Captions[lang] = captionId
+	if sum := trackChecksum(vmeta, lang); sum != "" {
+		if vmeta.Pushed == nil {
+			vmeta.Pushed = make(map[string]string)
+		}
+		vmeta.Pushed[drapi.TrackKind(lang)] = sum
+	}
+	fmt.Printf("updated youtube video %s caption id: %s\n", lang, captionId)
+	return drv.UpdateVideoMeta(vmeta)
+}
+
+// simplifiedCaption converts the main caption to simplified Chinese with
+// OpenCC and returns the SubRip file it wrote.
+func simplifiedCaption(vmeta *drapi.VideoMeta, lang string) (string, error) {
+	name := vmeta.CaptionFile()
+	format, err := subtitle.FormatOf(name)
+	if err != nil {
+		return "", err
+	}
+	content, err := vmeta.CaptionContent()
+	if err != nil {
+		return "", err
+	}
+	cues, err := subtitle.Parse(format, bytes.NewReader(content))
+	if err != nil {
+		return "", fmt.Errorf("%s: %s: %w", vmeta.FolderName(), name, err)
+	}
+	for i := range cues {
+		for j, line := range cues[i].Lines {
+			if cues[i].Lines[j], err = bigfive.ToSimplified(line); err != nil {
+				return "", fmt.Errorf("%s: %s: %w", vmeta.FolderName(), name, err)
+			}
+		}
+	}
+	var out bytes.Buffer
+	if err := subtitle.WriteSRT(&out, cues); err != nil {
+		return "", err
+	}
+	return vmeta.WriteTemp(strings.TrimSuffix(name, filepath.Ext(name))+"."+lang+".srt", out.Bytes())
+}
+
+// captionEvery uploads every caption track of the folder, as release does.
+func captionEvery(vmeta *drapi.VideoMeta) error {
+	if err := captionVideo(vmeta); err != nil {
+		return err
+	}
+	for _, lang := range extraTracks(vmeta) {
+		if err := captionTrack(vmeta, lang); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteTrack deletes the caption track in lang recorded on the folder.
+func deleteTrack(vmeta *drapi.VideoMeta, lang string) (err error) {
+	rec := beginAudit(vmeta, "delete caption "+lang)
+	defer rec.end(&err)
+	captionId := trackId(vmeta, lang)
+	if captionId == "" {
+		return fmt.Errorf("%s: no %s caption track recorded", vmeta.FolderName(), lang)
+	}
+	if _, err := pullCaptions(vmeta, func(c *youtube.Caption) bool { return c.Id == captionId }); err != nil {
+		return err
+	}
+	if err := yt.DeleteCaption(captionId); err != nil {
+		return err
+	}
+	rec.id("deletedCaptionId "+lang, captionId)
+	kind := drapi.TrackKind(lang)
+	if lang == cfg.Caption.Language {
+		setSptr(&vmeta.CaptionId, "")
+		kind = drapi.CAPTION_FILE
+	} else {
+		vmeta.Captions[lang] = ""
+	}
+	if vmeta.Pushed[kind] != "" {
+		vmeta.Pushed[kind] = ""
+	}
+	if !activePlan.Active() {
+		fmt.Printf("successfully deleted youtube video caption %s id: %s  for video %s\n", lang, captionId, vmeta.Title)
+	}
+	return drv.UpdateVideoMeta(vmeta)
+}
+
+// pullCaptions saves the SubRip text of the video's caption tracks that
+// keep selects into the folder, so edits made in YouTube Studio survive
+// uploads and deletes; it returns the names of the saved files. Tracks
+// generated by speech recognition cannot be downloaded and are skipped;
+// other tracks that fail, like ones the channel does not own, are listed
+// and left out unless the quota is exhausted.
+func pullCaptions(vmeta *drapi.VideoMeta, keep func(*youtube.Caption) bool) ([]string, error) {
+	if !hasVideo(vmeta) {
+		return nil, fmt.Errorf("%s: %w", vmeta.FolderName(), errNoVideo)
+	}
+	resp, err := yt.ListCaption(*vmeta.VideoId)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var names []string
+	for _, item := range resp.Items {
+		if !keep(item) || item.Snippet.TrackKind == "asr" {
+			continue
+		}
+		data, err := yt.DownloadCaption(item.Id)
+		if errors.Is(err, ytapi.ErrQuotaExceeded) {
+			return names, fmt.Errorf("%s: pull caption %s: %w", vmeta.FolderName(), item.Id, err)
+		} else if err != nil {
+			fmt.Printf("%s: could not pull %s caption %s: %v\n", vmeta.FolderName(), item.Snippet.Language, item.Id, err)
+			continue
+		}
+		name := drapi.PulledName(vmeta.FolderName(), strings.ToLower(item.Snippet.Language), now)
+		if err := drv.SaveFile(vmeta, name, data); err != nil {
+			return names, err
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// youtubeCaptionPull saves the folder's caption tracks from YouTube, or
+// only the one in lang when it is not empty.
+func youtubeCaptionPull(name, lang string) error {
+	vmeta, err := drv.GetVideoMeta(name)
+	if err != nil {
+		return err
+	}
+	defer vmeta.CleanUp()
+	names, err := pullCaptions(vmeta, func(c *youtube.Caption) bool {
+		return lang == "" || strings.EqualFold(c.Snippet.Language, lang)
+	})
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		fmt.Printf("%s: no %s caption track on YouTube\n", name, orAll(lang))
+		return nil
+	}
+	if !activePlan.Active() {
+		fmt.Printf("%s: saved %s\n", name, strings.Join(names, ", "))
+	}
+	return nil
+}
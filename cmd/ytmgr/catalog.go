@@ -0,0 +1,241 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"twsati/internal/catalog"
+	drapi "twsati/internal/google/drive"
+)
+
+// clipCatalog is the local catalog, opened by connect; nil when it is
+// disabled or could not be opened.
+var clipCatalog *catalog.Catalog
+
+// openCatalog opens the configured catalog on first use.
+func openCatalog() (*catalog.Catalog, error) {
+	if clipCatalog != nil || cfg.Catalog == "off" {
+		return clipCatalog, nil
+	}
+	path := cfg.Catalog
+	if path == "" {
+		var err error
+		if path, err = catalog.DefaultPath(); err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	clipCatalog, err = catalog.Open(path)
+	return clipCatalog, err
+}
+
+// cachedMeta returns the catalog entry of a folder synced within the
+// configured age, or nil.
+func cachedMeta(name string) *catalog.Entry {
+	if clipCatalog == nil {
+		return nil
+	}
+	entry, err := clipCatalog.Get(name)
+	if err != nil || time.Since(entry.SyncedAt) > cfg.MaxCatalogAge() {
+		return nil
+	}
+	return entry
+}
+
+// readMeta returns the folder metadata for commands that only read it:
+// from the catalog while the folder's entry is fresh, from Drive
+// otherwise.
+func readMeta(name string) (*drapi.VideoMeta, error) {
+	if entry := cachedMeta(name); entry != nil {
+		return entry.VideoMeta, nil
+	}
+	return drv.GetVideoMeta(name)
+}
+
+// findFolders lists the folder names matching pattern, from the catalog
+// when it is fresh.
+func findFolders(pattern string) ([]string, error) {
+	if clipCatalog == nil || !clipCatalog.Fresh(cfg.MaxCatalogAge()) {
+		return drv.FindFolders(pattern)
+	}
+	entries, err := clipCatalog.List(catalog.Query{})
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if drapi.MatchName(pattern, e.FolderName()) {
+			names = append(names, e.FolderName())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// requireCatalog is openCatalog for the catalog commands, which have
+// nothing to do without one.
+func requireCatalog() (*catalog.Catalog, error) {
+	c, err := openCatalog()
+	if err == nil && c == nil {
+		err = errors.New(`the catalog is disabled with "catalog": "off"`)
+	}
+	return c, err
+}
+
+func syncCatalog(full bool) error {
+	c, err := requireCatalog()
+	if err != nil {
+		return err
+	}
+	stats, err := c.Sync(drv, full)
+	if err != nil {
+		return err
+	}
+	kind := "incremental"
+	if stats.Full {
+		kind = "full"
+	}
+	fmt.Printf("%s sync: %d folder(s) updated, %d removed\n", kind, stats.Updated, stats.Removed)
+	return nil
+}
+
+func listCatalog(q catalog.Query) error {
+	c, err := requireCatalog()
+	if err != nil {
+		return err
+	}
+	entries, err := c.List(q)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		privacy := deref(e.Privacy)
+		if privacy == "" {
+			privacy = "-"
+		}
+		videoId := deref(e.VideoId)
+		if videoId == "" {
+			videoId = "-"
+		}
+		changed := ""
+		if kinds := e.Changed(); len(kinds) > 0 {
+			changed = "  (changed: " + strings.Join(kinds, ", ") + ")"
+		}
+		fmt.Printf("%s  %-8s  %-11s  %s%s\n", e.Date.Format("2006-01-02"), privacy, videoId, e.FolderName(), changed)
+	}
+	fmt.Printf("%d clip(s)\n", len(entries))
+	return nil
+}
+
+func catalogStatus() error {
+	c, err := requireCatalog()
+	if err != nil {
+		return err
+	}
+	fmt.Println("catalog:", c.Path())
+	last, err := c.LastSync()
+	if err != nil {
+		return err
+	}
+	if last.IsZero() {
+		fmt.Println("last sync: never, run `ytmgr catalog sync`")
+	} else {
+		state := "fresh"
+		if !c.Fresh(cfg.MaxCatalogAge()) {
+			state = "stale, commands read from Drive"
+		}
+		fmt.Printf("last sync: %s (%s)\n", last.Local().Format(time.RFC3339), state)
+	}
+	counts, err := c.Counts()
+	if err != nil {
+		return err
+	}
+	var keys []string
+	total := 0
+	for k, n := range counts {
+		keys = append(keys, k)
+		total += n
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		label := k
+		if label == "" {
+			label = "not uploaded"
+		}
+		fmt.Printf("  %-13s %d\n", label, counts[k])
+	}
+	fmt.Printf("  %-13s %d\n", "total", total)
+	return nil
+}
+
+// scheduledMetas lists the folders of private videos, from the catalog
+// when it is fresh.
+func scheduledMetas() ([]*drapi.VideoMeta, error) {
+	if clipCatalog != nil && clipCatalog.Fresh(cfg.MaxCatalogAge()) {
+		entries, err := clipCatalog.List(catalog.Query{Privacy: PRIVATE.string()})
+		if err != nil {
+			return nil, err
+		}
+		metas := make([]*drapi.VideoMeta, len(entries))
+		for i, e := range entries {
+			metas[i] = e.VideoMeta
+		}
+		return metas, nil
+	}
+	return drv.FindVideoMeta(drapi.PRIVACY, PRIVATE.string())
+}
+
+func catalogCommand() *command {
+	var full bool
+	var q catalog.Query
+	return &command{
+		name:  "catalog",
+		short: "sync, list and search the local catalog of clip folders",
+		long: "The catalog is a local copy of the folder metadata. It is updated by every command\n" +
+			"that reads or writes a folder and by sync; while the last sync is younger than\n" +
+			"catalogMaxAge, name patterns, meta show, drive url, schedule list and reconcile\n" +
+			"without -fix answer from it without calling Drive.",
+		sub: []*command{
+			{
+				name:  "sync",
+				short: "refresh the catalog with the folders changed on Drive since the last sync",
+				setFlags: func(fs *flag.FlagSet) {
+					fs.BoolVar(&full, "full", false, "reload every folder and drop the ones deleted from Drive")
+				},
+				run: online(func([]string) error {
+					return syncCatalog(full)
+				}),
+			},
+			{
+				name:  "list",
+				short: "list the cataloged clips, newest first",
+				setFlags: func(fs *flag.FlagSet) {
+					fs.StringVar(&q.Privacy, "privacy", "", "only clips with this privacy status")
+					fs.StringVar(&q.Series, "series", "", "only clips whose folder names this series")
+				},
+				run: func([]string) error {
+					return listCatalog(q)
+				},
+			},
+			{
+				name:    "search",
+				args:    "TEXT...",
+				short:   "list the cataloged clips whose folder name or title contains TEXT",
+				minArgs: 1,
+				run: func(args []string) error {
+					return listCatalog(catalog.Query{Text: strings.Join(args, " ")})
+				},
+			},
+			{
+				name:  "status",
+				short: "show the catalog location, last sync and clip counts by privacy",
+				run: func([]string) error {
+					return catalogStatus()
+				},
+			},
+		},
+	}
+}
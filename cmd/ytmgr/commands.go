@@ -0,0 +1,760 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"twsati/internal/audit"
+	"twsati/internal/config"
+	drapi "twsati/internal/google/drive"
+	ytapi "twsati/internal/google/youtube"
+	"twsati/internal/plan"
+	"twsati/internal/subtitle"
+	"twsati/internal/sys"
+)
+
+var drv *drapi.Client
+var yt *ytapi.Client
+
+// newClients builds the API clients; tests replace it to use a fake
+// backend.
+var newClients = func(p *plan.Plan) (*drapi.Client, *ytapi.Client, error) {
+	dopts, yopts := driveOptions(), youtubeOptions()
+	dopts.Plan, yopts.Plan = p, p
+	if db, err := openCatalog(); err != nil {
+		fmt.Fprintln(os.Stderr, "catalog disabled:", err)
+	} else if db != nil {
+		dopts.Cache = db
+	}
+	d, err := drapi.NewClient(dopts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("drive client: %w", err)
+	}
+	y, err := ytapi.NewClient(yopts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("youtube client: %w", err)
+	}
+	return d, y, nil
+}
+
+// Global flags.
+var dryRun, planJSON bool
+var workers int
+var manifest string
+
+// activePlan collects the changes of a dry run and is nil otherwise;
+// fsops records or makes the local file changes accordingly.
+var activePlan *plan.Plan
+var fsops sys.Ops
+
+// cfg holds the effective settings; main replaces the defaults with the
+// loaded configuration.
+var cfg = config.Defaults()
+
+func driveOptions() drapi.Options {
+	return drapi.Options{ClientSecretFile: cfg.Drive.ClientSecretFile, TokenFile: cfg.Drive.TokenFile}
+}
+
+func youtubeOptions() ytapi.Options {
+	return ytapi.Options{ClientSecretFile: cfg.YouTube.ClientSecretFile, TokenFile: cfg.YouTube.TokenFile}
+}
+
+// connect creates the Drive and YouTube clients on first use.
+func connect() error {
+	if drv != nil && yt != nil {
+		return nil
+	}
+	var err error
+	drv, yt, err = newClients(activePlan)
+	return err
+}
+
+// online wraps run so the API clients exist before it is called.
+func online(run func(args []string) error) func(args []string) error {
+	return func(args []string) error {
+		if err := connect(); err != nil {
+			return err
+		}
+		return run(args)
+	}
+}
+
+// eachName runs fn for every positional folder name, expanding name
+// patterns against Drive.
+func eachName(fn func(name string) error) func(args []string) error {
+	return online(func(args []string) error {
+		names, err := expandNames(args)
+		if err != nil {
+			return err
+		}
+		return forEach(names, fn)
+	})
+}
+
+func prettyPrint(i interface{}) string {
+	s, _ := json.MarshalIndent(i, "", "   ")
+	return string(s)
+}
+
+func setSptr(ptr **string, rvalue string) {
+	if *ptr == nil {
+		*ptr = new(string)
+	}
+	**ptr = rvalue
+}
+
+func rootCommand() *command {
+	root := &command{
+		name: "ytmgr",
+		setFlags: func(fs *flag.FlagSet) {
+			fs.BoolVar(&dryRun, "dry-run", false, "print the changes to Drive, YouTube and local files instead of making them")
+			fs.BoolVar(&planJSON, "json", false, "print the dry-run plan as JSON")
+			fs.IntVar(&workers, "workers", cfg.Workers, "number of folders processed at the same time")
+			fs.StringVar(&manifest, "from", "", "also process the names listed in `FILE`, one per line (- reads standard input)")
+		},
+		long: "ytmgr manages clip folders on Google Drive and publishes them to YouTube.\n" +
+			"NAME arguments may be patterns such as zh2210*, matched against the Drive folder names;\n" +
+			"with several names a failed folder does not stop the others and a summary is printed.\n" +
+			"Exit codes: 0 ok, 1 failure, 2 usage, 3 YouTube quota exhausted, 4 Drive folder not found or ambiguous.",
+		sub: []*command{
+			loginCommand(),
+			logoutCommand(),
+			{
+				name:  "hello",
+				short: "check that the Drive and YouTube credentials work",
+				run: online(func([]string) error {
+					if err := yt.ChannelsListById("snippet,contentDetails,statistics", cfg.ChannelId); err != nil {
+						return err
+					}
+					if err := drv.HelloDrive(); err != nil {
+						return err
+					}
+					fmt.Println("Hello Youtube!!\nHello Google Drive!!")
+					return nil
+				}),
+			},
+			videoCommand(),
+			captionCommand(),
+			releaseCommand(),
+			scheduleCommand(),
+			reconcileCommand(),
+			catalogCommand(),
+			auditCommand(),
+			watchCommand(),
+			metaCommand(),
+			driveCommand(),
+			prepCommand(),
+			configCommand(),
+		},
+	}
+	root.sub = append(root.sub, &command{
+		name:  "help",
+		args:  "[COMMAND...]",
+		short: "show help for a command",
+		run: func(args []string) error {
+			cmd, path := root, root.name
+			for _, name := range args {
+				next := cmd.find(name)
+				if next == nil {
+					return fmt.Errorf("%w: unknown command %q", errUsage, path+" "+name)
+				}
+				cmd, path = next, path+" "+name
+			}
+			if cmd.run == nil {
+				cmd.usage(path, nil, os.Stdout)
+			} else {
+				cmd.flagSet(path, os.Stdout).Usage()
+			}
+			return nil
+		},
+	})
+	return root
+}
+
+func loginCommand() *command {
+	return &command{
+		name:  "login",
+		short: "authorize in the browser and cache fresh Drive and YouTube tokens",
+		run: func([]string) error {
+			if activePlan.Record("fs", "write tokens", cfg.Drive.TokenFile+", "+cfg.YouTube.TokenFile, "after browser authorization") {
+				return nil
+			}
+			if err := drapi.Login(driveOptions()); err != nil {
+				return fmt.Errorf("drive login: %w", err)
+			}
+			if err := ytapi.Login(youtubeOptions()); err != nil {
+				return fmt.Errorf("youtube login: %w", err)
+			}
+			fmt.Println("Logged in to Google Drive and YouTube")
+			return nil
+		},
+	}
+}
+
+func logoutCommand() *command {
+	return &command{
+		name:  "logout",
+		short: "revoke and remove the cached Drive and YouTube tokens",
+		run: func([]string) error {
+			if activePlan.Record("fs", "revoke and remove tokens", cfg.Drive.TokenFile+", "+cfg.YouTube.TokenFile, "") {
+				return nil
+			}
+			if err := drapi.Logout(driveOptions()); err != nil {
+				return fmt.Errorf("drive logout: %w", err)
+			}
+			if err := ytapi.Logout(youtubeOptions()); err != nil {
+				return fmt.Errorf("youtube logout: %w", err)
+			}
+			fmt.Println("Logged out of Google Drive and YouTube")
+			return nil
+		},
+	}
+}
+
+func videoCommand() *command {
+	var upload uploadOptions
+	var chunkSize int64
+	var yes, force bool
+	var previewSeries, previewContent string
+	var previewDrive bool
+	return &command{
+		name:  "video",
+		short: "upload, publish and delete videos",
+		sub: []*command{
+			{
+				name:  "upload",
+				args:  "NAME...",
+				short: "upload the newest .mp4 of each folder as an unlisted video",
+				long: "The upload is sent in chunks and shows its progress. If it is interrupted,\n" +
+					"running the same command again resumes where it stopped. -replace deletes the\n" +
+					"old video the way video delete does, unless the .mp4 is the one uploaded before.",
+				minArgs: 1,
+				setFlags: func(fs *flag.FlagSet) {
+					fs.Int64Var(&chunkSize, "chunk-size", ytapi.DefaultChunkSize>>20, "upload chunk size in MiB")
+					fs.BoolVar(&upload.replace, "replace", false, "delete an already uploaded video and upload again")
+					fs.BoolVar(&upload.yes, "yes", false, "replace without asking")
+					fs.BoolVar(&upload.force, "force", false, "replace even when the .mp4 is the one already uploaded")
+				},
+				run: eachName(func(name string) error {
+					o := upload
+					o.chunkSize = chunkSize << 20
+					return youtubeUpload(name, o)
+				}),
+			},
+			{
+				name:  "preview",
+				args:  "NAME...",
+				short: "print the title and description publish would set, without calling YouTube",
+				long: "Without -drive only the folder name is used, so the description text is empty\n" +
+					"unless -content names a local file.",
+				minArgs: 1,
+				setFlags: func(fs *flag.FlagSet) {
+					fs.StringVar(&previewSeries, "series", "", "render with this series instead of the folder's")
+					fs.StringVar(&previewContent, "content", "", "local file with the description text")
+					fs.BoolVar(&previewDrive, "drive", false, "read the folder's series and description from Drive")
+				},
+				run: func(names []string) error {
+					if previewDrive {
+						if err := connect(); err != nil {
+							return err
+						}
+					}
+					return forEach(names, func(name string) error {
+						return previewVideo(name, previewSeries, previewContent, previewDrive)
+					})
+				},
+			},
+			{
+				name:  "delete",
+				args:  "NAME...",
+				short: "delete the uploaded video and clear the folder's video, caption and privacy",
+				long: "The video resource with its statistics and every caption track are saved in the\n" +
+					"folder's " + drapi.ArchiveFolder + " subfolder first, and the old id is kept in the folder's\n" +
+					drapi.DELETED + ". Asks before deleting unless -yes is given.",
+				minArgs: 1,
+				setFlags: func(fs *flag.FlagSet) {
+					fs.BoolVar(&yes, "yes", false, "delete without asking")
+				},
+				run: eachName(func(name string) error {
+					return youtubeDelete(name, yes)
+				}),
+			},
+			{
+				name:    "cover",
+				args:    "NAME...",
+				short:   "set the newest .png or .jpg of each folder as the video thumbnail",
+				long:    "A thumbnail that is the one already set is skipped unless -force is given.",
+				minArgs: 1,
+				setFlags: func(fs *flag.FlagSet) {
+					fs.BoolVar(&force, "force", false, "set the thumbnail even when it did not change")
+				},
+				run: eachName(func(name string) error {
+					return youtubeUploadCover(name, force)
+				}),
+			},
+			{
+				name:    "publish",
+				args:    "NAME...",
+				short:   "update title and description and make the video public",
+				minArgs: 1,
+				run: eachName(func(name string) error {
+					return youtubeUpdateVideo(name, PUBLIC)
+				}),
+			},
+			{
+				name:    "unlist",
+				args:    "NAME...",
+				short:   "update title and description and make the video unlisted",
+				minArgs: 1,
+				run: eachName(func(name string) error {
+					return youtubeUpdateVideo(name, UNLISTED)
+				}),
+			},
+		},
+	}
+}
+
+func captionCommand() *command {
+	var force, lintJSON bool
+	var lang string
+	return &command{
+		name:  "caption",
+		short: "upload and delete caption tracks",
+		sub: []*command{
+			{
+				name:  "upload",
+				args:  "NAME...",
+				short: "upload the caption tracks of each folder, replacing the existing ones",
+				long: "The main track in caption.language is the newest .srt, .vtt, .sbv or .ttml; it is\n" +
+					"linted first and not uploaded when it has errors. caption.simplified is converted\n" +
+					"from it with OpenCC and every NAME.LANG.srt (or other format) is uploaded as the\n" +
+					"track in LANG. Tracks whose file did not change since they were uploaded are\n" +
+					"skipped; -force uploads in both cases. -lang uploads a single track.",
+				minArgs: 1,
+				setFlags: func(fs *flag.FlagSet) {
+					fs.BoolVar(&force, "force", false, "upload even when the caption did not change or has lint errors")
+					fs.StringVar(&lang, "lang", "", "upload only the track in `LANG`, e.g. zh-tw, zh-cn or en")
+				},
+				run: eachName(func(name string) error {
+					return youtubeCaption(name, strings.ToLower(lang), force)
+				}),
+			},
+			{
+				name:  "lint",
+				args:  "NAME...",
+				short: "check the caption file of each folder for timing, speed and length problems",
+				long: "Overlapping or out of order cues, cues that end before they start and cues past\n" +
+					"the end of the clip are errors; empty cues, cues faster than caption.maxCps\n" +
+					"characters per second, lines longer than caption.maxLineLength and gaps longer\n" +
+					"than caption.maxGap are warnings. Folders with errors fail. -json prints one\n" +
+					"JSON report per folder.",
+				minArgs: 1,
+				setFlags: func(fs *flag.FlagSet) {
+					fs.BoolVar(&lintJSON, "json", false, "print the reports as JSON, one per line")
+				},
+				run: eachName(func(name string) error {
+					return showLint(name, lintJSON)
+				}),
+			},
+			{
+				name:  "pull",
+				args:  "NAME...",
+				short: "save the caption tracks on YouTube into each folder as .srt",
+				long: "Each track is saved as NAME.LANG.yt-TIME.srt, TIME in UTC, so edits made in YouTube\n" +
+					"Studio are kept on Drive. Pulled files are never uploaded; rename one to\n" +
+					"NAME.srt or NAME.LANG.srt to make it the caption. caption delete pulls the\n" +
+					"tracks it deletes first.",
+				minArgs: 1,
+				setFlags: func(fs *flag.FlagSet) {
+					fs.StringVar(&lang, "lang", "", "save only the track in `LANG`")
+				},
+				run: eachName(func(name string) error {
+					return youtubeCaptionPull(name, lang)
+				}),
+			},
+			{
+				name:    "delete",
+				args:    "NAME...",
+				short:   "delete every caption track of the folder's video",
+				minArgs: 1,
+				setFlags: func(fs *flag.FlagSet) {
+					fs.StringVar(&lang, "lang", "", "delete only the track in `LANG` recorded on the folder")
+				},
+				run: eachName(func(name string) error {
+					return youtubeDeleteCaption(name, strings.ToLower(lang))
+				}),
+			},
+		},
+	}
+}
+
+func releaseCommand() *command {
+	var opts releaseOptions
+	return &command{
+		name:  "release",
+		args:  "NAME...",
+		short: "upload, set the thumbnail, caption, add to the series playlist and publish in one go",
+		long: "Each step is recorded on the Drive folder when it completes. If a step fails,\n" +
+			"running the same command again continues with it; -restart runs every step again.",
+		minArgs: 1,
+		setFlags: func(fs *flag.FlagSet) {
+			fs.Int64Var(&opts.chunkSize, "chunk-size", ytapi.DefaultChunkSize>>20, "upload chunk size in MiB")
+			fs.BoolVar(&opts.restart, "restart", false, "ignore the steps already recorded as done")
+		},
+		run: eachName(func(name string) error {
+			o := opts
+			o.chunkSize <<= 20
+			return release(name, o)
+		}),
+	}
+}
+
+func scheduleCommand() *command {
+	var at string
+	var all bool
+	return &command{
+		name:  "schedule",
+		short: "schedule videos to go public at a set time",
+		sub: []*command{
+			{
+				name:  "set",
+				args:  "NAME...",
+				short: "update title and description and keep the video private until the -at time",
+				long: "The time is given as \"2026-11-01 20:00 Asia/Taipei\", without the zone name in\n" +
+					"the local time zone, or in RFC 3339.",
+				minArgs: 1,
+				setFlags: func(fs *flag.FlagSet) {
+					fs.StringVar(&at, "at", "", "when YouTube makes the video public")
+				},
+				run: func(names []string) error {
+					if at == "" {
+						return fmt.Errorf("%w: -at is required", errUsage)
+					}
+					when, err := parseScheduleTime(at, time.Now())
+					if err != nil {
+						return err
+					}
+					return eachName(func(name string) error {
+						return youtubeSchedule(name, when)
+					})(names)
+				},
+			},
+			{
+				name:  "list",
+				short: "list the scheduled releases of every folder in time order",
+				setFlags: func(fs *flag.FlagSet) {
+					fs.BoolVar(&all, "all", false, "include releases whose time has passed")
+				},
+				run: online(func([]string) error {
+					return listSchedule(all, time.Now())
+				}),
+			},
+			{
+				name:    "cancel",
+				args:    "NAME...",
+				short:   "drop the scheduled release and make the video unlisted",
+				minArgs: 1,
+				run:     eachName(youtubeCancelSchedule),
+			},
+		},
+	}
+}
+
+func reconcileCommand() *command {
+	var fix string
+	return &command{
+		name:  "reconcile",
+		args:  "NAME...",
+		short: "compare the folder's video, status, thumbnail and caption ids with YouTube",
+		long: "Reports videos missing from YouTube, a different privacy or release time, a cover\n" +
+			"that is not the thumbnail last set, caption ids YouTube no longer has and caption\n" +
+			"tracks the folder does not know about.\n" +
+			"Without -fix the command fails when anything differs.",
+		minArgs: 1,
+		setFlags: func(fs *flag.FlagSet) {
+			fs.StringVar(&fix, "fix", "", "repair the mismatches: drive updates the folder, youtube updates the video")
+		},
+		run: func(names []string) error {
+			if fix != "" && fix != "drive" && fix != "youtube" {
+				return fmt.Errorf("%w: -fix %q, want drive or youtube", errUsage, fix)
+			}
+			return eachName(func(name string) error {
+				return reconcile(name, fix)
+			})(names)
+		},
+	}
+}
+
+func auditCommand() *command {
+	return &command{
+		name:  "audit",
+		args:  "NAME...",
+		short: "show who changed the folder's video and metadata, and when",
+		long: "Every upload, caption, privacy, schedule, playlist and delete ytmgr runs on a\n" +
+			"folder appends an entry to the " + audit.FileName + " file in that folder, with the\n" +
+			"metadata before and after and the ids YouTube returned. -dry-run writes nothing.",
+		minArgs: 1,
+		run:     eachName(showAudit),
+	}
+}
+
+func watchCommand() *command {
+	var once bool
+	return &command{
+		name:  "watch",
+		short: "upload captions and descriptions as their files change on Drive",
+		long: "Follows the Drive changes feed from the first run on. Once a clip folder has had\n" +
+			"no changes for the configured watch.debounce, a changed .srt is uploaded as the\n" +
+			"caption, a changed .txt refreshes the title and description, and watch.notify\n" +
+			"is run. Files identical to the ones on YouTube are not pushed again.",
+		setFlags: func(fs *flag.FlagSet) {
+			fs.BoolVar(&once, "once", false, "act on the changes so far without waiting, then exit")
+		},
+		run: online(func([]string) error {
+			return watch(once)
+		}),
+	}
+}
+
+func metaCommand() *command {
+	var videoId, captionId, privacy, series optString
+	return &command{
+		name:  "meta",
+		short: "show or edit the video metadata stored on the Drive folder",
+		sub: []*command{
+			{
+				name:    "show",
+				args:    "NAME...",
+				short:   "print the folder's video metadata",
+				minArgs: 1,
+				run:     eachName(dumpMeta),
+			},
+			{
+				name:    "set",
+				args:    "NAME...",
+				short:   "overwrite the video id, caption id, privacy or series recorded on the folder",
+				long:    "Only the flags that are given are written.",
+				minArgs: 1,
+				setFlags: func(fs *flag.FlagSet) {
+					fs.Var(&videoId, "video-id", "YouTube video id")
+					fs.Var(&captionId, "caption-id", "YouTube caption id")
+					fs.Var(&privacy, "privacy", "privacy status, unlisted or public")
+					fs.Var(&series, "series", "series whose title and description templates the folder uses")
+				},
+				run: eachName(func(name string) error {
+					return setMeta(name, videoId.ptr, captionId.ptr, privacy.ptr, series.ptr)
+				}),
+			},
+		},
+	}
+}
+
+// optString is a string flag that stays nil unless it is given.
+type optString struct {
+	ptr *string
+}
+
+func (o *optString) String() string {
+	if o.ptr == nil {
+		return ""
+	}
+	return *o.ptr
+}
+
+func (o *optString) Set(v string) error {
+	o.ptr = &v
+	return nil
+}
+
+// captionFormat reads the value of a caption format flag.
+func captionFormat(flagName, value string) (subtitle.Format, error) {
+	format, err := subtitle.ParseFormat(value)
+	if err != nil {
+		return "", fmt.Errorf("%w: -%s %q, want srt, vtt, sbv or ttml", errUsage, flagName, value)
+	}
+	return format, nil
+}
+
+func driveCommand() *command {
+	var dir, to string
+	return &command{
+		name:  "drive",
+		short: "inspect and download clip folders on Google Drive",
+		sub: []*command{
+			{
+				name:    "download",
+				args:    "NAME...",
+				short:   "download the caption, description and video of each folder",
+				long:    "With -caption-format the caption is also written in that format next to the original.",
+				minArgs: 1,
+				setFlags: func(fs *flag.FlagSet) {
+					fs.StringVar(&dir, "dir", ".", "local directory to download into, one subdirectory per folder")
+					fs.StringVar(&to, "caption-format", "", "also convert the caption to `FORMAT`: srt, vtt, sbv or ttml")
+				},
+				run: func(names []string) error {
+					var format subtitle.Format
+					if to != "" {
+						var err error
+						if format, err = captionFormat("caption-format", to); err != nil {
+							return err
+						}
+					}
+					return eachName(func(name string) error {
+						return download(name, dir, format)
+					})(names)
+				},
+			},
+			{
+				name:    "url",
+				args:    "NAME...",
+				short:   "print and open the folder's Drive URL",
+				minArgs: 1,
+				run:     eachName(dumpFolderUrl),
+			},
+		},
+	}
+}
+
+func prepCommand() *command {
+	var to string
+	var force bool
+	return &command{
+		name:  "prep",
+		short: "prepare a local staging directory before it is synced to Drive",
+		sub: []*command{
+			{
+				name:    "propername",
+				args:    "DIR",
+				short:   "rename files to the standard clip name format in traditional Chinese",
+				minArgs: 1,
+				run:     eachDir(toProperNames),
+			},
+			{
+				name:    "init",
+				args:    "DIR",
+				short:   "create a folder for each .mp4 and .mp3 file and move the file into it",
+				minArgs: 1,
+				run:     eachDir(InitDataDir),
+			},
+			{
+				name:    "normalize",
+				args:    "DIR",
+				short:   "rename the .mp4, .srt and .txt files of each folder after the folder",
+				minArgs: 1,
+				run: eachDir(func(dir string) error {
+					defer trace("BasefyAll")()
+					return BasefyAll(dir)
+				}),
+			},
+			{
+				name:    "bigfy",
+				args:    "DIR",
+				short:   "convert the contents of every .txt and .srt file to traditional Chinese",
+				minArgs: 1,
+				run:     eachDir(BigfyAll),
+			},
+			{
+				name:    "txtfy",
+				args:    "DIR",
+				short:   "write the text of the newest .srt of each folder to a .txt next to it",
+				minArgs: 1,
+				run:     eachDir(TxtfyAll),
+			},
+			{
+				name:  "convert",
+				args:  "DIR",
+				short: "convert the newest caption file of each folder to another format",
+				long: "Reads SubRip .srt, WebVTT .vtt, YouTube .sbv and TTML .ttml files and writes the\n" +
+					"converted file next to the original, keeping the timing and line breaks.",
+				minArgs: 1,
+				setFlags: func(fs *flag.FlagSet) {
+					fs.StringVar(&to, "to", "vtt", "target `FORMAT`: srt, vtt, sbv or ttml")
+				},
+				run: func(dirs []string) error {
+					format, err := captionFormat("to", to)
+					if err != nil {
+						return err
+					}
+					return eachDir(func(dir string) error {
+						return ConvertAll(dir, format)
+					})(dirs)
+				},
+			},
+			{
+				name:  "cut",
+				args:  "SESSION DIR...",
+				short: "cut each clip folder's captions out of the full session's caption file",
+				long: "SESSION is the caption file of the whole livestream, in any format prep convert\n" +
+					"reads. Each DIR is a clip folder named like zh230114_[37.34-38.51]_title; the\n" +
+					"captions in its time range are trimmed to it, moved to start at zero and\n" +
+					"written to the .srt named after the folder.",
+				minArgs: 2,
+				setFlags: func(fs *flag.FlagSet) {
+					fs.BoolVar(&force, "force", false, "overwrite a clip's existing .srt")
+				},
+				run: func(args []string) error {
+					session, err := readCaption(args[0])
+					if err != nil {
+						return err
+					}
+					return eachDir(func(dir string) error {
+						return cutClip(session, dir, force)
+					})(args[1:])
+				},
+			},
+			{
+				name:    "from-json",
+				args:    "DIR FILE",
+				short:   "create clip folders in DIR from a playlist items JSON dump",
+				minArgs: 2,
+				run: func(args []string) error {
+					return processJson(args[0], args[1])
+				},
+			},
+			{
+				name:    "aux",
+				args:    "DIR",
+				short:   "append an empty time range to every file name",
+				minArgs: 1,
+				run:     eachDir(auxProcess),
+			},
+		},
+	}
+}
+
+// eachDir runs fn for every positional staging directory.
+func eachDir(fn func(dir string) error) func(args []string) error {
+	return func(dirs []string) error {
+		return forEach(dirs, fn)
+	}
+}
+
+func configCommand() *command {
+	return &command{
+		name:  "config",
+		short: "inspect the ytmgr configuration",
+		sub: []*command{
+			{
+				name:  "show",
+				short: "print the effective settings after merging the config file and environment",
+				long: "The config file is ytmgr.json in the working directory, else ~/.config/ytmgr/config.json,\n" +
+					"or the file named by YTMGR_CONFIG. YTMGR_* environment variables override single settings.",
+				run: func([]string) error {
+					if cfg.Source != "" {
+						fmt.Println("# config file:", cfg.Source)
+					} else {
+						fmt.Println("# config file: none, searched", strings.Join(config.SearchPath(), ", "))
+					}
+					if len(cfg.Overrides) > 0 {
+						fmt.Println("# environment:", strings.Join(cfg.Overrides, ", "))
+					}
+					fmt.Println(prettyPrint(cfg))
+					return nil
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"twsati/internal/subtitle"
+)
+
+func dumpFolderUrl(name string) error {
+	vmeta, err := readMeta(name)
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("https://drive.google.com/drive/folders/%s", vmeta.FolderId)
+	fmt.Println(url)
+	cli := exec.Command("explorer", url)
+	return cli.Run()
+
+}
+
+// download copies the folder's caption, description and video into a
+// subdirectory of localRoot; a non-empty format also converts the caption.
+func download(name string, localRoot string, format subtitle.Format) error {
+	vmeta, err := drv.GetVideoMeta(name)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(localRoot, name)
+	if err := fsops.MkdirAll(path); err != nil {
+		return err
+	}
+	vmeta.SetTempDir(path)
+	if vmeta.HasCaption() {
+		if _, err := vmeta.CaptionPath(); err != nil {
+			return err
+		}
+		if format != "" && !strings.HasSuffix(vmeta.CaptionFile(), format.Ext()) {
+			content, err := vmeta.CaptionContent()
+			if err != nil {
+				return err
+			}
+			if err := convertCaption(filepath.Join(path, vmeta.CaptionFile()), content, format); err != nil {
+				return err
+			}
+		}
+	}
+	if vmeta.HasDescription() {
+
+		if _, err := vmeta.DescriptionPath(); err != nil {
+			return err
+		}
+	}
+	if vmeta.HasVideo() {
+		if _, err := vmeta.VideoFilePath(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	drapi "twsati/internal/google/drive"
+	"twsati/internal/subtitle"
+)
+
+// errLint is returned for captions with lint errors.
+var errLint = errors.New("caption has lint errors")
+
+// lintReport is what caption lint -json prints for each folder, one JSON
+// object per line.
+type lintReport struct {
+	Folder   string           `json:"folder"`
+	File     string           `json:"file"`
+	Errors   int              `json:"errors"`
+	Warnings int              `json:"warnings"`
+	Issues   []subtitle.Issue `json:"issues"`
+}
+
+// clipLength is the length of the clip named by the folder, zero when the
+// folder name carries no times.
+func clipLength(vmeta *drapi.VideoMeta) time.Duration {
+	start := vmeta.Smin*60 + vmeta.Ssec
+	end := vmeta.Emin*60 + vmeta.Esec
+	if end <= start {
+		return 0
+	}
+	return time.Duration(end-start) * time.Second
+}
+
+// lintCaption checks the folder's caption file against the configured
+// limits.
+func lintCaption(vmeta *drapi.VideoMeta) (*lintReport, error) {
+	content, err := vmeta.CaptionContent()
+	if err != nil {
+		return nil, err
+	}
+	format, err := subtitle.FormatOf(vmeta.CaptionFile())
+	if err != nil {
+		return nil, err
+	}
+	cues, err := subtitle.Parse(format, bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s: %w", vmeta.FolderName(), vmeta.CaptionFile(), err)
+	}
+	report := &lintReport{
+		Folder: vmeta.FolderName(),
+		File:   vmeta.CaptionFile(),
+		Issues: subtitle.Lint(cues, subtitle.LintOptions{
+			MaxCPS:        cfg.Caption.MaxCPS,
+			MaxLineLength: cfg.Caption.MaxLineLength,
+			MaxGap:        cfg.Caption.Gap(),
+			ClipLength:    clipLength(vmeta),
+		}),
+	}
+	for _, i := range report.Issues {
+		if i.Severity == subtitle.Error {
+			report.Errors++
+		} else {
+			report.Warnings++
+		}
+	}
+	if report.Issues == nil {
+		report.Issues = []subtitle.Issue{}
+	}
+	return report, nil
+}
+
+// String lists the issues under the folder name, in one piece so
+// parallel workers do not interleave.
+func (r *lintReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s: %d errors, %d warnings\n", r.Folder, r.File, r.Errors, r.Warnings)
+	for _, i := range r.Issues {
+		fmt.Fprintf(&b, "  %s\n", i)
+	}
+	return b.String()
+}
+
+func (r *lintReport) err() error {
+	if r.Errors > 0 {
+		return fmt.Errorf("%s: %w", r.Folder, errLint)
+	}
+	return nil
+}
+
+// checkLint prints the issues of the folder's caption and fails on lint
+// errors unless force is set.
+func checkLint(vmeta *drapi.VideoMeta, force bool) error {
+	report, err := lintCaption(vmeta)
+	if err != nil {
+		return err
+	}
+	if len(report.Issues) > 0 {
+		fmt.Print(report)
+	}
+	if err := report.err(); err != nil && !force {
+		return fmt.Errorf("%w (-force uploads it anyway)", err)
+	}
+	return nil
+}
+
+// showLint prints the lint report of the folder's caption and fails when
+// it has errors.
+func showLint(name string, asJSON bool) error {
+	vmeta, err := drv.GetVideoMeta(name)
+	if err != nil {
+		return err
+	}
+	defer vmeta.CleanUp()
+	report, err := lintCaption(vmeta)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		line, _ := json.Marshal(report)
+		fmt.Println(string(line))
+	} else {
+		fmt.Print(report)
+	}
+	return report.err()
+}
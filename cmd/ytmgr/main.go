@@ -0,0 +1,239 @@
+// Command ytmgr manages the clip folders on Google Drive and the videos,
+// captions and playlists they are published to on YouTube.
+//
+//	ytmgr [-dry-run [-json]] <command> [<subcommand>] [flags] [NAME...]
+//
+// Run `ytmgr help` for the list of commands and `ytmgr help <command>` for
+// the flags of one of them.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"twsati/internal/config"
+	drapi "twsati/internal/google/drive"
+	ytapi "twsati/internal/google/youtube"
+	"twsati/internal/plan"
+	"twsati/internal/sys"
+)
+
+// Exit codes shared by every command.
+const (
+	exitOK       = 0
+	exitFailure  = 1 // the operation failed
+	exitUsage    = 2 // bad command line, same as the flag package
+	exitQuota    = 3 // the YouTube quota is exhausted, retry after it resets
+	exitNotFound = 4 // the Drive folder is missing or not unique
+)
+
+var errUsage = errors.New("usage error")
+
+// command is a node in the ytmgr command tree: either a group of
+// subcommands or a runnable leaf.
+type command struct {
+	name  string
+	args  string // synopsis of the positional arguments
+	short string
+	long  string
+	// setFlags registers the command's own flags.
+	setFlags func(fs *flag.FlagSet)
+	// run receives the positional arguments left after flag parsing.
+	run func(args []string) error
+	// minArgs is the number of positional arguments run needs.
+	minArgs int
+	sub     []*command
+}
+
+func (c *command) find(name string) *command {
+	for _, s := range c.sub {
+		if s.name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func (c *command) flagSet(path string, out io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(path, flag.ContinueOnError)
+	fs.SetOutput(out)
+	if c.setFlags != nil {
+		c.setFlags(fs)
+	}
+	fs.Usage = func() { c.usage(path, fs, out) }
+	return fs
+}
+
+func (c *command) usage(path string, fs *flag.FlagSet, out io.Writer) {
+	if c.run == nil {
+		fmt.Fprintf(out, "usage: %s <command> ...\n\n", path)
+		if c.long != "" {
+			fmt.Fprintf(out, "%s\n\n", c.long)
+		}
+		fmt.Fprintln(out, "commands:")
+		for _, s := range c.sub {
+			fmt.Fprintf(out, "  %-12s %s\n", s.name, s.short)
+		}
+		if fs != nil {
+			fmt.Fprintln(out, "\nflags:")
+			fs.PrintDefaults()
+		}
+		return
+	}
+	synopsis := path
+	hasFlags := false
+	fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		synopsis += " [flags]"
+	}
+	if c.args != "" {
+		synopsis += " " + c.args
+	}
+	fmt.Fprintf(out, "usage: %s\n\n%s\n", synopsis, c.short)
+	if c.long != "" {
+		fmt.Fprintf(out, "\n%s\n", c.long)
+	}
+	if hasFlags {
+		fmt.Fprintln(out, "\nflags:")
+		fs.PrintDefaults()
+	}
+}
+
+// parseInterspersed parses fs allowing flags after positional arguments,
+// so `ytmgr video upload NAME -chunk-size 4` works as expected.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		if args[0] == "--" {
+			return append(positional, args[1:]...), nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// execute resolves args against the command tree rooted at root and runs
+// the selected command, returning the process exit code. The root's flags
+// are global: they are accepted before the command and among its own.
+func execute(root *command, args []string, stdout, stderr io.Writer) int {
+	rootFlags := root.flagSet(root.name, stderr)
+	if err := rootFlags.Parse(args); errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	} else if err != nil {
+		return exitUsage
+	}
+	args = rootFlags.Args()
+
+	cmd, path := root, root.name
+	for cmd.run == nil {
+		if len(args) == 0 || strings.HasPrefix(args[0], "-") && args[0] != "-h" && args[0] != "-help" && args[0] != "--help" {
+			cmd.usage(path, nil, stderr)
+			return exitUsage
+		}
+		if args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+			cmd.usage(path, nil, stderr)
+			return exitOK
+		}
+		next := cmd.find(args[0])
+		if next == nil {
+			fmt.Fprintf(stderr, "%s: unknown command %q\n\n", path, args[0])
+			cmd.usage(path, nil, stderr)
+			return exitUsage
+		}
+		cmd, path, args = next, path+" "+args[0], args[1:]
+	}
+
+	fs := cmd.flagSet(path, stderr)
+	if root.setFlags != nil {
+		// registering resets the globals to their defaults, so carry over
+		// the ones given before the command
+		given := map[string]string{}
+		rootFlags.Visit(func(f *flag.Flag) { given[f.Name] = f.Value.String() })
+		root.setFlags(fs)
+		for name, value := range given {
+			fs.Set(name, value)
+		}
+	}
+	positional, err := parseInterspersed(fs, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	} else if err != nil {
+		return exitUsage
+	}
+	if manifest != "" {
+		names, err := readManifest(manifest)
+		if err != nil {
+			return exitCode(err, stderr)
+		}
+		positional = append(positional, names...)
+	}
+	if len(positional) < cmd.minArgs {
+		fmt.Fprintf(stderr, "%s: missing %s\n\n", path, cmd.args)
+		fs.Usage()
+		return exitUsage
+	}
+	if !dryRun {
+		return exitCode(cmd.run(positional), stderr)
+	}
+
+	// a dry run gets its own clients so every change lands in the plan
+	activePlan = plan.New()
+	fsops = sys.Ops{Plan: activePlan}
+	drv, yt = nil, nil
+	defer func() {
+		activePlan, fsops = nil, sys.Ops{}
+		drv, yt = nil, nil
+	}()
+	code := exitCode(cmd.run(positional), stderr)
+	if planJSON {
+		activePlan.WriteJSON(stdout)
+	} else {
+		activePlan.WriteText(stdout)
+	}
+	return code
+}
+
+// exitCode prints err with a hint for the failures callers commonly need
+// to act on and maps it to an exit code.
+func exitCode(err error, stderr io.Writer) int {
+	if err == nil {
+		return exitOK
+	}
+	var ferr *drapi.FolderError
+	switch {
+	case errors.Is(err, errUsage):
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	case errors.Is(err, ytapi.ErrQuotaExceeded):
+		fmt.Fprintf(stderr, "the daily YouTube API quota is exhausted; it resets at midnight Pacific Time, retry then: %v\n", err)
+		return exitQuota
+	case errors.Is(err, drapi.ErrFolderNotFound), errors.Is(err, drapi.ErrFolderAmbiguous):
+		fmt.Fprintf(stderr, "check the Drive folder name: %v\n", err)
+		return exitNotFound
+	case errors.As(err, &ferr):
+		fmt.Fprintf(stderr, "folder %s: %v\n", ferr.Name, ferr.Err)
+	default:
+		fmt.Fprintln(stderr, err)
+	}
+	return exitFailure
+}
+
+func main() {
+	loaded, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitUsage)
+	}
+	cfg = loaded
+	os.Exit(execute(rootCommand(), os.Args[1:], os.Stdout, os.Stderr))
+}
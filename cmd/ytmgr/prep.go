@@ -0,0 +1,469 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"twsati/internal/bigfive"
+	"twsati/internal/naming"
+	"twsati/internal/subtitle"
+	"twsati/internal/sys"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+func InitDataDir(path string) error {
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		ext := ""
+		if !f.IsDir() {
+			ext = filepath.Ext(f.Name())
+			if !(ext == ".mp4" || ext == ".mp3") {
+				fmt.Println("skipping unknown extensison for file: ", f.Name())
+				continue
+			}
+		}
+		fileOldPath := filepath.Join(path, f.Name())
+		fileBaseName := bigfive.ToBig5(strings.TrimSuffix(f.Name(), ext))
+		fileBaseName, err = naming.ProperName(fileBaseName, "")
+		if err != nil {
+			fmt.Println("skipping file: ", err)
+			continue
+		}
+		newPathDir := filepath.Join(path, fileBaseName)
+
+		fileNewPath := newPathDir
+		if !f.IsDir() {
+			err = fsops.MkdirAll(newPathDir)
+			if err != nil {
+				return err
+			}
+			fileNewPath = filepath.Join(newPathDir, fileBaseName+ext)
+		}
+		if fileOldPath != fileNewPath {
+			if err := fsops.CascadeRename(fileOldPath, fileNewPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func bigfy(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	bigContent := func() string {
+		defer trace("convert file: " + path)()
+		return bigfive.ToBig5(string(content))
+	}()
+	return fsops.WriteFile(path, []byte(bigContent))
+}
+
+func recurse(path string, doit func(string, fs.FileInfo) error) error {
+	files, err := sys.ListFilesSorted(path, sys.TimeAsc)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if f.IsDir() {
+			err = recurse(filepath.Join(path, f.Name()), doit)
+		} else {
+			err = doit(path, f)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func BigfyAll(path string) error {
+
+	return recurse(path, func(basePath string, finfo fs.FileInfo) error {
+		fName := finfo.Name()
+		if !finfo.IsDir() && (strings.HasSuffix(fName, ".txt") || strings.HasSuffix(fName, ".srt")) {
+			time.Sleep(200 * time.Millisecond)
+			return bigfy(filepath.Join(basePath, fName))
+		}
+		return nil
+	})
+}
+
+// txtfy writes the text of the .srt at path, one line per caption line,
+// to the .txt next to it.
+func txtfy(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	cues, err := subtitle.ParseSRT(file)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	var txt []string
+	for _, cue := range cues {
+		txt = append(txt, cue.Lines...)
+	}
+	newpath := strings.TrimSuffix(path, ".srt") + ".txt"
+	return fsops.WriteFile(newpath, []byte(strings.Join(txt, "\n")))
+}
+
+// TxtfyAll txtfies the newest .srt of every folder in path.
+func TxtfyAll(path string) error {
+	files, err := sys.ListFilesSorted(path, sys.TimeAsc)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if f.IsDir() {
+
+			baseContents, err := sys.ListFilesSorted(filepath.Join(path, f.Name()), sys.TimeDesc)
+			if err != nil {
+				return err
+			}
+			//sort descending by time
+			for _, txtf := range baseContents {
+				txtName := txtf.Name()
+				if !txtf.IsDir() && strings.HasSuffix(txtName, ".srt") {
+					if err := txtfy(filepath.Join(path, f.Name(), txtName)); err != nil {
+						return err
+					}
+					time.Sleep(200 * time.Millisecond)
+					break
+					//break because we only txtfy the latest srt file
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// convertCaption writes the cues of the caption file src, read from
+// content, next to it in format to.
+func convertCaption(src string, content []byte, to subtitle.Format) error {
+	from, err := subtitle.FormatOf(src)
+	if err != nil {
+		return err
+	}
+	cues, err := subtitle.Parse(from, bytes.NewReader(content))
+	if err != nil {
+		return fmt.Errorf("%s: %w", src, err)
+	}
+	var out bytes.Buffer
+	if err := subtitle.Write(to, &out, cues); err != nil {
+		return err
+	}
+	return fsops.WriteFile(strings.TrimSuffix(src, filepath.Ext(src))+to.Ext(), out.Bytes())
+}
+
+// ConvertAll converts the newest caption file of every folder in path to
+// format to, unless it already is in that format.
+func ConvertAll(path string, to subtitle.Format) error {
+	files, err := sys.ListFilesSorted(path, sys.TimeAsc)
+	if err != nil {
+		return err
+	}
+	exts := subtitle.Exts()
+	for _, f := range files {
+		if !f.IsDir() {
+			continue
+		}
+		contents, err := sys.ListFilesSorted(filepath.Join(path, f.Name()), sys.TimeDesc)
+		if err != nil {
+			return err
+		}
+		for _, cf := range contents {
+			if cf.IsDir() || !hasAnySuffix(cf.Name(), exts) {
+				continue
+			}
+			if !strings.HasSuffix(cf.Name(), to.Ext()) {
+				src := filepath.Join(path, f.Name(), cf.Name())
+				content, err := os.ReadFile(src)
+				if err != nil {
+					return err
+				}
+				if err := convertCaption(src, content, to); err != nil {
+					return err
+				}
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// readCaption parses the caption file at path in the format its
+// extension names.
+func readCaption(path string) ([]subtitle.Cue, error) {
+	format, err := subtitle.FormatOf(path)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cues, err := subtitle.Parse(format, bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cues, nil
+}
+
+// cutClip writes the cues of the full session falling in the time range
+// of the clip folder dir, re-based to the start of the clip, to the .srt
+// named after the folder. An existing .srt is kept unless force is set.
+func cutClip(session []subtitle.Cue, dir string, force bool) error {
+	name := filepath.Base(dir)
+	info, err := naming.ExtractName2(name)
+	if err != nil {
+		return err
+	}
+	start := time.Duration(info.Smin*60+info.Ssec) * time.Second
+	end := time.Duration(info.Emin*60+info.Esec) * time.Second
+	if end <= start {
+		return fmt.Errorf("%s: clip ends at %v, not after its start %v", name, end, start)
+	}
+	path := filepath.Join(dir, name+".srt")
+	if _, err := os.Stat(path); err == nil && !force {
+		return fmt.Errorf("%s: %s already exists (-force overwrites it)", name, filepath.Base(path))
+	}
+	cues := subtitle.Cut(session, start, end)
+	if len(cues) == 0 {
+		return fmt.Errorf("%s: no captions between %v and %v", name, start, end)
+	}
+	var out bytes.Buffer
+	if err := subtitle.WriteSRT(&out, cues); err != nil {
+		return err
+	}
+	if err := fsops.MkdirAll(dir); err != nil {
+		return err
+	}
+	fmt.Printf("%s: %d captions from %v to %v\n", name, len(cues), start, end)
+	return fsops.WriteFile(path, out.Bytes())
+}
+
+func hasAnySuffix(name string, suffixes []string) bool {
+	for _, s := range suffixes {
+		if strings.HasSuffix(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// changedF := func(files []string, ext string) bool {
+// 	for i, fname := range files {
+// 		if i == 0 && fname != baseName+ext {
+// 			return true
+// 		}
+// 		if i > 0 && fname != fmt.Sprintf("bak_%d%s", i, ext) {
+// 			return true
+// 		}
+// 	}
+// 	return false
+// }
+
+// renameGroupF := func(files []string, ext string) {
+// 	if !changedF(files, ext) {
+// 		return
+// 	}
+// 	fmt.Println(baseName, ext, "changeset: ", files)
+
+// 	tmpFiles := make([]string, 0)
+// 	for i, fname := range files {
+// 		oldPath := filepath.Join(basePath, fname)
+// 		tmpPath := filepath.Join(basePath, fmt.Sprintf("%d%s", i, ext))
+// 		err := os.Rename(oldPath, tmpPath)
+// 		sys.CheckErr(err)
+// 		// fmt.Println(oldPath, "->", tmpPath)
+// 		time.Sleep(200 * time.Millisecond)
+// 		tmpFiles = append(tmpFiles, tmpPath)
+// 	}
+
+// 	// fmt.Println(tmpFiles)
+// 	for i, tmpPath := range tmpFiles {
+// 		name := baseName
+// 		if i > 0 {
+// 			name = fmt.Sprintf("bak_%d", i)
+// 		}
+// 		newPath := filepath.Join(basePath, name+ext)
+// 		// dependency[oldPath] = newPath
+// 		err := os.Rename(tmpPath, newPath)
+// 		time.Sleep(200 * time.Millisecond)
+// 		sys.CheckErr(err)
+
+// 		fmt.Println(basePath, files[i], "->", name+ext)
+// 	}
+
+// 	// for key := range dependency {
+// 	// 	topoRename(key, dependency)
+// 	// }
+
+// }
+
+func BasefyAll(path string) error {
+
+	files, err := sys.ListFilesSorted(path, sys.TimeAsc)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if f.IsDir() {
+			if err := fsops.NormalizeDir(path, f); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func trace(msg string) func() {
+	start := time.Now()
+	log.Printf("enter %s", msg)
+	return func() {
+		log.Printf("exit %s (%s)", msg, time.Since(start))
+	}
+}
+
+func toProperNames(dirPath string) error {
+	files, err := sys.ListFilesSorted(dirPath, sys.TimeAsc)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		fName := f.Name()
+		fName = strings.ReplaceAll(fName, " ", "")
+		fName = strings.ReplaceAll(fName, "—", "-")
+		fName = strings.ReplaceAll(fName, "--", "-")
+		ext := filepath.Ext(fName)
+		if f.IsDir() {
+			ext = ""
+		}
+		propername, err := naming.ProperName(fName, ext)
+		if err != nil {
+			fmt.Println("skipping file: ", err)
+			continue
+		}
+		propername = bigfive.ToBig5(propername)
+		if f.Name() != propername {
+			fmt.Println(f.Name(), "->", propername)
+			err := fsops.Rename(filepath.Join(dirPath, f.Name()), filepath.Join(dirPath, propername))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func toBig5FileName(dirPath string) error {
+	return recurse(dirPath, func(basePath string, finfo fs.FileInfo) error {
+		fName := finfo.Name()
+		newName := bigfive.ToBig5(fName)
+		if fName != newName {
+			fmt.Println(fName, "->", newName)
+			err := fsops.Rename(filepath.Join(dirPath, fName), filepath.Join(dirPath, newName))
+			time.Sleep(200 * time.Millisecond)
+			return err
+		}
+		return nil
+
+	})
+
+}
+
+func processJson(dataDir string, jsonF string) error {
+	content, err := os.ReadFile(jsonF)
+	if err != nil {
+		return err
+	}
+	var items []youtube.PlaylistItem
+	err = json.Unmarshal(content, &items)
+	if err != nil {
+		return err
+	}
+	re := regexp.MustCompile(`(\d\d\d\d)年(\d+)月(\d+)日`)
+	for i, e := range items {
+		if i <= 6 {
+			continue
+		}
+		videoId := e.Snippet.ResourceId.VideoId
+		title := e.Snippet.Title
+		title = strings.ReplaceAll(title, "——隆波帕默尊者", "")
+		title = strings.ReplaceAll(title, "-", "")
+		title = strings.ReplaceAll(title, "微視頻", "")
+		title = strings.ReplaceAll(title, "繁體中文", "")
+		title = strings.TrimLeft(title, " ")
+		titleParts := strings.Split(title, "｜")
+		fmt.Print(videoId, " ")
+		dirName := ""
+		if len(titleParts) > 1 {
+			dateStr := titleParts[1]
+			match := re.FindAllStringSubmatch(dateStr, -1)[0]
+			// the pattern only captures digits
+			year, _ := naming.Atoi(match[1])
+			month, _ := naming.Atoi(match[2])
+			day, _ := naming.Atoi(match[3])
+			tmStr := fmt.Sprintf("%04d-%02d-%02d", year, month, day)
+			// fmt.Println(match[0], tmStr)
+			tm, err := time.Parse("2006-01-02", tmStr)
+			if err != nil {
+				return err
+			}
+			dirName += tm.Format("zh060102")
+		}
+		dirName += titleParts[0]
+		dirName += "(.-.)"
+		fullPath := filepath.Join(dataDir, dirName)
+		fmt.Print(fullPath)
+		fmt.Println()
+		err = fsops.MkdirAll(fullPath)
+		if err != nil {
+			return err
+		}
+		jsBytes, err := json.MarshalIndent(e, "", "    ")
+		if err != nil {
+			return err
+		}
+		err = fsops.WriteFile(filepath.Join(fullPath, "_META_.json"), jsBytes)
+		if err != nil {
+			return err
+		}
+	}
+	// fmt.Println(len(strings.Split("a｜b｜c｜d", "｜")))
+	return nil
+}
+
+func auxProcess(dataDir string) error {
+	files, err := sys.ListFilesSorted(dataDir, sys.NameAsc)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		title := f.Name()
+		ext := filepath.Ext(title)
+		title = strings.TrimSuffix(title, ext)
+		newfname := title + "(.-.)" + ext
+		fmt.Println(newfname)
+		err := fsops.Rename(filepath.Join(dataDir, f.Name()), filepath.Join(dataDir, newfname))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
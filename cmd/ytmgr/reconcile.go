@@ -0,0 +1,361 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	drapi "twsati/internal/google/drive"
+	ytapi "twsati/internal/google/youtube"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// errDrift is returned for folders whose metadata disagrees with YouTube
+// and was left as it is.
+var errDrift = errors.New("metadata out of sync with YouTube")
+
+// drift is one difference between what a folder records and what YouTube
+// has. fixDrive makes the folder metadata match YouTube and fixYouTube
+// the other way round; either is nil when that side cannot be repaired.
+type drift struct {
+	kind, detail string
+	fixDrive     func(vmeta *drapi.VideoMeta)
+	fixYouTube   func(vmeta *drapi.VideoMeta) error
+}
+
+func deref(ptr *string) string {
+	if ptr == nil {
+		return ""
+	}
+	return *ptr
+}
+
+// sameTime compares two RFC 3339 times regardless of their zone.
+func sameTime(a, b string) bool {
+	ta, errA := time.Parse(time.RFC3339, a)
+	tb, errB := time.Parse(time.RFC3339, b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return ta.Equal(tb)
+}
+
+// checkDrift compares the folder's video, status, thumbnail and caption
+// ids with YouTube.
+func checkDrift(vmeta *drapi.VideoMeta) ([]drift, error) {
+	if !hasVideo(vmeta) {
+		return nil, nil
+	}
+	video, err := yt.GetVideo(*vmeta.VideoId)
+	if errors.Is(err, ytapi.ErrNotFound) {
+		return []drift{{
+			kind:   "missing video",
+			detail: *vmeta.VideoId + " is not on YouTube, upload it again",
+			fixDrive: func(vmeta *drapi.VideoMeta) {
+				vmeta.Tombstone(*vmeta.VideoId)
+				setSptr(&vmeta.VideoId, "")
+				setSptr(&vmeta.CaptionId, "")
+				vmeta.ResetCaptions()
+				setSptr(&vmeta.Privacy, "")
+				setSptr(&vmeta.Scheduled, "")
+				vmeta.ResetRelease()
+				vmeta.ResetPushed()
+			},
+		}}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var drifts []drift
+	privacy, publishAt := video.Status.PrivacyStatus, video.Status.PublishAt
+	if privacy != deref(vmeta.Privacy) || !sameTime(publishAt, deref(vmeta.Scheduled)) {
+		drifts = append(drifts, drift{
+			kind:   "status",
+			detail: fmt.Sprintf("drive %s, youtube %s", describeStatus(deref(vmeta.Privacy), deref(vmeta.Scheduled)), describeStatus(privacy, publishAt)),
+			fixDrive: func(vmeta *drapi.VideoMeta) {
+				setSptr(&vmeta.Privacy, privacy)
+				if publishAt != "" || vmeta.Scheduled != nil {
+					setSptr(&vmeta.Scheduled, publishAt)
+				}
+			},
+			fixYouTube: pushStatus,
+		})
+	}
+
+	drifts = append(drifts, thumbnailDrift(vmeta, video)...)
+
+	resp, err := yt.ListCaption(*vmeta.VideoId)
+	if err != nil {
+		return nil, err
+	}
+	return append(drifts, captionDrift(vmeta, resp.Items)...), nil
+}
+
+// recordedTrack is a caption id the folder records: the main track's or
+// the one of an extra track.
+type recordedTrack struct {
+	lang, id string
+	set      func(vmeta *drapi.VideoMeta, id string)
+	upload   func(vmeta *drapi.VideoMeta) error
+}
+
+// recordedTracks lists the main track and every extra track the folder
+// records or would upload, by language.
+func recordedTracks(vmeta *drapi.VideoMeta) []recordedTrack {
+	tracks := []recordedTrack{{
+		lang: cfg.Caption.Language,
+		id:   deref(vmeta.CaptionId),
+		set:  func(vmeta *drapi.VideoMeta, id string) { setSptr(&vmeta.CaptionId, id) },
+		upload: func(vmeta *drapi.VideoMeta) error {
+			if !vmeta.HasCaption() {
+				return nil
+			}
+			return captionVideo(vmeta)
+		},
+	}}
+	langs := extraTracks(vmeta)
+	for lang := range vmeta.Captions {
+		if !hasLang(langs, lang) {
+			langs = append(langs, lang)
+		}
+	}
+	sort.Strings(langs)
+	for _, lang := range langs {
+		lang := lang
+		tracks = append(tracks, recordedTrack{
+			lang: lang,
+			id:   vmeta.Captions[lang],
+			set: func(vmeta *drapi.VideoMeta, id string) {
+				if vmeta.Captions == nil {
+					vmeta.Captions = make(map[string]string)
+				}
+				vmeta.Captions[lang] = id
+			},
+			upload: func(vmeta *drapi.VideoMeta) error {
+				if !hasLang(extraTracks(vmeta), lang) {
+					return nil
+				}
+				return captionTrack(vmeta, lang)
+			},
+		})
+	}
+	return tracks
+}
+
+// captionDrift compares each recorded caption id with the tracks on
+// YouTube. A recorded id YouTube no longer has is stale; a track the
+// folder does not record is an orphan, except the ones YouTube generates
+// by speech recognition. A single orphan in the language of a stale or
+// unset id is the track the folder should point at.
+func captionDrift(vmeta *drapi.VideoMeta, items []*youtube.Caption) []drift {
+	recorded := recordedTracks(vmeta)
+	onYouTube := make(map[string]bool)
+	for _, item := range items {
+		onYouTube[item.Id] = true
+	}
+	known := make(map[string]bool)
+	for _, r := range recorded {
+		known[r.id] = true
+	}
+	var orphans []*youtube.Caption
+	for _, item := range items {
+		if !known[item.Id] && item.Snippet.TrackKind != "asr" {
+			orphans = append(orphans, item)
+		}
+	}
+
+	var drifts []drift
+	// adoptedBy maps an orphan to the recorded track that should point at
+	// it; the ones a stale id adopts are not reported on their own
+	adoptedBy := make(map[string]recordedTrack)
+	staleAdopts := make(map[string]bool)
+	for _, r := range recorded {
+		r := r
+		stale := r.id != "" && !onYouTube[r.id]
+		var sameLang []*youtube.Caption
+		for _, o := range orphans {
+			if strings.EqualFold(o.Snippet.Language, r.lang) {
+				sameLang = append(sameLang, o)
+			}
+		}
+		adopt := ""
+		if (stale || r.id == "") && len(sameLang) == 1 {
+			adopt = sameLang[0].Id
+			adoptedBy[adopt] = r
+		}
+		if !stale {
+			continue
+		}
+		staleAdopts[adopt] = true
+		drifts = append(drifts, drift{
+			kind:   "stale caption",
+			detail: fmt.Sprintf("%s track %s is not on YouTube", r.lang, r.id),
+			fixDrive: func(vmeta *drapi.VideoMeta) {
+				r.set(vmeta, adopt)
+			},
+			fixYouTube: func(vmeta *drapi.VideoMeta) error {
+				r.set(vmeta, "")
+				return r.upload(vmeta)
+			},
+		})
+	}
+	for _, track := range orphans {
+		track := track
+		if staleAdopts[track.Id] {
+			// fixing the stale id adopts it
+			continue
+		}
+		d := drift{
+			kind:   "orphaned caption",
+			detail: fmt.Sprintf("%s %s %q is not recorded on the folder", track.Id, track.Snippet.Language, track.Snippet.Name),
+			fixYouTube: func(vmeta *drapi.VideoMeta) error {
+				return deleteOrphan(vmeta, track)
+			},
+		}
+		if r, ok := adoptedBy[track.Id]; ok {
+			d.fixDrive = func(vmeta *drapi.VideoMeta) { r.set(vmeta, track.Id) }
+		}
+		drifts = append(drifts, d)
+	}
+	return drifts
+}
+
+// deleteOrphan deletes a caption track the folder does not record, after
+// saving it into the folder.
+func deleteOrphan(vmeta *drapi.VideoMeta, track *youtube.Caption) (err error) {
+	rec := beginAudit(vmeta, "delete orphaned caption "+track.Snippet.Language)
+	defer rec.end(&err)
+	if _, err := pullCaptions(vmeta, func(c *youtube.Caption) bool { return c.Id == track.Id }); err != nil {
+		return err
+	}
+	if err := yt.DeleteCaption(track.Id); err != nil {
+		return err
+	}
+	rec.id("deletedCaptionId "+track.Snippet.Language, track.Id)
+	return nil
+}
+
+// thumbnailDrift compares the folder's cover with the one last set on
+// YouTube. YouTube does not tell a custom thumbnail from a generated one,
+// so a cover counts as set when its checksum was recorded as pushed and
+// the video has a thumbnail at all.
+func thumbnailDrift(vmeta *drapi.VideoMeta, video *youtube.Video) []drift {
+	cover, pushed := vmeta.Checksum(drapi.THUMBNAIL_FILE), vmeta.Pushed[drapi.THUMBNAIL_FILE]
+	onYouTube := video.Snippet != nil && video.Snippet.Thumbnails != nil && video.Snippet.Thumbnails.Default != nil
+	forget := func(vmeta *drapi.VideoMeta) { vmeta.Pushed[drapi.THUMBNAIL_FILE] = "" }
+	switch {
+	case cover == "" && pushed == "":
+		return nil
+	case cover == "":
+		return []drift{{
+			kind:     "thumbnail",
+			detail:   "the cover set on YouTube is no longer in the folder",
+			fixDrive: forget,
+		}}
+	case !onYouTube:
+		d := drift{
+			kind:       "missing thumbnail",
+			detail:     "the video has no thumbnail, the folder has a cover",
+			fixYouTube: coverVideo,
+		}
+		if pushed != "" {
+			d.fixDrive = forget
+		}
+		return []drift{d}
+	case pushed == "":
+		return []drift{{
+			kind:       "thumbnail",
+			detail:     "the folder's cover is not recorded as set on YouTube",
+			fixDrive:   func(vmeta *drapi.VideoMeta) { vmeta.MarkPushed(drapi.THUMBNAIL_FILE) },
+			fixYouTube: coverVideo,
+		}}
+	case pushed != cover:
+		return []drift{{
+			kind:       "thumbnail",
+			detail:     "the cover changed since it was set on YouTube",
+			fixYouTube: coverVideo,
+		}}
+	}
+	return nil
+}
+
+func describeStatus(privacy, publishAt string) string {
+	if privacy == "" {
+		privacy = "(none)"
+	}
+	if publishAt != "" {
+		return privacy + " until " + publishAt
+	}
+	return privacy
+}
+
+// pushStatus sets the privacy and release time recorded on the folder on
+// YouTube.
+func pushStatus(vmeta *drapi.VideoMeta) error {
+	switch deref(vmeta.Privacy) {
+	case PUBLIC.string():
+		return updateVideo(vmeta, PUBLIC)
+	case UNLISTED.string():
+		return updateVideo(vmeta, UNLISTED)
+	case PRIVATE.string():
+		if at, err := time.Parse(time.RFC3339, deref(vmeta.Scheduled)); err == nil && at.After(time.Now()) {
+			return scheduleVideo(vmeta, at)
+		}
+	}
+	return fmt.Errorf("%s: cannot set recorded status %s on YouTube", vmeta.FolderName(), describeStatus(deref(vmeta.Privacy), deref(vmeta.Scheduled)))
+}
+
+// reconcile reports how the folder's metadata differs from YouTube and,
+// when fix is "drive" or "youtube", repairs that side.
+func reconcile(name, fix string) (err error) {
+	// a report only reads the folder, fixes start from Drive's state
+	read := drv.GetVideoMeta
+	if fix == "" {
+		read = readMeta
+	}
+	vmeta, err := read(name)
+	if err != nil {
+		return err
+	}
+	defer vmeta.CleanUp()
+	if fix != "" {
+		rec := beginAudit(vmeta, "reconcile "+fix)
+		defer rec.end(&err)
+	}
+	drifts, err := checkDrift(vmeta)
+	if err != nil {
+		return err
+	}
+	if len(drifts) == 0 {
+		fmt.Printf("reconcile %s: in sync\n", name)
+		return nil
+	}
+	left := 0
+	for _, d := range drifts {
+		fmt.Printf("reconcile %s: %s: %s\n", name, d.kind, d.detail)
+		switch {
+		case fix == "drive" && d.fixDrive != nil:
+			d.fixDrive(vmeta)
+		case fix == "youtube" && d.fixYouTube != nil:
+			if err := d.fixYouTube(vmeta); err != nil {
+				return err
+			}
+		default:
+			if fix != "" {
+				fmt.Printf("reconcile %s: %s cannot be fixed on %s\n", name, d.kind, fix)
+			}
+			left++
+		}
+	}
+	if fix == "drive" {
+		if err := drv.UpdateVideoMeta(vmeta); err != nil {
+			return err
+		}
+	}
+	if left > 0 {
+		return fmt.Errorf("%s: %d mismatch(es): %w", name, left, errDrift)
+	}
+	return nil
+}
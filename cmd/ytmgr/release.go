@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"time"
+	drapi "twsati/internal/google/drive"
+)
+
+// releaseStep is one stage of publishing a clip folder. Completed steps are
+// recorded on the folder so a failed release resumes where it stopped.
+type releaseStep struct {
+	name string
+	// skip reports why the step does not apply to the folder, if it does not.
+	skip func(vmeta *drapi.VideoMeta) string
+	run  func(vmeta *drapi.VideoMeta) error
+}
+
+// releaseOptions are the flags of the release command.
+type releaseOptions struct {
+	chunkSize int64
+	restart   bool
+}
+
+func releaseSteps(opts releaseOptions) []releaseStep {
+	return []releaseStep{
+		{
+			name: "upload",
+			run: func(vmeta *drapi.VideoMeta) error {
+				// a video uploaded by hand earlier counts as this step
+				if hasVideo(vmeta) {
+					return nil
+				}
+				return uploadVideo(vmeta, uploadOptions{chunkSize: opts.chunkSize})
+			},
+		},
+		{
+			name: "thumbnail",
+			skip: func(vmeta *drapi.VideoMeta) string {
+				if !vmeta.HasExt(".png") && !vmeta.HasExt(".jpg") {
+					return "no .png or .jpg in the folder"
+				}
+				return ""
+			},
+			run: coverVideo,
+		},
+		{
+			name: "caption",
+			skip: func(vmeta *drapi.VideoMeta) string {
+				if !vmeta.HasCaption() {
+					return "no caption file in the folder"
+				}
+				return ""
+			},
+			run: captionEvery,
+		},
+		{
+			name: "playlist",
+			skip: func(vmeta *drapi.VideoMeta) string {
+				if cfg.Series[seriesOf(vmeta)].Playlist == "" {
+					return "no playlist configured for series " + seriesOf(vmeta)
+				}
+				return ""
+			},
+			run: addToPlaylist,
+		},
+		{
+			name: "publish",
+			run: func(vmeta *drapi.VideoMeta) error {
+				return updateVideo(vmeta, PUBLIC)
+			},
+		},
+	}
+}
+
+// addToPlaylist adds the folder's video to its series playlist unless it
+// is already there.
+func addToPlaylist(vmeta *drapi.VideoMeta) (err error) {
+	rec := beginAudit(vmeta, "playlist")
+	defer rec.end(&err)
+	if !hasVideo(vmeta) {
+		return fmt.Errorf("%s: %w", vmeta.FolderName(), errNoVideo)
+	}
+	series := cfg.Series[seriesOf(vmeta)]
+	items, err := yt.PlaylistsItemsAll("snippet", series.Playlist)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if item.Snippet.ResourceId.VideoId == *vmeta.VideoId {
+			fmt.Printf("video %s is already in playlist %s\n", *vmeta.VideoId, series.Playlist)
+			return nil
+		}
+	}
+	position := int64(len(items))
+	if series.PlaylistPosition == "first" {
+		position = 0
+	}
+	item, err := yt.PlaylistsItemInsert(series.Playlist, *vmeta.VideoId, position)
+	if err != nil {
+		return err
+	}
+	rec.id("playlistItemId", item.Id)
+	return nil
+}
+
+// release runs the release steps of a folder in order, skipping the ones
+// recorded as done, and records each step on the folder once it succeeds.
+func release(name string, opts releaseOptions) error {
+	vmeta, err := drv.GetVideoMeta(name)
+	if err != nil {
+		return err
+	}
+	// one temp dir for every step, so nothing is downloaded twice
+	defer vmeta.CleanUp()
+	if opts.restart {
+		vmeta.ResetRelease()
+	}
+	for _, step := range releaseSteps(opts) {
+		if vmeta.ReleaseDone(step.name) {
+			fmt.Printf("release %s: %s already done at %s\n", name, step.name, vmeta.Release[step.name])
+			continue
+		}
+		if step.skip != nil {
+			if reason := step.skip(vmeta); reason != "" {
+				fmt.Printf("release %s: skipping %s, %s\n", name, step.name, reason)
+				continue
+			}
+		}
+		fmt.Printf("release %s: %s\n", name, step.name)
+		if err := step.run(vmeta); err != nil {
+			return fmt.Errorf("release %s: %s: %w", name, step.name, err)
+		}
+		vmeta.MarkRelease(step.name, time.Now())
+		if err := drv.UpdateVideoMeta(vmeta); err != nil {
+			return fmt.Errorf("release %s: %s: %w", name, step.name, err)
+		}
+	}
+	fmt.Printf("release %s: done\n", name)
+	return nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	drapi "twsati/internal/google/drive"
+
+	// Windows has no zoneinfo database for names like Asia/Taipei.
+	_ "time/tzdata"
+)
+
+// scheduleLayout is the -at format without a zone name.
+const scheduleLayout = "2006-01-02 15:04"
+
+var errNotScheduled = errors.New("video not scheduled")
+
+// parseScheduleTime reads "2006-01-02 15:04 [ZONE]" in the named zone, or
+// the local one when it is omitted, as well as RFC 3339. YouTube only
+// accepts release times in the future.
+func parseScheduleTime(str string, now time.Time) (time.Time, error) {
+	at, err := time.Parse(time.RFC3339, str)
+	if err != nil {
+		fields := strings.Fields(str)
+		loc := time.Local
+		if len(fields) == 3 {
+			if loc, err = time.LoadLocation(fields[2]); err != nil {
+				return time.Time{}, fmt.Errorf("%w: unknown time zone %q", errUsage, fields[2])
+			}
+			fields = fields[:2]
+		}
+		if len(fields) != 2 {
+			return time.Time{}, fmt.Errorf("%w: release time %q, want %q with an optional zone name", errUsage, str, scheduleLayout)
+		}
+		if at, err = time.ParseInLocation(scheduleLayout, fields[0]+" "+fields[1], loc); err != nil {
+			return time.Time{}, fmt.Errorf("%w: release time %q, want %q with an optional zone name", errUsage, str, scheduleLayout)
+		}
+	}
+	if !at.After(now) {
+		return time.Time{}, fmt.Errorf("%w: release time %s is in the past", errUsage, at.Format(time.RFC3339))
+	}
+	return at, nil
+}
+
+func youtubeSchedule(name string, at time.Time) error {
+	vmeta, err := drv.GetVideoMeta(name)
+	if err != nil {
+		return err
+	}
+	defer vmeta.CleanUp()
+	return scheduleVideo(vmeta, at)
+}
+
+func scheduleVideo(vmeta *drapi.VideoMeta, at time.Time) (err error) {
+	rec := beginAudit(vmeta, "schedule")
+	defer rec.end(&err)
+	if !hasVideo(vmeta) {
+		return fmt.Errorf("%s: %w", vmeta.FolderName(), errNoVideo)
+	}
+	title, desc, err := wrapVideo(vmeta)
+	if err != nil {
+		return err
+	}
+	ytId, err := yt.ScheduleVideo(*vmeta.VideoId, title, desc, cfg.CategoryId, cfg.Keywords(), at)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("scheduled youtube video: %s id: %s, public at %s\n", vmeta.Title, ytId, at.Format(time.RFC3339))
+	rec.id("publishAt", at.UTC().Format(time.RFC3339))
+	setSptr(&vmeta.Privacy, PRIVATE.string())
+	setSptr(&vmeta.Scheduled, at.UTC().Format(time.RFC3339))
+	vmeta.MarkPushed(drapi.DESCRIPTION_FILE)
+	return drv.UpdateVideoMeta(vmeta)
+}
+
+// youtubeCancelSchedule drops the pending release and leaves the video
+// unlisted.
+func youtubeCancelSchedule(name string) error {
+	vmeta, err := drv.GetVideoMeta(name)
+	if err != nil {
+		return err
+	}
+	defer vmeta.CleanUp()
+	if vmeta.Scheduled == nil || *vmeta.Scheduled == "" {
+		return fmt.Errorf("%s: %w", name, errNotScheduled)
+	}
+	return updateVideo(vmeta, UNLISTED)
+}
+
+// scheduled is a folder with a pending release.
+type scheduled struct {
+	vmeta *drapi.VideoMeta
+	at    time.Time
+}
+
+// listSchedule prints the scheduled releases in time order; past ones,
+// which YouTube has published by now, only when all is set.
+func listSchedule(all bool, now time.Time) error {
+	metas, err := scheduledMetas()
+	if err != nil {
+		return err
+	}
+	var list []scheduled
+	for _, vmeta := range metas {
+		if vmeta.Scheduled == nil || *vmeta.Scheduled == "" {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, *vmeta.Scheduled)
+		if err != nil {
+			return fmt.Errorf("%s: %s %q: %w", vmeta.FolderName(), drapi.SCHEDULED, *vmeta.Scheduled, err)
+		}
+		if all || at.After(now) {
+			list = append(list, scheduled{vmeta, at})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].at.Before(list[j].at) })
+	if len(list) == 0 {
+		fmt.Println("no scheduled releases")
+	}
+	for _, s := range list {
+		videoId := ""
+		if s.vmeta.VideoId != nil {
+			videoId = *s.vmeta.VideoId
+		}
+		mark := ""
+		if !s.at.After(now) {
+			mark = " (past)"
+		}
+		fmt.Printf("%s%s  %s  %s\n", s.at.Local().Format(scheduleLayout+" MST"), mark, videoId, s.vmeta.FolderName())
+	}
+	return nil
+}
@@ -0,0 +1,496 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	drapi "twsati/internal/google/drive"
+	ytapi "twsati/internal/google/youtube"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// ytId := ytapi.UploadVideo(upld.Title, upld.Transcript, "27", "meditation", videoPath)
+// upld.VideoId = ytId
+// upld.Privacy = "unlisted"
+// updateVideoId(db, upld)
+// updatePrivacy(db, upld)
+
+// templateData is what the title and description templates see: every
+// VideoMeta field plus the folder's description text and the configured
+// footer.
+type templateData struct {
+	*drapi.VideoMeta
+	Content string
+	Footer  string
+	Series  string
+}
+
+// renderVideoText renders the title and description of vmeta with the
+// templates of series, or of the series selected for the folder when
+// series is empty.
+func renderVideoText(vmeta *drapi.VideoMeta, series, content string) (string, string, error) {
+	if series == "" {
+		series = seriesOf(vmeta)
+	}
+	set, err := cfg.Templates(series)
+	if err != nil {
+		return "", "", err
+	}
+	data := templateData{VideoMeta: vmeta, Content: content, Footer: cfg.DescriptionFooter, Series: series}
+	title, err := set.Title(data)
+	if err != nil {
+		return "", "", err
+	}
+	desc, err := set.Description(data)
+	if err != nil {
+		return "", "", err
+	}
+	return title, desc, nil
+}
+
+// seriesOf is the series whose settings apply to the folder.
+func seriesOf(vmeta *drapi.VideoMeta) string {
+	folderSeries := ""
+	if vmeta.Series != nil {
+		folderSeries = *vmeta.Series
+	}
+	return cfg.SeriesFor(vmeta.FolderName(), folderSeries)
+}
+
+func wrapVideo(vmeta *drapi.VideoMeta) (string, string, error) {
+	content, err := vmeta.DescriptionContent()
+	if err != nil {
+		return "", "", err
+	}
+	return renderVideoText(vmeta, "", content)
+}
+
+// previewVideo prints the rendered title and description of a folder.
+// Unless fromDrive is set nothing is fetched: the metadata comes from the
+// folder name and the description text from contentFile.
+func previewVideo(name, series, contentFile string, fromDrive bool) error {
+	var vmeta *drapi.VideoMeta
+	var err error
+	content := ""
+	if fromDrive {
+		if vmeta, err = drv.GetVideoMeta(name); err != nil {
+			return err
+		}
+		defer vmeta.CleanUp()
+		if content, err = vmeta.DescriptionContent(); err != nil {
+			return err
+		}
+	} else if vmeta, err = drapi.MetaFromName(name); err != nil {
+		return err
+	}
+	if contentFile != "" {
+		payload, err := os.ReadFile(contentFile)
+		if err != nil {
+			return err
+		}
+		content = string(payload)
+	}
+	title, desc, err := renderVideoText(vmeta, series, content)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s\n\n%s\n", title, desc)
+	return nil
+}
+
+// errNoVideo is returned by operations that need an uploaded video.
+var errNoVideo = errors.New("no video uploaded")
+
+// errVideoExists is returned by upload when the folder already has a video.
+var errVideoExists = errors.New("video already uploaded")
+
+func hasVideo(vmeta *drapi.VideoMeta) bool {
+	return vmeta.VideoId != nil && len(strings.TrimSpace(*vmeta.VideoId)) > 0
+}
+
+// setMeta overwrites the fields whose value is not nil and keeps the
+// others as the folder records them.
+func setMeta(name string, vidId *string, capId *string, privacy *string, series *string) (err error) {
+
+	vmeta, err := drv.GetVideoMeta(name)
+	if err != nil {
+		return err
+	}
+	rec := beginAudit(vmeta, "set meta")
+	defer rec.end(&err)
+	if vidId != nil {
+		vmeta.VideoId = vidId
+	}
+	if capId != nil {
+		vmeta.CaptionId = capId
+	}
+	if privacy != nil {
+		vmeta.Privacy = privacy
+	}
+	if series != nil {
+		vmeta.Series = series
+	}
+	return drv.UpdateVideoMeta(vmeta)
+
+}
+
+func dumpMeta(name string) error {
+	if entry := cachedMeta(name); entry != nil {
+		fmt.Println("# from the catalog, synced", entry.SyncedAt.Local().Format(time.RFC3339))
+		printChanged(entry.VideoMeta)
+		fmt.Println(prettyPrint(entry.VideoMeta))
+		return nil
+	}
+	vmeta, err := drv.GetVideoMeta(name)
+	if err != nil {
+		return err
+	}
+	printChanged(vmeta)
+	// vmeta.CaptionPath()
+	// defer vmeta.CleanUp()
+
+	// vmeta.VideoId = "EViH9AYi6UM"
+	// vmeta.Privacy = "unlisted"
+	// drapi.UpdateVideoMeta(vmeta)
+	// fmt.Printf("%s\n", prettyPrint(vmeta))
+	// fmt.Printf("%+v\n", vmeta)
+	fmt.Println(prettyPrint(vmeta))
+	return nil
+}
+
+// printChanged notes the files that differ from the ones on YouTube.
+func printChanged(vmeta *drapi.VideoMeta) {
+	if changed := vmeta.Changed(); len(changed) > 0 {
+		fmt.Println("# changed since last publish:", strings.Join(changed, ", "))
+	}
+}
+
+// skipUnchanged reports, and says so, when the file of kind is the one
+// already on YouTube.
+func skipUnchanged(vmeta *drapi.VideoMeta, kind string) bool {
+	if !vmeta.Unchanged(kind) {
+		return false
+	}
+	fmt.Printf("%s: %s unchanged since it was pushed, skipped (-force pushes it anyway)\n", vmeta.FolderName(), kind)
+	return true
+}
+
+// youtubeDeleteCaption deletes the folder's caption track in lang, or
+// every track of the video when lang is empty.
+func youtubeDeleteCaption(name, lang string) (err error) {
+	vmeta, err := drv.GetVideoMeta(name)
+	if err != nil {
+		return err
+	}
+	if lang != "" {
+		if !hasVideo(vmeta) {
+			return fmt.Errorf("%s: %w", name, errNoVideo)
+		}
+		return deleteTrack(vmeta, lang)
+	}
+	rec := beginAudit(vmeta, "delete caption")
+	defer rec.end(&err)
+	if !hasVideo(vmeta) {
+		return fmt.Errorf("%s: %w", name, errNoVideo)
+	}
+	// keep a copy of every track, Studio edits included
+	if _, err := pullCaptions(vmeta, func(*youtube.Caption) bool { return true }); err != nil {
+		return err
+	}
+	resp, err := yt.ListCaption(*vmeta.VideoId)
+	if err != nil {
+		return err
+	}
+	for _, item := range resp.Items {
+		if err := yt.DeleteCaption(item.Id); err != nil {
+			return err
+		}
+		rec.id("deletedCaptionId "+item.Snippet.Language, item.Id)
+		if !activePlan.Active() {
+			fmt.Printf("successfully deleted youtube video caption %s id: %s  for video %s\n", item.Snippet.Language, item.Id, vmeta.Title)
+		}
+	}
+	setSptr(&vmeta.CaptionId, "")
+	vmeta.ResetCaptions()
+	for kind := range vmeta.Pushed {
+		if kind == drapi.CAPTION_FILE || strings.HasPrefix(kind, drapi.CAPTION_FILE+"_") {
+			vmeta.Pushed[kind] = ""
+		}
+	}
+	return drv.UpdateVideoMeta(vmeta)
+}
+
+// youtubeCaption uploads the folder's caption tracks, or only the one in
+// lang when it is not empty.
+func youtubeCaption(name, lang string, force bool) error {
+	vmeta, err := drv.GetVideoMeta(name)
+	if err != nil {
+		return err
+	}
+	defer vmeta.CleanUp()
+	if !hasVideo(vmeta) {
+		return fmt.Errorf("%s: %w", vmeta.FolderName(), errNoVideo)
+	}
+	return uploadCaptions(vmeta, lang, force)
+}
+
+// captionVideo uploads the folder's caption unless it has lint errors.
+func captionVideo(vmeta *drapi.VideoMeta) error {
+	if err := checkLint(vmeta, false); err != nil {
+		return err
+	}
+	return uploadCaption(vmeta)
+}
+
+// uploadCaption uploads the folder's caption as it is.
+func uploadCaption(vmeta *drapi.VideoMeta) (err error) {
+	rec := beginAudit(vmeta, "caption")
+	defer rec.end(&err)
+	if !hasVideo(vmeta) {
+		return fmt.Errorf("%s: %w", vmeta.FolderName(), errNoVideo)
+	}
+	captionId := ""
+	if vmeta.CaptionId != nil {
+		captionId = *vmeta.CaptionId
+	}
+	captionPath, err := vmeta.CaptionPath()
+	if err != nil {
+		return err
+	}
+	captionId, err = yt.UploadCaption(captionId, *vmeta.VideoId, cfg.Caption.Language, cfg.Caption.Name, captionPath)
+	if err != nil {
+		return err
+	}
+	rec.id("captionId", captionId)
+	setSptr(&vmeta.CaptionId, captionId)
+	vmeta.MarkPushed(drapi.CAPTION_FILE)
+	fmt.Println("updated youtube video caption id: ", *vmeta.CaptionId)
+	return drv.UpdateVideoMeta(vmeta)
+}
+
+type privacy int
+
+const (
+	UNLISTED privacy = iota
+	PUBLIC
+	PRIVATE
+)
+
+func (p privacy) string() string {
+	return []string{"unlisted", "public", "private"}[p]
+}
+
+func youtubeUpdateVideo(name string, priv privacy) error {
+	vmeta, err := drv.GetVideoMeta(name)
+	if err != nil {
+		return err
+	}
+	defer vmeta.CleanUp()
+	return updateVideo(vmeta, priv)
+}
+
+func updateVideo(vmeta *drapi.VideoMeta, priv privacy) (err error) {
+	rec := beginAudit(vmeta, "privacy "+priv.string())
+	defer rec.end(&err)
+	if !hasVideo(vmeta) {
+		return fmt.Errorf("%s: %w", vmeta.FolderName(), errNoVideo)
+	}
+	title, desc, err := wrapVideo(vmeta)
+	if err != nil {
+		return err
+	}
+	ytId, err := yt.UpdateVideo(*vmeta.VideoId, title, desc, cfg.CategoryId, priv.string(), cfg.Keywords())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("updated youtube video: %s id: %s, status: %s\n", vmeta.Title, ytId, priv.string())
+	setSptr(&vmeta.Privacy, priv.string())
+	vmeta.MarkPushed(drapi.DESCRIPTION_FILE)
+	if vmeta.Scheduled != nil {
+		// the new status replaces any pending schedule
+		setSptr(&vmeta.Scheduled, "")
+	}
+	return drv.UpdateVideoMeta(vmeta)
+
+}
+
+// updateDescription pushes the folder's title, description and tags and
+// keeps the video's privacy and release time as they are.
+func updateDescription(vmeta *drapi.VideoMeta) (err error) {
+	rec := beginAudit(vmeta, "description")
+	defer rec.end(&err)
+	if !hasVideo(vmeta) {
+		return fmt.Errorf("%s: %w", vmeta.FolderName(), errNoVideo)
+	}
+	title, desc, err := wrapVideo(vmeta)
+	if err != nil {
+		return err
+	}
+	ytId, err := yt.UpdateSnippet(*vmeta.VideoId, title, desc, cfg.CategoryId, cfg.Keywords())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("updated youtube video: %s id: %s, description\n", vmeta.Title, ytId)
+	vmeta.MarkPushed(drapi.DESCRIPTION_FILE)
+	return drv.UpdateVideoMeta(vmeta)
+}
+
+func youtubeUploadCover(name string, force bool) error {
+	vmeta, err := drv.GetVideoMeta(name)
+	if err != nil {
+		return err
+	}
+	// vmeta.SetTempDir(`C:\Users\kaile\AppData\Local\Temp\生命中別投降別氣餒2919784654`)
+	defer vmeta.CleanUp()
+	if !force && hasVideo(vmeta) && skipUnchanged(vmeta, drapi.THUMBNAIL_FILE) {
+		return nil
+	}
+	return coverVideo(vmeta)
+}
+
+func coverVideo(vmeta *drapi.VideoMeta) (err error) {
+	rec := beginAudit(vmeta, "thumbnail")
+	defer rec.end(&err)
+	if !hasVideo(vmeta) {
+		return fmt.Errorf("%s: %w", vmeta.FolderName(), errNoVideo)
+	}
+	thumbnail, err := vmeta.ThumbnailPath()
+	if err != nil {
+		return err
+	}
+	rec.id("videoId", *vmeta.VideoId)
+	if err := yt.UploadCover(*vmeta.VideoId, thumbnail); err != nil {
+		return err
+	}
+	vmeta.MarkPushed(drapi.THUMBNAIL_FILE)
+	return drv.UpdateVideoMeta(vmeta)
+}
+
+// uploadOptions are the flags of video upload.
+type uploadOptions struct {
+	// replace deletes an existing video, asking first unless yes is set,
+	// when the .mp4 changed since it was uploaded or force is set.
+	replace, yes, force bool
+	chunkSize           int64
+}
+
+func youtubeUpload(name string, opts uploadOptions) error {
+	vmeta, err := drv.GetVideoMeta(name)
+	if err != nil {
+		return err
+	}
+	// vmeta.SetTempDir(`C:\Users\kaile\AppData\Local\Temp\生命中別投降別氣餒2919784654`)
+	defer vmeta.CleanUp()
+	return uploadVideo(vmeta, opts)
+}
+
+// uploadVideo uploads the folder's video. An existing video is deleted as
+// deleteVideo does.
+func uploadVideo(vmeta *drapi.VideoMeta, opts uploadOptions) (err error) {
+	// ytapi.UploadVideo()
+	if hasVideo(vmeta) {
+		if !opts.replace {
+			return fmt.Errorf("%s: %w: %s", vmeta.FolderName(), errVideoExists, *vmeta.VideoId)
+		}
+		if !opts.force && skipUnchanged(vmeta, drapi.VIDEO_FILE) {
+			return nil
+		}
+	}
+	rec := beginAudit(vmeta, "upload")
+	defer rec.end(&err)
+	if hasVideo(vmeta) {
+		if err := deleteVideo(vmeta, opts.yes, rec); err != nil {
+			return err
+		}
+	}
+
+	description, err := vmeta.DescriptionContent()
+	if err != nil {
+		return err
+	}
+	// if description == "" {
+	// 	description = "empty description"
+	// }
+	// fmt.Printf("%+v\n %s\n", vmeta, description)
+	videoPath, err := vmeta.VideoFilePath()
+	if err != nil {
+		return err
+	}
+	sessionFile, err := uploadSessionFile(vmeta)
+	if err != nil {
+		return err
+	}
+	vidId, err := yt.UploadVideo(vmeta.Title, description, cfg.CategoryId, cfg.Keywords(), videoPath, ytapi.UploadOptions{
+		ChunkSize:   opts.chunkSize,
+		SessionFile: sessionFile,
+		Progress:    uploadProgress(vmeta),
+	})
+	if workers <= 1 {
+		fmt.Println()
+	}
+	if err != nil {
+		return err
+	}
+	// upld.VideoId = ytId
+	// upld.Privacy = "unlisted"
+	// updateVideoId(db, upld)
+	// updatePrivacy(db, upld)
+	rec.id("videoId", vidId)
+	setSptr(&vmeta.VideoId, vidId)
+	setSptr(&vmeta.Privacy, "unlisted")
+	vmeta.MarkPushed(drapi.VIDEO_FILE)
+	vmeta.MarkPushed(drapi.DESCRIPTION_FILE)
+	return drv.UpdateVideoMeta(vmeta)
+
+}
+
+// uploadProgress redraws a single progress line, unless several folders
+// upload at once: their \r lines would overwrite each other, so each
+// chunk then gets a whole line prefixed with the folder name.
+func uploadProgress(vmeta *drapi.VideoMeta) func(ytapi.Progress) {
+	if workers > 1 {
+		return func(p ytapi.Progress) {
+			fmt.Printf("%s: uploading %s\n", vmeta.FolderName(), p)
+		}
+	}
+	return func(p ytapi.Progress) {
+		fmt.Printf("\ruploading %s: %s   ", vmeta.Title, p)
+	}
+}
+
+// uploadSessionFile keeps one resumable upload session per Drive folder,
+// so an interrupted upload continues where it stopped on the next run.
+func uploadSessionFile(vmeta *drapi.VideoMeta) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "ytmgr", "uploads", vmeta.FolderId+".json"), nil
+}
+
+func youtubeDelete(name string, yes bool) (err error) {
+	vmeta, err := drv.GetVideoMeta(name)
+	if err != nil {
+		return err
+	}
+	// vmeta.SetTempDir(`C:\Users\kaile\AppData\Local\Temp\生命中別投降別氣餒2919784654`)
+	defer vmeta.CleanUp()
+	// ytapi.UploadVideo()
+	if !hasVideo(vmeta) {
+		return fmt.Errorf("unable to delete video: %s: %w", name, errNoVideo)
+	}
+	videoId := *vmeta.VideoId
+	rec := beginAudit(vmeta, "delete video")
+	defer rec.end(&err)
+	if err := deleteVideo(vmeta, yes, rec); err != nil {
+		return err
+	}
+	if !activePlan.Active() {
+		fmt.Println("successfully deleted video: " + videoId)
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"twsati/internal/audit"
+	drapi "twsati/internal/google/drive"
+	ytapi "twsati/internal/google/youtube"
+	"twsati/internal/subtitle"
+)
+
+// watchTokenFile keeps the position in the Drive changes feed between
+// runs.
+func watchTokenFile() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "ytmgr", "watch-token"), nil
+}
+
+// pendingFolder is a clip folder with changed files not acted on yet.
+type pendingFolder struct {
+	name  string
+	files map[string]bool
+	last  time.Time
+}
+
+// watcher follows the Drive changes feed and collects the changed files
+// of each clip folder until the folder settles.
+type watcher struct {
+	tokenFile string
+	token     string
+	// folders maps the parent ids seen so far to the clip folder name,
+	// "" for folders that are not clip folders.
+	folders map[string]string
+	pending map[string]*pendingFolder
+}
+
+// newWatcher continues from the saved token, or starts watching from now
+// on the first run.
+func newWatcher() (*watcher, error) {
+	path, err := watchTokenFile()
+	if err != nil {
+		return nil, err
+	}
+	w := &watcher{tokenFile: path, folders: map[string]string{}, pending: map[string]*pendingFolder{}}
+	content, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	w.token = strings.TrimSpace(string(content))
+	if w.token == "" {
+		if w.token, err = drv.StartPageToken(); err != nil {
+			return nil, err
+		}
+		fmt.Println("watch: following Drive changes from now on")
+		if err := w.save(); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// save persists the token. It only moves past changes whose folders have
+// been acted on, so a restart picks up what was still pending.
+func (w *watcher) save() error {
+	if activePlan.Active() || len(w.pending) > 0 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(w.tokenFile), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(w.tokenFile, []byte(w.token+"\n"), 0600)
+}
+
+// clipFolder returns the name of the clip folder id, or "" when id is
+// not one.
+func (w *watcher) clipFolder(id string) (string, error) {
+	if name, ok := w.folders[id]; ok {
+		return name, nil
+	}
+	folder, err := drv.Folder(id)
+	if err != nil {
+		return "", err
+	}
+	name := ""
+	if drapi.IsFolder(folder) && !folder.Trashed {
+		if _, err := drapi.MetaFromName(folder.Name); err == nil {
+			name = folder.Name
+		}
+	}
+	w.folders[id] = name
+	return name, nil
+}
+
+// poll reads the changes since the last poll and marks the clip folders
+// they touch as changed at now.
+func (w *watcher) poll(now time.Time) error {
+	changes, next, err := drv.Changes(w.token)
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		f := change.File
+		// removals, folders themselves and ytmgr's own log are no news
+		if change.Removed || f == nil || f.Trashed || drapi.IsFolder(f) || f.Name == audit.FileName {
+			continue
+		}
+		for _, parent := range f.Parents {
+			name, err := w.clipFolder(parent)
+			if err != nil {
+				return err
+			}
+			if name == "" {
+				continue
+			}
+			p := w.pending[parent]
+			if p == nil {
+				p = &pendingFolder{name: name, files: map[string]bool{}}
+				w.pending[parent] = p
+			}
+			p.files[f.Name] = true
+			p.last = now
+		}
+	}
+	w.token = next
+	return w.save()
+}
+
+// settle acts on the folders left unchanged for quiet, in name order.
+// Folders that fail are reported and dropped, except when the YouTube
+// quota runs out: then everything stays pending for the next poll.
+func (w *watcher) settle(now time.Time, quiet time.Duration) error {
+	var ready []string
+	for id, p := range w.pending {
+		if now.Sub(p.last) >= quiet {
+			ready = append(ready, id)
+		}
+	}
+	sort.Slice(ready, func(i, j int) bool { return w.pending[ready[i]].name < w.pending[ready[j]].name })
+	var first error
+	for _, id := range ready {
+		p := w.pending[id]
+		err := watchAct(p)
+		if errors.Is(err, ytapi.ErrQuotaExceeded) {
+			return err
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "watch %s: %v\n", p.name, err)
+			if first == nil {
+				first = err
+			}
+		}
+		delete(w.pending, id)
+	}
+	if err := w.save(); err != nil {
+		return err
+	}
+	return first
+}
+
+// watchAct runs the configured actions for the changed files of a folder.
+func watchAct(p *pendingFolder) error {
+	var files []string
+	for name := range p.files {
+		files = append(files, name)
+	}
+	sort.Strings(files)
+	fmt.Printf("watch %s: changed %s\n", p.name, strings.Join(files, ", "))
+	changed := func(exts ...string) bool {
+		for _, name := range files {
+			for _, ext := range exts {
+				if strings.HasSuffix(name, ext) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	vmeta, err := drv.GetVideoMeta(p.name)
+	if err != nil {
+		return err
+	}
+	defer vmeta.CleanUp()
+	pushCaption := cfg.Watch.Caption && changed(subtitle.Exts()...)
+	pushDescription := cfg.Watch.Description && changed(".txt") && !vmeta.Unchanged(drapi.DESCRIPTION_FILE)
+	if (pushCaption || pushDescription) && !hasVideo(vmeta) {
+		fmt.Printf("watch %s: no video uploaded yet, nothing to push\n", p.name)
+	} else {
+		if pushCaption {
+			if err := uploadCaptions(vmeta, "", false); err != nil {
+				return err
+			}
+		}
+		if pushDescription {
+			if err := updateDescription(vmeta); err != nil {
+				return err
+			}
+		}
+	}
+	if len(cfg.Watch.Notify) > 0 {
+		return notify(p.name, files)
+	}
+	return nil
+}
+
+// notify runs the configured notify command for a settled folder.
+func notify(name string, files []string) error {
+	args := append(append(append([]string{}, cfg.Watch.Notify[1:]...), name), files...)
+	cmd := exec.Command(cfg.Watch.Notify[0], args...)
+	if activePlan.Record("local", "notify", name, strings.Join(cmd.Args, " ")) {
+		return nil
+	}
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("notify: %w", err)
+	}
+	return nil
+}
+
+// watch polls the changes feed every configured interval until
+// interrupted. With once it reads the pending changes a single time and
+// acts on them without waiting for the folders to settle.
+func watch(once bool) error {
+	w, err := newWatcher()
+	if err != nil {
+		return err
+	}
+	quiet := cfg.Watch.QuietPeriod()
+	if once || activePlan.Active() {
+		if err := w.poll(time.Now()); err != nil {
+			return err
+		}
+		return w.settle(time.Now(), 0)
+	}
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+	defer signal.Stop(stop)
+	fmt.Printf("watch: polling every %s, acting after %s without changes; Ctrl+C stops\n", cfg.Watch.Interval, cfg.Watch.Debounce)
+	for {
+		if err := w.poll(time.Now()); err != nil {
+			fmt.Fprintln(os.Stderr, "watch:", err)
+		} else if err := w.settle(time.Now(), quiet); errors.Is(err, ytapi.ErrQuotaExceeded) {
+			fmt.Fprintln(os.Stderr, "watch:", err)
+		}
+		select {
+		case <-stop:
+			fmt.Println("watch: stopped")
+			return nil
+		case <-time.After(cfg.Watch.PollInterval()):
+		}
+	}
+}
@@ -0,0 +1,1386 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"twsati/internal/audit"
+	"twsati/internal/catalog"
+	"twsati/internal/config"
+	drapi "twsati/internal/google/drive"
+	"twsati/internal/google/fake"
+	"twsati/internal/google/retry"
+	ytapi "twsati/internal/google/youtube"
+	"twsati/internal/plan"
+	"twsati/internal/subtitle"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+const clipName = "zh230114_[37.34-38.51]_生命中別投降別氣餒"
+
+// useFake makes newClients talk to a fresh fake backend and points the
+// drv and yt globals at it.
+func useFake(t *testing.T) *fake.Server {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	srv := fake.NewServer()
+	t.Cleanup(srv.Close)
+	policy := &retry.Policy{MaxAttempts: 2, Sleep: func(time.Duration) {}}
+	saved := newClients
+	newClients = func(p *plan.Plan) (*drapi.Client, *ytapi.Client, error) {
+		dopts := drapi.Options{HTTPClient: srv.HTTPClient(), Endpoint: srv.DriveEndpoint(), Retry: policy, Plan: p}
+		if clipCatalog != nil {
+			dopts.Cache = clipCatalog
+		}
+		d, err := drapi.NewClient(dopts)
+		if err != nil {
+			return nil, nil, err
+		}
+		y, err := ytapi.NewClient(ytapi.Options{HTTPClient: srv.HTTPClient(), Endpoint: srv.YouTubeEndpoint(), Retry: policy, Plan: p})
+		return d, y, err
+	}
+	t.Cleanup(func() { newClients, drv, yt = saved, nil, nil })
+	var err error
+	if drv, yt, err = newClients(nil); err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+// trackOf returns the video's caption track in lang, nil if there is none.
+func trackOf(srv *fake.Server, videoId, lang string) *youtube.Caption {
+	for _, c := range srv.Captions(videoId) {
+		if c.Snippet.Language == lang {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestYoutubeUpload(t *testing.T) {
+	srv := useFake(t)
+	folder := srv.AddFolder(clipName, nil)
+	media := bytes.Repeat([]byte("video"), 1000)
+	srv.AddFile(folder, clipName+".mp4", media, time.Time{})
+	srv.AddFile(folder, clipName+".txt", []byte("說明"), time.Time{})
+
+	if err := youtubeUpload(clipName, uploadOptions{chunkSize: ytapi.DefaultChunkSize}); err != nil {
+		t.Fatal(err)
+	}
+	props := srv.File(folder).AppProperties
+	video := srv.Video(props[drapi.VIDEO_ID])
+	if video == nil {
+		t.Fatalf("no video recorded, app properties %v", props)
+	}
+	if video.Snippet.Title != "生命中別投降別氣餒" || video.Snippet.Description != "說明" || video.Snippet.CategoryId != "27" {
+		t.Errorf("unexpected snippet %+v", video.Snippet)
+	}
+	if len(video.Snippet.Tags) != 1 || video.Snippet.Tags[0] != "meditation" {
+		t.Errorf("tags = %q", video.Snippet.Tags)
+	}
+	if !bytes.Equal(srv.VideoContent(video.Id), media) {
+		t.Error("uploaded media differs from the Drive file")
+	}
+	if props[drapi.PRIVACY] != "unlisted" {
+		t.Errorf("privacy = %q", props[drapi.PRIVACY])
+	}
+
+	if err := youtubeUpload(clipName, uploadOptions{chunkSize: ytapi.DefaultChunkSize}); !errors.Is(err, errVideoExists) {
+		t.Errorf("expected errVideoExists, got %v", err)
+	}
+	if err := youtubeUpload(clipName, uploadOptions{replace: true, yes: true, force: true, chunkSize: ytapi.DefaultChunkSize}); err != nil {
+		t.Fatal(err)
+	}
+	if ids := srv.VideoIds(); len(ids) != 1 || ids[0] == video.Id {
+		t.Errorf("re-upload should replace %s, videos now %v", video.Id, ids)
+	}
+}
+
+func TestYoutubeCaption(t *testing.T) {
+	srv := useFake(t)
+	videoId := srv.AddVideo("生命中別投降別氣餒", "unlisted")
+	folder := srv.AddFolder(clipName, map[string]string{drapi.VIDEO_ID: videoId})
+	srv.AddFile(folder, clipName+".srt", []byte("1\n00:00:01,000 --> 00:00:02,000\n你好\n"), time.Time{})
+
+	if err := youtubeCaption(clipName, "", false); err != nil {
+		t.Fatal(err)
+	}
+	// the main track and the simplified one converted from it
+	main, simplified := trackOf(srv, videoId, "zh-tw"), trackOf(srv, videoId, "zh-cn")
+	if n := len(srv.Captions(videoId)); n != 2 || main == nil || simplified == nil {
+		t.Fatalf("captions = %+v", srv.Captions(videoId))
+	}
+	props := srv.File(folder).AppProperties
+	if props[drapi.CAPTION_ID] != main.Id || props[drapi.TrackKind("zh-cn")] != simplified.Id {
+		t.Errorf("caption ids = %v, want %s and %s", props, main.Id, simplified.Id)
+	}
+
+	// a second run replaces the track in place
+	srv.AddFile(folder, "fixed.srt", []byte("1\n00:00:01,000 --> 00:00:02,000\n您好\n"), time.Now().Add(time.Hour))
+	if err := youtubeCaption(clipName, "", false); err != nil {
+		t.Fatal(err)
+	}
+	if main := trackOf(srv, videoId, "zh-tw"); len(srv.Captions(videoId)) != 2 || main == nil || !bytes.Contains(srv.CaptionContent(main.Id), []byte("您好")) {
+		t.Errorf("caption not updated: %+v", srv.Captions(videoId))
+	}
+
+	if err := youtubeDeleteCaption(clipName, ""); err != nil {
+		t.Fatal(err)
+	}
+	if captions := srv.Captions(videoId); len(captions) != 0 {
+		t.Errorf("captions left after delete: %+v", captions)
+	}
+}
+
+func TestYoutubeCaptionConfigured(t *testing.T) {
+	srv := useFake(t)
+	cfg.Caption.Language, cfg.Caption.Name, cfg.Caption.Simplified = "en", "English", ""
+	t.Cleanup(func() { cfg = config.Defaults() })
+	videoId := srv.AddVideo("生命中別投降別氣餒", "unlisted")
+	folder := srv.AddFolder(clipName, map[string]string{drapi.VIDEO_ID: videoId})
+	srv.AddFile(folder, clipName+".srt", []byte("1\n00:00:01,000 --> 00:00:02,000\nhello\n"), time.Time{})
+	if err := youtubeCaption(clipName, "", false); err != nil {
+		t.Fatal(err)
+	}
+	if captions := srv.Captions(videoId); len(captions) != 1 || captions[0].Snippet.Language != "en" || captions[0].Snippet.Name != "English" {
+		t.Errorf("captions = %+v", captions)
+	}
+}
+
+func TestYoutubeCaptionNoVideo(t *testing.T) {
+	srv := useFake(t)
+	srv.AddFolder(clipName, nil)
+	if err := youtubeCaption(clipName, "", false); !errors.Is(err, errNoVideo) {
+		t.Errorf("expected errNoVideo, got %v", err)
+	}
+}
+
+func TestYoutubePublishTemplates(t *testing.T) {
+	srv := useFake(t)
+	t.Cleanup(func() { cfg = config.Defaults() })
+	cfg.Series["qa"] = config.Series{Title: "問答｜{{.Title}}", Description: "{{trim .Content}} [{{.Series}}]"}
+	videoId := srv.AddVideo("生命中別投降別氣餒", "unlisted")
+	folder := srv.AddFolder(clipName, map[string]string{drapi.VIDEO_ID: videoId})
+	srv.AddFile(folder, clipName+".txt", []byte("說明\n"), time.Time{})
+
+	if err := youtubeUpdateVideo(clipName, PUBLIC); err != nil {
+		t.Fatal(err)
+	}
+	snippet := srv.Video(videoId).Snippet
+	if snippet.Title != "微視頻-生命中別投降別氣餒 (繁體中文) ｜ 2023年01月14日" {
+		t.Errorf("title = %q", snippet.Title)
+	}
+	if !strings.HasPrefix(snippet.Description, "【生命中別投降別氣餒】\n\n說明\n\n\n聽錄、摘錄自2023年01月14日直播開示 37'34\" ~ 38'51\"\n本文內容") {
+		t.Errorf("description = %q", snippet.Description)
+	}
+	if srv.Video(videoId).Status.PrivacyStatus != "public" {
+		t.Errorf("privacy = %q", srv.Video(videoId).Status.PrivacyStatus)
+	}
+
+	// the folder's series property switches the layout
+	if err := setMeta(clipName, nil, nil, nil, &[]string{"qa"}[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := youtubeUpdateVideo(clipName, PUBLIC); err != nil {
+		t.Fatal(err)
+	}
+	if snippet := srv.Video(videoId).Snippet; snippet.Title != "問答｜生命中別投降別氣餒" || snippet.Description != "說明 [qa]" {
+		t.Errorf("snippet = %q, %q", snippet.Title, snippet.Description)
+	}
+}
+
+func TestExecuteExitCodes(t *testing.T) {
+	srv := useFake(t)
+	videoId := srv.AddVideo("生命中別投降別氣餒", "unlisted")
+	srv.AddFolder(clipName, map[string]string{drapi.VIDEO_ID: videoId})
+
+	cases := []struct {
+		args []string
+		code int
+	}{
+		{nil, exitUsage},
+		{[]string{"bogus"}, exitUsage},
+		{[]string{"video"}, exitUsage},
+		{[]string{"video", "upload"}, exitUsage},
+		{[]string{"video", "upload", "-no-such-flag", clipName}, exitUsage},
+		{[]string{"video", "upload", "-h"}, exitOK},
+		{[]string{"help", "caption", "upload"}, exitOK},
+		{[]string{"help", "bogus"}, exitUsage},
+		{[]string{"caption", "upload", "zh230115_[00.00-01.00]_不存在"}, exitNotFound},
+		{[]string{"video", "upload", clipName}, exitFailure},
+	}
+	for _, c := range cases {
+		if code := execute(rootCommand(), c.args, io.Discard, io.Discard); code != c.code {
+			t.Errorf("ytmgr %v: exit %d, want %d", c.args, code, c.code)
+		}
+	}
+}
+
+func TestExecuteQuotaExceeded(t *testing.T) {
+	srv := useFake(t)
+	videoId := srv.AddVideo("生命中別投降別氣餒", "unlisted")
+	srv.AddFolder(clipName, map[string]string{drapi.VIDEO_ID: videoId})
+	srv.FailNext("DELETE", "/youtube/v3/videos", 403, "quotaExceeded")
+	if code := execute(rootCommand(), []string{"video", "delete", "-yes", clipName}, io.Discard, io.Discard); code != exitQuota {
+		t.Errorf("exit %d, want %d", code, exitQuota)
+	}
+	if srv.Video(videoId) == nil {
+		t.Error("video deleted despite the quota error")
+	}
+}
+
+func TestDeleteArchives(t *testing.T) {
+	srv := useFake(t)
+	videoId := srv.AddVideo("生命中別投降別氣餒", "public")
+	folder := srv.AddFolder(clipName, map[string]string{drapi.VIDEO_ID: videoId, drapi.PRIVACY: "public"})
+	captionId := srv.AddCaption(videoId, "zh-TW", "繁體", []byte("1\n00:00:01,000 --> 00:00:02,000\n你好\n"))
+	srv.AddASRCaption(videoId, "zh-TW")
+	// a track of another channel cannot be downloaded either
+	foreign := srv.AddCaption(videoId, "en", "English", []byte("en"))
+	t.Cleanup(func() { confirmInput = bufio.NewReader(os.Stdin) })
+
+	confirmInput = bufio.NewReader(strings.NewReader("n\n"))
+	if err := youtubeDelete(clipName, false); !errors.Is(err, errNotConfirmed) {
+		t.Fatalf("expected errNotConfirmed, got %v", err)
+	}
+	if srv.Video(videoId) == nil {
+		t.Fatal("declined delete removed the video")
+	}
+
+	confirmInput = bufio.NewReader(strings.NewReader("y\n"))
+	srv.FailNext("GET", "/youtube/v3/captions/"+foreign, 403, "forbidden")
+	if err := youtubeDelete(clipName, false); err != nil {
+		t.Fatal(err)
+	}
+	if srv.Video(videoId) != nil {
+		t.Error("video not deleted")
+	}
+	props := srv.File(folder).AppProperties
+	if props[drapi.VIDEO_ID] != "" || props[drapi.DELETED] != videoId {
+		t.Errorf("app properties %v, want %s as tombstone", props, videoId)
+	}
+
+	var archive string
+	for _, f := range srv.Children(folder) {
+		if f.Name == drapi.ArchiveFolder {
+			archive = f.Id
+		}
+	}
+	if archive == "" {
+		t.Fatal("no archive folder")
+	}
+	files := map[string][]byte{}
+	for _, f := range srv.Children(archive) {
+		files[f.Name] = srv.Content(f.Id)
+	}
+	if srt := files[videoId+".zh-TW."+captionId+".srt"]; !strings.Contains(string(srt), "你好") {
+		t.Errorf("caption not archived, files %v", files)
+	}
+	var snap snapshot
+	if err := json.Unmarshal(files[videoId+".json"], &snap); err != nil {
+		t.Fatal(err)
+	}
+	if snap.Video.Id != videoId || snap.Video.Statistics == nil || len(snap.Captions) != 3 {
+		t.Errorf("snapshot = %+v", snap)
+	}
+	if _, ok := snap.NotArchived[foreign]; !ok || len(snap.NotArchived) != 1 {
+		t.Errorf("not archived = %v, want only %s", snap.NotArchived, foreign)
+	}
+	if len(files) != 2 {
+		t.Errorf("archived files %v, want the snapshot and one track", files)
+	}
+}
+
+func TestTombstone(t *testing.T) {
+	vmeta := &drapi.VideoMeta{}
+	for i := 0; i < 20; i++ {
+		vmeta.Tombstone(fmt.Sprintf("video%06d", i))
+	}
+	value := vmeta.Properties()[drapi.DELETED]
+	if len(drapi.DELETED)+len(value) > 124 {
+		t.Errorf("%s is %d bytes, too long for Drive", value, len(value))
+	}
+	if !strings.HasSuffix(value, "video000019") {
+		t.Errorf("newest id dropped: %s", value)
+	}
+}
+
+func TestExecuteManyNames(t *testing.T) {
+	srv := useFake(t)
+	other := "zh230115_[01.00-02.00]_第二段"
+	for _, name := range []string{clipName, other} {
+		folder := srv.AddFolder(name, nil)
+		srv.AddFile(folder, name+".mp4", []byte("video"), time.Time{})
+	}
+	args := []string{"-workers", "2", "video", "upload", clipName, "-chunk-size", "1", other}
+	var code int
+	out := captureStdout(t, func() {
+		code = execute(rootCommand(), args, io.Discard, io.Discard)
+	})
+	if code != exitOK {
+		t.Fatalf("exit %d", code)
+	}
+	if ids := srv.VideoIds(); len(ids) != 2 {
+		t.Errorf("videos = %v, want one per folder", ids)
+	}
+	// concurrent uploads must not share a \r-redrawn line
+	if strings.Contains(out, "\r") {
+		t.Errorf("progress with several workers redraws lines:\n%q", out)
+	}
+	for _, name := range []string{clipName, other} {
+		if !strings.Contains(out, name+": uploading ") {
+			t.Errorf("no progress line for %s:\n%s", name, out)
+		}
+	}
+}
+
+func TestExecuteDryRun(t *testing.T) {
+	srv := useFake(t)
+	videoId := srv.AddVideo("生命中別投降別氣餒", "unlisted")
+	folder := srv.AddFolder(clipName, map[string]string{drapi.VIDEO_ID: videoId})
+	srv.AddFile(folder, clipName+".mp4", []byte("video"), time.Time{})
+	srv.AddCaption(videoId, "zh-tw", "繁體", []byte("1"))
+
+	var out bytes.Buffer
+	args := []string{"-dry-run", "video", "upload", "-replace", clipName}
+	if code := execute(rootCommand(), args, &out, io.Discard); code != exitOK {
+		t.Fatalf("exit %d", code)
+	}
+	for _, want := range []string{"[youtube] delete video " + videoId, "[drive] download " + clipName + ".mp4", "[youtube] upload video 生命中別投降別氣餒"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("plan lacks %q:\n%s", want, out.String())
+		}
+	}
+	if ids := srv.VideoIds(); len(ids) != 1 || ids[0] != videoId {
+		t.Errorf("dry run changed the videos: %v", ids)
+	}
+	if props := srv.File(folder).AppProperties; props[drapi.VIDEO_ID] != videoId {
+		t.Errorf("dry run changed the folder: %v", props)
+	}
+
+	out.Reset()
+	if code := execute(rootCommand(), []string{"caption", "delete", clipName, "-dry-run", "-json"}, &out, io.Discard); code != exitOK {
+		t.Fatalf("exit %d", code)
+	}
+	var steps []plan.Step
+	if err := json.Unmarshal(out.Bytes(), &steps); err != nil {
+		t.Fatalf("%v: %s", err, out.String())
+	}
+	if len(steps) != 3 || steps[0].Op != "save" || steps[1].Op != "delete caption" || steps[2].Op != "update folder" {
+		t.Errorf("steps = %+v", steps)
+	}
+	if len(srv.Captions(videoId)) != 1 {
+		t.Error("dry run deleted the caption")
+	}
+}
+
+func TestPrepDryRun(t *testing.T) {
+	dir := t.TempDir()
+	name := "zh230114_[37.34-38.51]_生命中別投降 別氣餒.mp4"
+	if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if code := execute(rootCommand(), []string{"-dry-run", "prep", "propername", dir}, &out, io.Discard); code != exitOK {
+		t.Fatalf("exit %d", code)
+	}
+	if !strings.Contains(out.String(), "[fs] rename") {
+		t.Errorf("plan = %s", out.String())
+	}
+	if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+		t.Errorf("dry run renamed the file: %v", err)
+	}
+}
+
+func TestPrepTxtfy(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), clipName)
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	srt := "\uFEFF1\r\n00:00:01,000 --> 00:00:02,000\r\n生命中別投降\r\n別氣餒\r\n\r\n2\r\n00:00:03,000 --> 00:00:04,000\r\n你好\r\n"
+	if err := os.WriteFile(filepath.Join(dir, clipName+".srt"), []byte(srt), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if code := execute(rootCommand(), []string{"prep", "txtfy", filepath.Dir(dir)}, io.Discard, io.Discard); code != exitOK {
+		t.Fatalf("exit %d", code)
+	}
+	txt, err := os.ReadFile(filepath.Join(dir, clipName+".txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(txt) != "生命中別投降\n別氣餒\n你好" {
+		t.Errorf("txt = %q", txt)
+	}
+}
+
+func TestPrepConvert(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), clipName)
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	srt := "1\n00:00:01,000 --> 00:00:02,500\n生命中別投降\n別氣餒\n"
+	if err := os.WriteFile(filepath.Join(dir, clipName+".srt"), []byte(srt), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if code := execute(rootCommand(), []string{"prep", "convert", "-to", "sbv", filepath.Dir(dir)}, io.Discard, io.Discard); code != exitOK {
+		t.Fatalf("exit %d", code)
+	}
+	sbv, err := os.ReadFile(filepath.Join(dir, clipName+".sbv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(sbv) != "0:00:01.000,0:00:02.500\n生命中別投降\n別氣餒\n" {
+		t.Errorf("sbv = %q", sbv)
+	}
+	if code := execute(rootCommand(), []string{"prep", "convert", "-to", "docx", filepath.Dir(dir)}, io.Discard, io.Discard); code != exitUsage {
+		t.Errorf("-to docx: exit %d, want %d", code, exitUsage)
+	}
+}
+
+func TestPrepCut(t *testing.T) {
+	root := t.TempDir()
+	session := filepath.Join(root, "zh230114.vtt")
+	vtt := "WEBVTT\n\n" +
+		"37:30.000 --> 37:33.000\n之前\n\n" +
+		"37:33.000 --> 37:36.500\n生命中別投降\n\n" +
+		"37:40.000 --> 37:42.000\n別氣餒\n\n" +
+		"38:50.000 --> 38:55.000\n之後\n"
+	if err := os.WriteFile(session, []byte(vtt), 0600); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(root, clipName)
+	if code := execute(rootCommand(), []string{"prep", "cut", session, dir}, io.Discard, io.Discard); code != exitOK {
+		t.Fatalf("exit %d", code)
+	}
+	srt, err := os.ReadFile(filepath.Join(dir, clipName+".srt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "1\n00:00:00,000 --> 00:00:02,500\n生命中別投降\n\n" +
+		"2\n00:00:06,000 --> 00:00:08,000\n別氣餒\n\n" +
+		"3\n00:01:16,000 --> 00:01:17,000\n之後\n\n"
+	if string(srt) != want {
+		t.Errorf("srt = %q", srt)
+	}
+	if code := execute(rootCommand(), []string{"prep", "cut", session, dir}, io.Discard, io.Discard); code == exitOK {
+		t.Error("overwrote the clip's .srt without -force")
+	}
+	if code := execute(rootCommand(), []string{"prep", "cut", "-force", session, dir}, io.Discard, io.Discard); code != exitOK {
+		t.Errorf("-force: exit %d", code)
+	}
+	if code := execute(rootCommand(), []string{"prep", "cut", session, filepath.Join(root, "zh230114無時間")}, io.Discard, io.Discard); code == exitOK {
+		t.Error("cut a folder without a time range")
+	}
+}
+
+func TestCaptionFormats(t *testing.T) {
+	srv := useFake(t)
+	folder := srv.AddFolder(clipName, nil)
+	srv.AddFile(folder, clipName+".mp4", []byte("video"), time.Time{})
+	vtt := "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\n你好\n"
+	srv.AddFile(folder, clipName+".vtt", []byte(vtt), time.Time{})
+	if err := youtubeUpload(clipName, uploadOptions{chunkSize: ytapi.DefaultChunkSize}); err != nil {
+		t.Fatal(err)
+	}
+	if err := youtubeCaption(clipName, "", false); err != nil {
+		t.Fatal(err)
+	}
+	captionId := srv.File(folder).AppProperties[drapi.CAPTION_ID]
+	if got := string(srv.CaptionContent(captionId)); got != vtt {
+		t.Errorf("uploaded %q, want the .vtt", got)
+	}
+
+	root := t.TempDir()
+	if err := download(clipName, root, subtitle.TTML); err != nil {
+		t.Fatal(err)
+	}
+	ttml, err := os.ReadFile(filepath.Join(root, clipName, clipName+".ttml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(ttml), `<p begin="00:00:01.000" end="00:00:02.000">你好</p>`) {
+		t.Errorf("ttml = %s", ttml)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	srv := useFake(t)
+	t.Cleanup(func() { cfg = config.Defaults() })
+	cfg.Series["micro"] = config.Series{Title: "{{.Title}}", Description: "{{.Content}}", Playlist: "PLmicro", PlaylistPosition: "first"}
+	srv.AddPlaylistItem("PLmicro", srv.AddVideo("older", "public"))
+	folder := srv.AddFolder(clipName, nil)
+	srv.AddFile(folder, clipName+".mp4", []byte("video"), time.Time{})
+	srv.AddFile(folder, clipName+".png", []byte("png"), time.Time{})
+	srv.AddFile(folder, clipName+".srt", []byte("1\n00:00:01,000 --> 00:00:02,000\n你好\n"), time.Time{})
+
+	srv.FailNext("POST", "/upload/youtube/v3/captions", 403, "quotaExceeded")
+	if code := execute(rootCommand(), []string{"release", clipName}, io.Discard, io.Discard); code != exitQuota {
+		t.Fatalf("exit %d, want %d", code, exitQuota)
+	}
+	props := srv.File(folder).AppProperties
+	videoId := props[drapi.VIDEO_ID]
+	if videoId == "" || props[drapi.RELEASE_PREFIX+"upload"] == "" || props[drapi.RELEASE_PREFIX+"thumbnail"] == "" {
+		t.Fatalf("upload and thumbnail not recorded: %v", props)
+	}
+	if props[drapi.RELEASE_PREFIX+"caption"] != "" {
+		t.Errorf("failed caption step recorded as done: %v", props)
+	}
+
+	// the second run picks up at the caption and uploads nothing twice
+	if code := execute(rootCommand(), []string{"release", clipName}, io.Discard, io.Discard); code != exitOK {
+		t.Fatalf("exit %d", code)
+	}
+	if ids := srv.VideoIds(); len(ids) != 2 {
+		t.Errorf("videos = %v, want the old one and one upload", ids)
+	}
+	if len(srv.Captions(videoId)) != 2 || srv.Thumbnail(videoId) == nil {
+		t.Errorf("caption or thumbnail missing")
+	}
+	if ids := srv.PlaylistVideoIds("PLmicro"); len(ids) != 2 || ids[0] != videoId {
+		t.Errorf("playlist = %v, want %s first", ids, videoId)
+	}
+	if v := srv.Video(videoId); v.Status.PrivacyStatus != "public" {
+		t.Errorf("privacy = %q", v.Status.PrivacyStatus)
+	}
+	props = srv.File(folder).AppProperties
+	for _, step := range []string{"upload", "thumbnail", "caption", "playlist", "publish"} {
+		if props[drapi.RELEASE_PREFIX+step] == "" {
+			t.Errorf("step %s not recorded: %v", step, props)
+		}
+	}
+
+	// a finished release does nothing, -restart runs every step again
+	calls := len(srv.Calls())
+	if code := execute(rootCommand(), []string{"release", clipName}, io.Discard, io.Discard); code != exitOK {
+		t.Fatalf("exit %d", code)
+	}
+	for _, call := range srv.Calls()[calls:] {
+		if !strings.HasPrefix(call, "GET") {
+			t.Errorf("finished release made call %s", call)
+		}
+	}
+	if code := execute(rootCommand(), []string{"release", "-restart", clipName}, io.Discard, io.Discard); code != exitOK {
+		t.Fatalf("exit %d", code)
+	}
+	if ids := srv.PlaylistVideoIds("PLmicro"); len(ids) != 2 {
+		t.Errorf("restart added the video to the playlist again: %v", ids)
+	}
+}
+
+func TestReleaseCaptionLint(t *testing.T) {
+	srv := useFake(t)
+	folder := srv.AddFolder(clipName, nil)
+	srv.AddFile(folder, clipName+".mp4", []byte("video"), time.Time{})
+	// the second cue overlaps the first and runs past the clip's end
+	srv.AddFile(folder, clipName+".srt", []byte("1\n00:00:01,000 --> 00:00:03,000\n你好\n\n"+
+		"2\n00:00:02,000 --> 00:01:30,000\n生命中別投降\n"), time.Time{})
+
+	var code int
+	out := captureStdout(t, func() {
+		code = execute(rootCommand(), []string{"release", clipName}, io.Discard, io.Discard)
+	})
+	if code != exitFailure || !strings.Contains(out, "error: cue 2: overlap:") {
+		t.Errorf("exit %d, printed %q", code, out)
+	}
+	props := srv.File(folder).AppProperties
+	if props[drapi.VIDEO_ID] == "" || props[drapi.RELEASE_PREFIX+"upload"] == "" {
+		t.Fatalf("upload not recorded: %v", props)
+	}
+	if props[drapi.CAPTION_ID] != "" || props[drapi.RELEASE_PREFIX+"caption"] != "" || len(srv.Captions(props[drapi.VIDEO_ID])) != 0 {
+		t.Errorf("caption with lint errors released: %v", props)
+	}
+	if props[drapi.RELEASE_PREFIX+"publish"] != "" {
+		t.Errorf("release went on past the caption: %v", props)
+	}
+}
+
+func TestParseScheduleTime(t *testing.T) {
+	now := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	at, err := parseScheduleTime("2026-11-01 20:00 Asia/Taipei", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC); !at.Equal(want) {
+		t.Errorf("at = %s, want %s", at, want)
+	}
+	if at, err := parseScheduleTime("2026-11-01T20:00:00+08:00", now); err != nil || at.UTC().Hour() != 12 {
+		t.Errorf("RFC 3339: %s, %v", at, err)
+	}
+	for _, bad := range []string{"2026-10-01 20:00 Asia/Taipei", "2026-11-01 20:00 Mars/Olympus", "tomorrow", "2026-11-01"} {
+		if _, err := parseScheduleTime(bad, now); !errors.Is(err, errUsage) {
+			t.Errorf("%q: expected a usage error, got %v", bad, err)
+		}
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	srv := useFake(t)
+	videoId := srv.AddVideo("生命中別投降別氣餒", "unlisted")
+	folder := srv.AddFolder(clipName, map[string]string{drapi.VIDEO_ID: videoId})
+	other := "zh230115_[01.00-02.00]_第二段"
+	srv.AddFolder(other, map[string]string{drapi.VIDEO_ID: srv.AddVideo("第二段", "unlisted")})
+
+	at := time.Now().Add(48 * time.Hour).Truncate(time.Minute)
+	if err := youtubeSchedule(clipName, at); err != nil {
+		t.Fatal(err)
+	}
+	if err := youtubeSchedule(other, at.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	video := srv.Video(videoId)
+	if video.Status.PrivacyStatus != "private" || video.Status.PublishAt != at.UTC().Format(time.RFC3339) {
+		t.Errorf("status = %+v", video.Status)
+	}
+	props := srv.File(folder).AppProperties
+	if props[drapi.PRIVACY] != "private" || props[drapi.SCHEDULED] != at.UTC().Format(time.RFC3339) {
+		t.Errorf("app properties = %v", props)
+	}
+
+	out := captureStdout(t, func() {
+		if err := listSchedule(false, time.Now()); err != nil {
+			t.Error(err)
+		}
+	})
+	if i, j := strings.Index(out, other), strings.Index(out, clipName); i < 0 || j < 0 || i > j {
+		t.Errorf("schedule list not in time order:\n%s", out)
+	}
+	if out := captureStdout(t, func() { listSchedule(false, at.Add(time.Minute)) }); strings.Contains(out, clipName) {
+		t.Errorf("past release listed:\n%s", out)
+	}
+
+	if err := youtubeCancelSchedule(clipName); err != nil {
+		t.Fatal(err)
+	}
+	if video := srv.Video(videoId); video.Status.PrivacyStatus != "unlisted" || video.Status.PublishAt != "" {
+		t.Errorf("status after cancel = %+v", video.Status)
+	}
+	if props := srv.File(folder).AppProperties; props[drapi.PRIVACY] != "unlisted" || props[drapi.SCHEDULED] != "" {
+		t.Errorf("app properties after cancel = %v", props)
+	}
+	if err := youtubeCancelSchedule(clipName); !errors.Is(err, errNotScheduled) {
+		t.Errorf("expected errNotScheduled, got %v", err)
+	}
+}
+
+// captureStdout returns what fn prints to standard output.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := os.Stdout
+	os.Stdout = w
+	done := make(chan []byte)
+	go func() {
+		out, _ := io.ReadAll(r)
+		done <- out
+	}()
+	fn()
+	os.Stdout = saved
+	w.Close()
+	return string(<-done)
+}
+
+func TestExecuteBatch(t *testing.T) {
+	srv := useFake(t)
+	srt := []byte("1\n00:00:01,000 --> 00:00:02,000\n你好\n")
+	names := []string{"zh221001_[00.00-01.00]_一", "zh221002_[00.00-01.00]_二", "zh221003_[00.00-01.00]_三", clipName}
+	videos := map[string]string{}
+	for _, name := range names {
+		props := map[string]string{}
+		if name != names[1] {
+			videos[name] = srv.AddVideo(name, "unlisted")
+			props[drapi.VIDEO_ID] = videos[name]
+		}
+		srv.AddFile(srv.AddFolder(name, props), name+".srt", srt, time.Time{})
+	}
+	manifest := filepath.Join(t.TempDir(), "names.txt")
+	if err := os.WriteFile(manifest, []byte("# retranscribed\n"+clipName+"\n\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// the folder without a video fails, the others are still captioned
+	var out string
+	code := 0
+	out = captureStdout(t, func() {
+		code = execute(rootCommand(), []string{"-workers", "2", "-from", manifest, "caption", "upload", "zh2210*"}, io.Discard, io.Discard)
+	})
+	if code != exitFailure {
+		t.Errorf("exit %d, want %d", code, exitFailure)
+	}
+	for name, videoId := range videos {
+		if len(srv.Captions(videoId)) != 2 {
+			t.Errorf("%s not captioned", name)
+		}
+	}
+	if !strings.Contains(out, "summary: 3 ok, 1 failed, 0 skipped") || !strings.Contains(out, "FAIL  "+names[1]) {
+		t.Errorf("unexpected summary:\n%s", out)
+	}
+
+	// once the quota is gone the remaining folders are skipped
+	srv.FailNext("DELETE", "/youtube/v3/captions", 403, "quotaExceeded")
+	out = captureStdout(t, func() {
+		code = execute(rootCommand(), []string{"-workers", "1", "caption", "delete", names[0], names[2], clipName}, io.Discard, io.Discard)
+	})
+	if code != exitQuota {
+		t.Errorf("exit %d, want %d", code, exitQuota)
+	}
+	if !strings.Contains(out, "summary: 0 ok, 1 failed, 2 skipped") {
+		t.Errorf("unexpected summary:\n%s", out)
+	}
+
+	if code := execute(rootCommand(), []string{"caption", "upload", "en*"}, io.Discard, io.Discard); code != exitNotFound {
+		t.Errorf("pattern without matches: exit %d, want %d", code, exitNotFound)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	srv := useFake(t)
+	// changed in YouTube Studio: made public, caption track replaced
+	videoId := srv.AddVideo("生命中別投降別氣餒", "public")
+	trackId := srv.AddCaption(videoId, "zh-tw", "繁體", []byte("1\n00:00:01,000 --> 00:00:02,000\n你好\n"))
+	folder := srv.AddFolder(clipName, map[string]string{drapi.VIDEO_ID: videoId, drapi.PRIVACY: "unlisted", drapi.CAPTION_ID: "gone"})
+	gone := "zh230115_[01.00-02.00]_第二段"
+	srv.AddFolder(gone, map[string]string{drapi.VIDEO_ID: "deleted", drapi.PRIVACY: "public"})
+
+	if err := reconcile(clipName, ""); !errors.Is(err, errDrift) {
+		t.Fatalf("expected errDrift, got %v", err)
+	}
+	if err := reconcile(clipName, "drive"); err != nil {
+		t.Fatal(err)
+	}
+	props := srv.File(folder).AppProperties
+	if props[drapi.PRIVACY] != "public" || props[drapi.CAPTION_ID] != trackId {
+		t.Errorf("app properties after fix = %v", props)
+	}
+	if err := reconcile(clipName, ""); err != nil {
+		t.Errorf("still out of sync: %v", err)
+	}
+
+	// the folder wins: privacy pushed back, unrecorded tracks of any
+	// language deleted
+	orphan := srv.AddCaption(videoId, "zh-tw", "舊", []byte("old"))
+	srv.AddCaption(videoId, "en", "English", []byte("en"))
+	if err := setMeta(clipName, &videoId, &trackId, strPtr("unlisted"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := reconcile(clipName, "youtube"); err != nil {
+		t.Fatal(err)
+	}
+	if v := srv.Video(videoId); v.Status.PrivacyStatus != "unlisted" {
+		t.Errorf("privacy = %q", v.Status.PrivacyStatus)
+	}
+	for _, c := range srv.Captions(videoId) {
+		if c.Id == orphan {
+			t.Error("orphaned track not deleted")
+		}
+	}
+	if captions := srv.Captions(videoId); len(captions) != 1 || captions[0].Id != trackId {
+		t.Errorf("tracks left = %+v, want only the recorded one", captions)
+	}
+	// the deleted tracks were saved into the folder first
+	saved := map[string]string{}
+	for _, f := range srv.Children(folder) {
+		if drapi.IsPulled(f.Name) {
+			saved[f.Name] = string(srv.Content(f.Id))
+		}
+	}
+	if len(saved) != 2 {
+		t.Errorf("saved before deleting: %v", saved)
+	}
+	for name, content := range saved {
+		if strings.Contains(name, ".zh-tw.") && content != "old" || strings.Contains(name, ".en.") && content != "en" {
+			t.Errorf("%s = %q", name, content)
+		}
+	}
+	var logged bool
+	for _, f := range srv.Children(folder) {
+		if f.Name == audit.FileName {
+			logged = bytes.Contains(srv.Content(f.Id), []byte(`"op":"delete orphaned caption zh-tw"`))
+		}
+	}
+	if !logged {
+		t.Error("orphan delete not in the audit log")
+	}
+
+	// a deleted video can only be forgotten
+	if err := reconcile(gone, "youtube"); !errors.Is(err, errDrift) {
+		t.Errorf("expected errDrift, got %v", err)
+	}
+	if err := reconcile(gone, "drive"); err != nil {
+		t.Fatal(err)
+	}
+	if vmeta, err := drv.GetVideoMeta(gone); err != nil || hasVideo(vmeta) {
+		t.Errorf("video id not cleared: %v", err)
+	}
+}
+
+func TestReconcileThumbnail(t *testing.T) {
+	srv := useFake(t)
+	videoId := srv.AddVideo("生命中別投降別氣餒", "unlisted")
+	folder := srv.AddFolder(clipName, map[string]string{drapi.VIDEO_ID: videoId, drapi.PRIVACY: "unlisted"})
+	srv.AddFile(folder, clipName+".png", []byte("png"), time.Time{})
+
+	if err := reconcile(clipName, "drive"); !errors.Is(err, errDrift) {
+		t.Fatalf("missing thumbnail: expected errDrift, got %v", err)
+	}
+	if err := reconcile(clipName, "youtube"); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(srv.Thumbnail(videoId)); got != "png" {
+		t.Errorf("thumbnail = %q", got)
+	}
+	if err := reconcile(clipName, ""); err != nil {
+		t.Errorf("still out of sync: %v", err)
+	}
+
+	// a new cover is not on YouTube yet
+	srv.AddFile(folder, "new.png", []byte("new png"), time.Now().Add(time.Hour))
+	if err := reconcile(clipName, "drive"); !errors.Is(err, errDrift) {
+		t.Fatalf("changed cover: expected errDrift, got %v", err)
+	}
+	if err := reconcile(clipName, "youtube"); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(srv.Thumbnail(videoId)); got != "new png" {
+		t.Errorf("thumbnail = %q", got)
+	}
+}
+
+func TestReconcileTracks(t *testing.T) {
+	srv := useFake(t)
+	folder := srv.AddFolder(clipName, nil)
+	srv.AddFile(folder, clipName+".mp4", []byte("video"), time.Time{})
+	srv.AddFile(folder, clipName+".srt", []byte("1\n00:00:01,000 --> 00:00:02,000\n你好\n"), time.Time{})
+	srv.AddFile(folder, clipName+".en.srt", []byte("1\n00:00:01,000 --> 00:00:02,000\nHello\n"), time.Time{})
+	if err := youtubeUpload(clipName, uploadOptions{chunkSize: ytapi.DefaultChunkSize}); err != nil {
+		t.Fatal(err)
+	}
+	if err := youtubeCaption(clipName, "", false); err != nil {
+		t.Fatal(err)
+	}
+	props := srv.File(folder).AppProperties
+	videoId := props[drapi.VIDEO_ID]
+
+	// the English track deleted in YouTube Studio is uploaded again
+	if err := yt.DeleteCaption(props[drapi.TrackKind("en")]); err != nil {
+		t.Fatal(err)
+	}
+	if err := reconcile(clipName, ""); !errors.Is(err, errDrift) {
+		t.Fatalf("deleted en track: expected errDrift, got %v", err)
+	}
+	if err := reconcile(clipName, "youtube"); err != nil {
+		t.Fatal(err)
+	}
+	en := trackOf(srv, videoId, "en")
+	if en == nil || srv.File(folder).AppProperties[drapi.TrackKind("en")] != en.Id {
+		t.Errorf("en track %+v, properties %v", en, srv.File(folder).AppProperties)
+	}
+
+	// the simplified track replaced in YouTube Studio is adopted
+	if err := yt.DeleteCaption(props[drapi.TrackKind("zh-cn")]); err != nil {
+		t.Fatal(err)
+	}
+	replaced := srv.AddCaption(videoId, "zh-cn", "简体", []byte("1\n00:00:01,000 --> 00:00:02,000\n你好\n"))
+	if err := reconcile(clipName, "drive"); err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.File(folder).AppProperties[drapi.TrackKind("zh-cn")]; got != replaced {
+		t.Errorf("zh-cn caption id = %q, want %q", got, replaced)
+	}
+	if err := reconcile(clipName, ""); err != nil {
+		t.Errorf("still out of sync: %v", err)
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestCatalogCommands(t *testing.T) {
+	srv := useFake(t)
+	db, err := catalog.Open(filepath.Join(t.TempDir(), "catalog.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clipCatalog = db
+	t.Cleanup(func() {
+		db.Close()
+		clipCatalog = nil
+		cfg = config.Defaults()
+	})
+	if drv, yt, err = newClients(nil); err != nil {
+		t.Fatal(err)
+	}
+	videoId := srv.AddVideo("生命中別投降別氣餒", "unlisted")
+	srv.AddFolder(clipName, map[string]string{drapi.VIDEO_ID: videoId, drapi.PRIVACY: "unlisted"})
+	srv.AddFolder("zh230115_[01.00-02.00]_第二段", nil)
+
+	run := func(args ...string) string {
+		code := 0
+		out := captureStdout(t, func() { code = execute(rootCommand(), args, io.Discard, io.Discard) })
+		if code != exitOK {
+			t.Fatalf("%v: exit %d", args, code)
+		}
+		return out
+	}
+	if out := run("catalog", "sync"); !strings.Contains(out, "full sync: 2 folder(s) updated") {
+		t.Errorf("sync printed %q", out)
+	}
+	if out := run("catalog", "list", "-privacy", "unlisted"); !strings.Contains(out, videoId) || !strings.Contains(out, "1 clip(s)") {
+		t.Errorf("list printed %q", out)
+	}
+	if out := run("catalog", "search", "第二段"); !strings.Contains(out, "zh230115_[01.00-02.00]_第二段") {
+		t.Errorf("search printed %q", out)
+	}
+
+	// a fresh catalog answers meta show without Drive
+	calls := len(srv.Calls())
+	if out := run("meta", "show", clipName); !strings.Contains(out, "from the catalog") || !strings.Contains(out, videoId) {
+		t.Errorf("meta show printed %q", out)
+	}
+	if n := len(srv.Calls()); n != calls {
+		t.Errorf("meta show called Drive %d time(s)", n-calls)
+	}
+	// so do name patterns and the reconcile report
+	if out := run("meta", "show", "zh23*"); !strings.Contains(out, "第二段") {
+		t.Errorf("meta show zh23* printed %q", out)
+	}
+	run("reconcile", clipName)
+	for _, call := range srv.Calls()[calls:] {
+		if strings.Contains(call, "/drive/") {
+			t.Errorf("fresh catalog, still called Drive: %s", call)
+		}
+	}
+	// setting one field keeps the others in the catalog
+	run("meta", "set", "-series", "micro", clipName)
+	if entry := cachedMeta(clipName); entry == nil || deref(entry.VideoId) != videoId || deref(entry.Privacy) != "unlisted" || deref(entry.Series) != "micro" {
+		t.Errorf("catalog after meta set = %s", prettyPrint(entry))
+	}
+
+	cfg.CatalogMaxAge = "0s"
+	if out := run("meta", "show", clipName); strings.Contains(out, "from the catalog") {
+		t.Errorf("stale catalog used: %q", out)
+	}
+	if out := run("catalog", "status"); !strings.Contains(out, "stale") || !strings.Contains(out, "unlisted      1") {
+		t.Errorf("status printed %q", out)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	srv := useFake(t)
+	folder := srv.AddFolder(clipName, nil)
+	srv.AddFile(folder, clipName+".mp4", []byte("video"), time.Time{})
+
+	if err := youtubeUpload(clipName, uploadOptions{chunkSize: ytapi.DefaultChunkSize}); err != nil {
+		t.Fatal(err)
+	}
+	if err := youtubeUpdateVideo(clipName, PUBLIC); err != nil {
+		t.Fatal(err)
+	}
+	if err := youtubeUploadCover(clipName, false); err == nil {
+		t.Fatal("cover without a thumbnail succeeded")
+	}
+	videoId := srv.File(folder).AppProperties[drapi.VIDEO_ID]
+
+	var logId string
+	for _, f := range srv.Children(folder) {
+		if f.Name == audit.FileName {
+			logId = f.Id
+		}
+	}
+	if logId == "" {
+		t.Fatalf("no %s in the folder", audit.FileName)
+	}
+	entries, err := audit.Parse(bytes.NewReader(srv.Content(logId)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].Op != "upload" || entries[1].Op != "privacy public" || entries[2].Op != "thumbnail" {
+		t.Fatalf("entries = %+v", entries)
+	}
+	if entries[0].Ids["videoId"] != videoId || entries[0].Error != "" {
+		t.Errorf("upload entry = %+v", entries[0])
+	}
+	if entries[2].Error == "" {
+		t.Errorf("failed cover not recorded: %+v", entries[2])
+	}
+	var after drapi.VideoMeta
+	if err := json.Unmarshal(entries[0].AfterMeta, &after); err != nil || after.Title != "生命中別投降別氣餒" || deref(after.VideoId) != videoId {
+		t.Errorf("upload entry metadata = %s (%v)", entries[0].AfterMeta, err)
+	}
+
+	out := captureStdout(t, func() {
+		if err := showAudit(clipName); err != nil {
+			t.Error(err)
+		}
+	})
+	for _, want := range []string{"upload", "videoId " + videoId, "privacy: unlisted -> public", "failed: "} {
+		if !strings.Contains(out, want) {
+			t.Errorf("audit output lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestSkipUnchanged(t *testing.T) {
+	srv := useFake(t)
+	folder := srv.AddFolder(clipName, nil)
+	srv.AddFile(folder, clipName+".mp4", []byte("video"), time.Time{})
+	srv.AddFile(folder, clipName+".png", []byte("png"), time.Time{})
+	srv.AddFile(folder, clipName+".srt", []byte("1\n00:00:01,000 --> 00:00:02,000\n你好\n"), time.Time{})
+
+	if err := youtubeUpload(clipName, uploadOptions{chunkSize: ytapi.DefaultChunkSize}); err != nil {
+		t.Fatal(err)
+	}
+	if err := youtubeCaption(clipName, "", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := youtubeUploadCover(clipName, false); err != nil {
+		t.Fatal(err)
+	}
+	props := srv.File(folder).AppProperties
+	for _, kind := range []string{drapi.VIDEO_FILE, drapi.CAPTION_FILE, drapi.THUMBNAIL_FILE} {
+		if props[drapi.PUSHED_PREFIX+kind] == "" {
+			t.Errorf("%s checksum not recorded: %v", kind, props)
+		}
+	}
+	videoId := props[drapi.VIDEO_ID]
+
+	calls := len(srv.Calls())
+	if err := youtubeUpload(clipName, uploadOptions{replace: true, chunkSize: ytapi.DefaultChunkSize}); err != nil {
+		t.Fatal(err)
+	}
+	if err := youtubeCaption(clipName, "", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := youtubeUploadCover(clipName, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, call := range srv.Calls()[calls:] {
+		if !strings.HasPrefix(call, "GET") {
+			t.Errorf("unchanged files pushed again: %s", call)
+		}
+	}
+	if ids := srv.VideoIds(); len(ids) != 1 || ids[0] != videoId {
+		t.Errorf("videos = %v, want %s kept", ids, videoId)
+	}
+
+	out := captureStdout(t, func() {
+		if err := dumpMeta(clipName); err != nil {
+			t.Error(err)
+		}
+	})
+	if strings.Contains(out, "changed since last publish") {
+		t.Errorf("nothing changed, meta show printed %q", out)
+	}
+	srv.AddFile(folder, "fixed.srt", []byte("1\n00:00:01,000 --> 00:00:02,000\n您好\n"), time.Now().Add(time.Hour))
+	out = captureStdout(t, func() {
+		if err := dumpMeta(clipName); err != nil {
+			t.Error(err)
+		}
+	})
+	if !strings.Contains(out, "# changed since last publish: caption\n") {
+		t.Errorf("meta show printed %q", out)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	srv := useFake(t)
+	t.Cleanup(func() { cfg = config.Defaults() })
+	videoId := srv.AddVideo("生命中別投降別氣餒", "unlisted")
+	folder := srv.AddFolder(clipName, map[string]string{drapi.VIDEO_ID: videoId, drapi.PRIVACY: "unlisted"})
+	srv.AddFile(folder, clipName+".mp4", []byte("video"), time.Time{})
+	run := func(args ...string) string {
+		var out bytes.Buffer
+		stdout := captureStdout(t, func() {
+			if code := execute(rootCommand(), args, &out, io.Discard); code != exitOK {
+				t.Errorf("ytmgr %v: exit %d", args, code)
+			}
+		})
+		return stdout + out.String()
+	}
+
+	// the first run only remembers where the feed is
+	if out := run("watch", "-once"); !strings.Contains(out, "from now on") {
+		t.Errorf("first run printed %q", out)
+	}
+
+	srv.AddFile(folder, clipName+".srt", []byte("1\n00:00:01,000 --> 00:00:02,000\n你好\n"), time.Time{})
+	srv.AddFile(folder, clipName+".txt", []byte("新的說明"), time.Time{})
+	srv.AddFile(srv.AddFolder("not a clip", nil), "other.srt", []byte("1"), time.Time{})
+	cfg.Watch.Notify = []string{"notify-send", "ytmgr"}
+	if out := run("-dry-run", "watch"); !strings.Contains(out, "[local] notify "+clipName) || !strings.Contains(out, "insert caption") {
+		t.Errorf("dry run printed %q", out)
+	}
+	if len(srv.Captions(videoId)) != 0 {
+		t.Fatal("dry run uploaded the caption")
+	}
+	cfg.Watch.Notify = nil
+
+	run("watch", "-once")
+	if captions := srv.Captions(videoId); len(captions) != 2 {
+		t.Errorf("captions = %+v", captions)
+	}
+	if v := srv.Video(videoId); !strings.Contains(v.Snippet.Description, "新的說明") || v.Status.PrivacyStatus != "unlisted" {
+		t.Errorf("video = %+v %+v", v.Snippet, v.Status)
+	}
+
+	calls := len(srv.Calls())
+	run("watch", "-once")
+	for _, call := range srv.Calls()[calls:] {
+		if !strings.HasPrefix(call, "GET") {
+			t.Errorf("nothing changed, watch made call %s", call)
+		}
+	}
+}
+
+func TestWatchDescriptionKeepsStatus(t *testing.T) {
+	srv := useFake(t)
+	// scheduled for a time that has passed without YouTube publishing it
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	videoId := srv.AddVideo("生命中別投降別氣餒", "private")
+	srv.SetPublishAt(videoId, past)
+	folder := srv.AddFolder(clipName, map[string]string{drapi.VIDEO_ID: videoId, drapi.PRIVACY: "private", drapi.SCHEDULED: past})
+	w, err := newWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	srv.AddFile(folder, clipName+".txt", []byte("新的說明"), time.Time{})
+	if err := w.poll(start); err != nil {
+		t.Fatal(err)
+	}
+	captureStdout(t, func() {
+		err = w.settle(start, 0)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := srv.Video(videoId)
+	if !strings.Contains(v.Snippet.Description, "新的說明") {
+		t.Errorf("description = %q", v.Snippet.Description)
+	}
+	if v.Status.PrivacyStatus != "private" || v.Status.PublishAt != past {
+		t.Errorf("status = %+v, want it untouched", v.Status)
+	}
+}
+
+func TestWatchDebounce(t *testing.T) {
+	srv := useFake(t)
+	folder := srv.AddFolder(clipName, nil)
+	w, err := newWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	srv.AddFile(folder, clipName+".srt", []byte("1"), time.Time{})
+	if err := w.poll(start); err != nil {
+		t.Fatal(err)
+	}
+	srv.AddFile(folder, clipName+".txt", []byte("說明"), time.Time{})
+	if err := w.poll(start.Add(3 * time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.settle(start.Add(5*time.Minute), 5*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	p := w.pending[folder]
+	if p == nil || len(p.files) != 2 {
+		t.Fatalf("folder settled while it was still changing: %+v", w.pending)
+	}
+	saved, err := os.ReadFile(w.tokenFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(saved)) == w.token {
+		t.Error("token saved past changes still pending")
+	}
+	if err := w.settle(start.Add(8*time.Minute), 5*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.pending) != 0 {
+		t.Errorf("pending = %+v", w.pending)
+	}
+}
+
+func TestCaptionLint(t *testing.T) {
+	srv := useFake(t)
+	folder := srv.AddFolder(clipName, nil)
+	srv.AddFile(folder, clipName+".mp4", []byte("video"), time.Time{})
+	// the clip is 1:17 long
+	srv.AddFile(folder, clipName+".srt", []byte("1\n00:00:01,000 --> 00:00:03,000\n你好\n\n"+
+		"2\n00:00:02,000 --> 00:01:30,000\n生命中別投降\n"), time.Time{})
+	if err := youtubeUpload(clipName, uploadOptions{chunkSize: ytapi.DefaultChunkSize}); err != nil {
+		t.Fatal(err)
+	}
+
+	var err error
+	out := captureStdout(t, func() { err = showLint(clipName, true) })
+	if !errors.Is(err, errLint) {
+		t.Errorf("showLint = %v, want errLint", err)
+	}
+	// the report is the last line, after the Drive progress messages
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	var report lintReport
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &report); err != nil {
+		t.Fatalf("%v in %q", err, out)
+	}
+	if report.File != clipName+".srt" || report.Errors != 2 || report.Warnings != 0 {
+		t.Errorf("report = %+v", report)
+	}
+	for i, check := range []string{"overlap", "clip end"} {
+		if report.Issues[i].Check != check || report.Issues[i].Cue != 2 {
+			t.Errorf("issue %d = %+v, want %s on cue 2", i, report.Issues[i], check)
+		}
+	}
+
+	out = captureStdout(t, func() { err = youtubeCaption(clipName, "", false) })
+	if !errors.Is(err, errLint) || !strings.Contains(out, "error: cue 2: overlap:") {
+		t.Errorf("youtubeCaption = %v, printed %q", err, out)
+	}
+	if props := srv.File(folder).AppProperties; props[drapi.CAPTION_ID] != "" {
+		t.Errorf("caption with lint errors uploaded: %v", props)
+	}
+	if err := youtubeCaption(clipName, "", true); err != nil {
+		t.Fatal(err)
+	}
+	if props := srv.File(folder).AppProperties; props[drapi.CAPTION_ID] == "" {
+		t.Errorf("-force did not upload the caption: %v", props)
+	}
+}
+
+func TestCaptionTracks(t *testing.T) {
+	srv := useFake(t)
+	folder := srv.AddFolder(clipName, nil)
+	srv.AddFile(folder, clipName+".mp4", []byte("video"), time.Time{})
+	srv.AddFile(folder, clipName+".srt", []byte("1\n00:00:01,000 --> 00:00:02,000\n你好\n"), time.Time{})
+	en := srv.AddFile(folder, clipName+".en.srt", []byte("1\n00:00:01,000 --> 00:00:02,000\nHello\n"), time.Time{})
+	if err := youtubeUpload(clipName, uploadOptions{chunkSize: ytapi.DefaultChunkSize}); err != nil {
+		t.Fatal(err)
+	}
+	if err := youtubeCaption(clipName, "", false); err != nil {
+		t.Fatal(err)
+	}
+	props := srv.File(folder).AppProperties
+	videoId := props[drapi.VIDEO_ID]
+	langs := make(map[string]string)
+	for _, c := range srv.Captions(videoId) {
+		langs[c.Snippet.Language] = c.Id
+	}
+	if len(langs) != 3 || langs["zh-tw"] != props[drapi.CAPTION_ID] ||
+		langs["zh-cn"] != props[drapi.CAPTION_PREFIX+"zh-cn"] || langs["en"] != props[drapi.CAPTION_PREFIX+"en"] {
+		t.Fatalf("tracks %v, properties %v", langs, props)
+	}
+	if got := string(srv.CaptionContent(langs["en"])); !strings.Contains(got, "Hello") {
+		t.Errorf("en track = %q", got)
+	}
+
+	// only the changed English file is uploaded again
+	srv.UpdateFile(en, []byte("1\n00:00:01,000 --> 00:00:02,000\nHi\n"))
+	calls := len(srv.Calls())
+	if err := youtubeCaption(clipName, "", false); err != nil {
+		t.Fatal(err)
+	}
+	var pushed []string
+	for _, call := range srv.Calls()[calls:] {
+		if strings.Contains(call, "/upload/youtube/v3/captions") {
+			pushed = append(pushed, call)
+		}
+	}
+	if len(pushed) != 1 || !strings.Contains(string(srv.CaptionContent(langs["en"])), "Hi") {
+		t.Errorf("uploads %v after changing the en file", pushed)
+	}
+
+	if err := youtubeCaption(clipName, "th", false); err == nil {
+		t.Error("uploading a track without a file succeeded")
+	}
+	if err := youtubeDeleteCaption(clipName, "en"); err != nil {
+		t.Fatal(err)
+	}
+	props = srv.File(folder).AppProperties
+	if props[drapi.CAPTION_PREFIX+"en"] != "" || props[drapi.CAPTION_ID] == "" || len(srv.Captions(videoId)) != 2 {
+		t.Errorf("after deleting en: properties %v, %d tracks", props, len(srv.Captions(videoId)))
+	}
+}
+
+func TestCaptionPull(t *testing.T) {
+	srv := useFake(t)
+	folder := srv.AddFolder(clipName, nil)
+	srv.AddFile(folder, clipName+".mp4", []byte("video"), time.Time{})
+	srv.AddFile(folder, clipName+".srt", []byte("1\n00:00:01,000 --> 00:00:02,000\n你好\n"), time.Time{})
+	if err := youtubeUpload(clipName, uploadOptions{chunkSize: ytapi.DefaultChunkSize}); err != nil {
+		t.Fatal(err)
+	}
+	videoId := srv.File(folder).AppProperties[drapi.VIDEO_ID]
+	// a track added in YouTube Studio
+	studio := "1\n00:00:01,000 --> 00:00:02,000\nHello from Studio\n"
+	srv.AddCaption(videoId, "en", "English", []byte(studio))
+
+	pulled := func() map[string]string {
+		// Drive keeps files of the same name apart, so key them by id
+		files := make(map[string]string)
+		for _, f := range srv.Children(folder) {
+			if drapi.IsPulled(f.Name) {
+				files[f.Id] = f.Name + "\n" + string(srv.Content(f.Id))
+			}
+		}
+		return files
+	}
+	if err := youtubeCaptionPull(clipName, "en"); err != nil {
+		t.Fatal(err)
+	}
+	files := pulled()
+	if len(files) != 1 {
+		t.Fatalf("pulled %v", files)
+	}
+	for _, file := range files {
+		if !strings.HasPrefix(file, clipName+".en.yt-") || !strings.HasSuffix(file, ".srt\n"+studio) {
+			t.Errorf("pulled %q", file)
+		}
+	}
+
+	// pulled copies are not uploaded
+	if err := youtubeCaption(clipName, "", false); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range srv.Captions(videoId) {
+		if strings.Contains(string(srv.CaptionContent(c.Id)), "Studio") && c.Snippet.Language != "en" {
+			t.Errorf("pulled file uploaded as %s", c.Snippet.Language)
+		}
+	}
+
+	// deleting keeps a copy of every track that can be downloaded: not
+	// the generated one, and here the English one is refused
+	srv.AddASRCaption(videoId, "en")
+	var english string
+	for _, c := range srv.Captions(videoId) {
+		if c.Snippet.Language == "en" && c.Snippet.TrackKind != "asr" {
+			english = c.Id
+		}
+	}
+	srv.FailNext("GET", "/youtube/v3/captions/"+english, 403, "forbidden")
+	before := len(pulled())
+	var err error
+	out := captureStdout(t, func() { err = youtubeDeleteCaption(clipName, "") })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(pulled()) - before; n != 2 {
+		t.Errorf("delete pulled %d tracks, want zh-tw and zh-cn", n)
+	}
+	if !strings.Contains(out, "could not pull en caption "+english) {
+		t.Errorf("refused track not reported: %q", out)
+	}
+	if len(srv.Captions(videoId)) != 0 {
+		t.Error("tracks not deleted")
+	}
+}
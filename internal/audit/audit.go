@@ -0,0 +1,173 @@
+// Package audit formats the operation log kept in each clip folder: one
+// JSON entry per line recording who changed the folder or its video,
+// when, and the folder metadata before and after.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FileName is the log file inside the clip folder.
+const FileName = "ytmgr-audit.jsonl"
+
+type Entry struct {
+	Time time.Time `json:"time"`
+	User string    `json:"user"`
+	Host string    `json:"host,omitempty"`
+	Op   string    `json:"op"`
+	// Before and After are the folder's app properties.
+	Before map[string]string `json:"before"`
+	After  map[string]string `json:"after"`
+	// BeforeMeta and AfterMeta are the whole folder metadata as JSON,
+	// including the fields that are not kept in app properties.
+	BeforeMeta json.RawMessage `json:"beforeMeta,omitempty"`
+	AfterMeta  json.RawMessage `json:"afterMeta,omitempty"`
+	// Ids holds the ids the APIs returned or acted on, e.g. the id of a
+	// deleted video.
+	Ids   map[string]string `json:"ids,omitempty"`
+	Error string            `json:"error,omitempty"`
+}
+
+// New starts an entry for op by the current OS user.
+func New(op string) *Entry {
+	host, _ := os.Hostname()
+	return &Entry{Time: time.Now().UTC(), User: currentUser(), Host: host, Op: op, Ids: map[string]string{}}
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	for _, key := range []string{"USER", "USERNAME"} {
+		if name := os.Getenv(key); name != "" {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// Snapshot encodes v for BeforeMeta or AfterMeta, nil if it cannot be
+// encoded.
+func Snapshot(v interface{}) json.RawMessage {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// Line is the entry as one line of the log.
+func (e *Entry) Line() ([]byte, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// Parse reads a log, oldest entry first.
+func Parse(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for n := 1; scanner.Scan(); n++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", FileName, n, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// Change is one app property or metadata field the operation changed.
+type Change struct {
+	Key, Before, After string
+}
+
+// Changes lists the properties that differ between Before and After,
+// followed by the other metadata fields that differ between BeforeMeta
+// and AfterMeta, each sorted by key.
+func (e *Entry) Changes() []Change {
+	keys := map[string]bool{}
+	for k := range e.Before {
+		keys[k] = true
+	}
+	for k := range e.After {
+		keys[k] = true
+	}
+	var changes []Change
+	for k := range keys {
+		if e.Before[k] != e.After[k] {
+			changes = append(changes, Change{k, e.Before[k], e.After[k]})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return append(changes, e.metaChanges(keys)...)
+}
+
+// metaChanges compares the scalar fields of the metadata snapshots,
+// skipping the ones app properties already cover: a field named like a
+// property, ignoring case, or holding a map that is spread over several.
+func (e *Entry) metaChanges(props map[string]bool) []Change {
+	before, after := fields(e.BeforeMeta), fields(e.AfterMeta)
+	if before == nil || after == nil {
+		return nil
+	}
+	covered := func(key string) bool {
+		for k := range props {
+			if strings.EqualFold(k, key) {
+				return true
+			}
+		}
+		return false
+	}
+	var changes []Change
+	for k, a := range after {
+		if a != before[k] && !covered(k) {
+			changes = append(changes, Change{k, before[k], a})
+		}
+	}
+	for k, b := range before {
+		if _, ok := after[k]; !ok && b != "" && !covered(k) {
+			changes = append(changes, Change{k, b, ""})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// fields decodes the scalar fields of a metadata snapshot as text; null
+// fields read as empty.
+func fields(meta json.RawMessage) map[string]string {
+	if len(meta) == 0 {
+		return nil
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(meta, &raw); err != nil {
+		return nil
+	}
+	m := make(map[string]string)
+	for k, v := range raw {
+		switch v := v.(type) {
+		case nil:
+			m[k] = ""
+		case string:
+			m[k] = v
+		case float64, bool:
+			m[k] = fmt.Sprint(v)
+		}
+	}
+	return m
+}
@@ -0,0 +1,62 @@
+package audit
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	var log bytes.Buffer
+	first := New("upload")
+	first.After = map[string]string{"videoId": "v1", "privacy": "unlisted"}
+	first.Ids["videoId"] = "v1"
+	second := New("delete video")
+	second.Before = first.After
+	second.After = map[string]string{"videoId": "", "privacy": "", "series": "qa"}
+	second.Error = "boom"
+	for _, e := range []*Entry{first, second} {
+		line, err := e.Line()
+		if err != nil {
+			t.Fatal(err)
+		}
+		log.Write(line)
+		log.WriteString("\n")
+	}
+	entries, err := Parse(&log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Op != "upload" || entries[0].User == "" || entries[1].Error != "boom" {
+		t.Fatalf("entries = %+v", entries)
+	}
+	want := []Change{{"privacy", "unlisted", ""}, {"series", "", "qa"}, {"videoId", "v1", ""}}
+	if got := entries[1].Changes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("changes = %+v, want %+v", got, want)
+	}
+
+	if _, err := Parse(strings.NewReader("{}\nnot json\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected an error for line 2, got %v", err)
+	}
+}
+
+func TestMetaChanges(t *testing.T) {
+	e := New("set meta")
+	e.Before = map[string]string{"videoId": "v1"}
+	e.After = map[string]string{"videoId": "v2"}
+	e.BeforeMeta = Snapshot(map[string]interface{}{"Title": "舊", "VideoId": "v1", "Smin": 37, "Pushed": map[string]string{"video": "a"}})
+	e.AfterMeta = Snapshot(map[string]interface{}{"Title": "新", "VideoId": "v2", "Smin": 37, "Pushed": map[string]string{"video": "b"}, "Series": nil})
+	line, err := e.Line()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := Parse(bytes.NewReader(line))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Change{{"videoId", "v1", "v2"}, {"Title", "舊", "新"}}
+	if got := entries[0].Changes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("changes = %+v, want %+v", got, want)
+	}
+}
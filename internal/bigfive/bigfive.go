@@ -9,12 +9,21 @@ import (
 	"io/ioutil"
 	"log"
 	"strings"
+	"sync"
 
 	"github.com/liuzl/gocc"
 )
 
 var s2t *gocc.OpenCC
 
+// tw2s converts the other way, for the simplified caption track; it is
+// only loaded when needed.
+var (
+	tw2s     *gocc.OpenCC
+	tw2sErr  error
+	tw2sOnce sync.Once
+)
+
 type base64Loader struct {
 }
 
@@ -24,8 +33,11 @@ func (base64Loader) Open(configFile string) (io.ReadCloser, error) {
 	 replace them with '/' to work with zipped archive
 	*/
 	configFile = strings.ReplaceAll(configFile, `\`, `/`)
-	fmt.Println(configFile)
-	return io.NopCloser(bytes.NewReader(openZippedFile(configFile))), nil
+	content, err := openZippedFile(configFile)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
 }
 
 var zipStream []byte
@@ -45,31 +57,20 @@ func init() {
 	// s2t.Convert("abc")
 }
 
-func openZippedFile(fileToOpen string) []byte {
+func openZippedFile(fileToOpen string) ([]byte, error) {
 
 	zipReader, err := zip.NewReader(bytes.NewReader(zipStream), int64(len(zipStream)))
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	// Read all the files from zip archive
 	for _, zipFile := range zipReader.File {
 		if zipFile.Name == strings.TrimSpace(fileToOpen) {
-			fmt.Println("Reading file:", zipFile.Name)
-			unzippedFileBytes, err := readZipFile(zipFile)
-			if err != nil {
-				log.Fatal(err)
-			}
-
-			return unzippedFileBytes
-			// fmt.Println(string(unzippedFileBytes))
-			// _ = unzippedFileBytes // this is unzipped file bytes
-
+			return readZipFile(zipFile)
 		}
 	}
-	log.Fatal("can't open file: " + fileToOpen)
-	// won't reach here
-	return nil
+	return nil, fmt.Errorf("can't open file: %s", fileToOpen)
 }
 
 func readZipFile(zf *zip.File) ([]byte, error) {
@@ -89,3 +90,15 @@ func ToBig5(s string) string {
 	}
 	return ret
 }
+
+// ToSimplified converts Taiwan traditional Chinese to simplified Chinese,
+// phrases included.
+func ToSimplified(s string) (string, error) {
+	tw2sOnce.Do(func() {
+		tw2s, tw2sErr = gocc.New("tw2s", gocc.WithLoader(base64Loader{}))
+	})
+	if tw2sErr != nil {
+		return "", fmt.Errorf("load tw2s dictionary: %w", tw2sErr)
+	}
+	return tw2s.Convert(s)
+}
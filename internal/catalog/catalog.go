@@ -0,0 +1,430 @@
+// Package catalog keeps a local SQLite copy of the clip folder metadata,
+// so listing, searching and status reports work without calling Drive.
+//
+// A Catalog is filled by Sync and kept current by passing it to the Drive
+// client as its Cache, which stores every folder the client loads or
+// updates.
+package catalog
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	drapi "twsati/internal/google/drive"
+
+	_ "github.com/mattn/go-sqlite3"
+	"google.golang.org/api/drive/v3"
+)
+
+// ErrNotCached is returned for folders the catalog does not know.
+var ErrNotCached = errors.New("not in the catalog")
+
+// migrations bring the schema from version i to i+1.
+var migrations = []string{
+	`CREATE TABLE clips (
+		folder_id  TEXT PRIMARY KEY,
+		name       TEXT NOT NULL,
+		title      TEXT NOT NULL,
+		date       TEXT NOT NULL,
+		smin       INTEGER NOT NULL,
+		ssec       INTEGER NOT NULL,
+		emin       INTEGER NOT NULL,
+		esec       INTEGER NOT NULL,
+		video_id   TEXT,
+		caption_id TEXT,
+		privacy    TEXT,
+		series     TEXT,
+		scheduled  TEXT,
+		release    TEXT NOT NULL DEFAULT '{}',
+		synced_at  TEXT NOT NULL
+	);
+	CREATE INDEX clips_name ON clips(name);
+	CREATE TABLE files (
+		file_id   TEXT PRIMARY KEY,
+		folder_id TEXT NOT NULL REFERENCES clips(folder_id) ON DELETE CASCADE,
+		name      TEXT NOT NULL,
+		mime_type TEXT NOT NULL,
+		md5       TEXT NOT NULL,
+		size      INTEGER NOT NULL,
+		modified  TEXT NOT NULL
+	);
+	CREATE INDEX files_folder ON files(folder_id);
+	CREATE TABLE state (
+		key   TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);`,
+	`ALTER TABLE clips ADD COLUMN deleted TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE clips ADD COLUMN pushed TEXT NOT NULL DEFAULT '{}';`,
+	`ALTER TABLE clips ADD COLUMN captions TEXT NOT NULL DEFAULT '{}';`,
+}
+
+type Catalog struct {
+	db   *sql.DB
+	path string
+	// now is replaced in tests.
+	now func() time.Time
+}
+
+// DefaultPath is where the catalog lives unless configured otherwise.
+func DefaultPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "ytmgr", "catalog.db"), nil
+}
+
+// Open opens the catalog at path, creating it when needed.
+func Open(path string) (*Catalog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite3", path+"?_foreign_keys=on&_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	// one connection serializes the writers of concurrent batch workers
+	db.SetMaxOpenConns(1)
+	c := &Catalog{db: db, path: path, now: time.Now}
+	if err := c.migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("catalog %s: %w", path, err)
+	}
+	return c, nil
+}
+
+func (c *Catalog) migrate() error {
+	var version int
+	if err := c.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	for ; version < len(migrations); version++ {
+		tx, err := c.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[version]); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Catalog) Close() error {
+	return c.db.Close()
+}
+
+// Path is the database file.
+func (c *Catalog) Path() string {
+	return c.path
+}
+
+// Entry is a cached folder. Its VideoMeta has no Drive client, so the
+// folder files cannot be downloaded through it.
+type Entry struct {
+	*drapi.VideoMeta
+	SyncedAt time.Time
+}
+
+func nullString(ptr *string) sql.NullString {
+	if ptr == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *ptr, Valid: true}
+}
+
+func stringPtr(ns sql.NullString) *string {
+	if !ns.Valid {
+		return nil
+	}
+	return &ns.String
+}
+
+// Store saves the folder metadata and file list as synced now.
+func (c *Catalog) Store(vmeta *drapi.VideoMeta) error {
+	return c.store(vmeta, c.now())
+}
+
+func (c *Catalog) store(vmeta *drapi.VideoMeta, syncedAt time.Time) error {
+	release, err := json.Marshal(vmeta.Release)
+	if err != nil {
+		return err
+	}
+	pushed, err := json.Marshal(vmeta.Pushed)
+	if err != nil {
+		return err
+	}
+	captions, err := json.Marshal(vmeta.Captions)
+	if err != nil {
+		return err
+	}
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec(`INSERT INTO clips (folder_id, name, title, date, smin, ssec, emin, esec,
+			video_id, caption_id, privacy, series, scheduled, release, deleted, pushed, captions, synced_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (folder_id) DO UPDATE SET name = excluded.name, title = excluded.title,
+			date = excluded.date, smin = excluded.smin, ssec = excluded.ssec, emin = excluded.emin,
+			esec = excluded.esec, video_id = excluded.video_id, caption_id = excluded.caption_id,
+			privacy = excluded.privacy, series = excluded.series, scheduled = excluded.scheduled,
+			release = excluded.release, deleted = excluded.deleted,
+			pushed = excluded.pushed, captions = excluded.captions, synced_at = excluded.synced_at`,
+		vmeta.FolderId, vmeta.FolderName(), vmeta.Title, vmeta.Date.Format("2006-01-02"),
+		vmeta.Smin, vmeta.Ssec, vmeta.Emin, vmeta.Esec,
+		nullString(vmeta.VideoId), nullString(vmeta.CaptionId), nullString(vmeta.Privacy),
+		nullString(vmeta.Series), nullString(vmeta.Scheduled), string(release),
+		strings.Join(vmeta.Deleted, ","), string(pushed), string(captions), syncedAt.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return err
+	}
+	// folders loaded without their contents keep the cached file list
+	if vmeta.Children != nil {
+		if _, err := tx.Exec("DELETE FROM files WHERE folder_id = ?", vmeta.FolderId); err != nil {
+			return err
+		}
+		for _, f := range vmeta.Children {
+			_, err := tx.Exec(`INSERT OR REPLACE INTO files (file_id, folder_id, name, mime_type, md5, size, modified)
+				VALUES (?, ?, ?, ?, ?, ?, ?)`, f.Id, vmeta.FolderId, f.Name, f.MimeType, f.Md5Checksum, f.Size, f.ModifiedTime)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+const clipColumns = `folder_id, name, video_id, caption_id, privacy, series, scheduled, release, deleted, pushed, captions, synced_at`
+
+func scanEntry(row interface{ Scan(...interface{}) error }) (*Entry, error) {
+	var folderId, name, release, deleted, pushed, captions, syncedAt string
+	var videoId, captionId, privacy, series, scheduled sql.NullString
+	if err := row.Scan(&folderId, &name, &videoId, &captionId, &privacy, &series, &scheduled, &release, &deleted, &pushed, &captions, &syncedAt); err != nil {
+		return nil, err
+	}
+	vmeta, err := drapi.MetaFromName(name)
+	if err != nil {
+		return nil, err
+	}
+	vmeta.FolderId = folderId
+	vmeta.VideoId = stringPtr(videoId)
+	vmeta.CaptionId = stringPtr(captionId)
+	vmeta.Privacy = stringPtr(privacy)
+	vmeta.Series = stringPtr(series)
+	vmeta.Scheduled = stringPtr(scheduled)
+	if err := json.Unmarshal([]byte(release), &vmeta.Release); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(pushed), &vmeta.Pushed); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(captions), &vmeta.Captions); err != nil {
+		return nil, err
+	}
+	if deleted != "" {
+		vmeta.Deleted = strings.Split(deleted, ",")
+	}
+	at, err := time.Parse(time.RFC3339Nano, syncedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &Entry{VideoMeta: vmeta, SyncedAt: at}, nil
+}
+
+// Get returns the cached folder with its file list.
+func (c *Catalog) Get(name string) (*Entry, error) {
+	entry, err := scanEntry(c.db.QueryRow("SELECT "+clipColumns+" FROM clips WHERE name = ?", name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &drapi.FolderError{Name: name, Err: ErrNotCached}
+	} else if err != nil {
+		return nil, err
+	}
+	rows, err := c.db.Query(`SELECT file_id, name, mime_type, md5, size, modified FROM files
+		WHERE folder_id = ? ORDER BY name`, entry.FolderId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	entry.Children = []*drive.File{}
+	for rows.Next() {
+		f := &drive.File{}
+		if err := rows.Scan(&f.Id, &f.Name, &f.MimeType, &f.Md5Checksum, &f.Size, &f.ModifiedTime); err != nil {
+			return nil, err
+		}
+		entry.Children = append(entry.Children, f)
+	}
+	return entry, rows.Err()
+}
+
+// Query selects catalog entries; empty fields match everything.
+type Query struct {
+	// Text is looked for in the folder name and the title.
+	Text    string
+	Privacy string
+	Series  string
+}
+
+// List returns the entries matching q, newest clip first. The file lists
+// are not loaded.
+func (c *Catalog) List(q Query) ([]*Entry, error) {
+	var where []string
+	var args []interface{}
+	if q.Text != "" {
+		where = append(where, "(name LIKE ? ESCAPE '\\' OR title LIKE ? ESCAPE '\\')")
+		pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(q.Text) + "%"
+		args = append(args, pattern, pattern)
+	}
+	if q.Privacy != "" {
+		where = append(where, "privacy = ?")
+		args = append(args, q.Privacy)
+	}
+	if q.Series != "" {
+		where = append(where, "series = ?")
+		args = append(args, q.Series)
+	}
+	query := "SELECT " + clipColumns + " FROM clips"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	rows, err := c.db.Query(query+" ORDER BY date DESC, name", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ret []*Entry
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, entry)
+	}
+	return ret, rows.Err()
+}
+
+// Counts reports how many cached clips have each privacy status; clips
+// never uploaded are counted under "".
+func (c *Catalog) Counts() (map[string]int, error) {
+	rows, err := c.db.Query("SELECT COALESCE(privacy, ''), COUNT(*) FROM clips GROUP BY 1")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := map[string]int{}
+	for rows.Next() {
+		var privacy string
+		var n int
+		if err := rows.Scan(&privacy, &n); err != nil {
+			return nil, err
+		}
+		counts[privacy] = n
+	}
+	return counts, rows.Err()
+}
+
+// LastSync is when the last Sync started, zero if it never ran.
+func (c *Catalog) LastSync() (time.Time, error) {
+	var value string
+	err := c.db.QueryRow("SELECT value FROM state WHERE key = 'last_sync'").Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	} else if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+func (c *Catalog) setLastSync(t time.Time) error {
+	_, err := c.db.Exec("INSERT OR REPLACE INTO state (key, value) VALUES ('last_sync', ?)", t.UTC().Format(time.RFC3339Nano))
+	return err
+}
+
+// Fresh reports whether a sync ran within maxAge.
+func (c *Catalog) Fresh(maxAge time.Duration) bool {
+	last, err := c.LastSync()
+	return err == nil && !last.IsZero() && c.now().Sub(last) <= maxAge
+}
+
+// SyncStats summarizes a Sync.
+type SyncStats struct {
+	Full    bool
+	Updated int
+	Removed int
+}
+
+// syncSlack covers clock differences between this machine and Drive.
+const syncSlack = time.Minute
+
+// Sync refreshes the catalog from Drive: only the folders changed since
+// the last sync, or every folder when full is set or the catalog is
+// empty. Only a full sync notices folders deleted for good.
+func (c *Catalog) Sync(d *drapi.Client, full bool) (SyncStats, error) {
+	last, err := c.LastSync()
+	if err != nil {
+		return SyncStats{}, err
+	}
+	stats := SyncStats{Full: full || last.IsZero()}
+	since := time.Time{}
+	if !stats.Full {
+		since = last.Add(-syncSlack)
+	}
+	started := c.now()
+	metas, trashed, err := d.ChangedVideoMeta(since)
+	if err != nil {
+		return stats, err
+	}
+	seen := map[string]bool{}
+	for _, vmeta := range metas {
+		if err := c.store(vmeta, started); err != nil {
+			return stats, err
+		}
+		seen[vmeta.FolderId] = true
+		stats.Updated++
+	}
+	if stats.Full {
+		// everything not listed is gone
+		rows, err := c.db.Query("SELECT folder_id FROM clips")
+		if err != nil {
+			return stats, err
+		}
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return stats, err
+			}
+			if !seen[id] {
+				trashed = append(trashed, id)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return stats, err
+		}
+	}
+	for _, id := range trashed {
+		res, err := c.db.Exec("DELETE FROM clips WHERE folder_id = ?", id)
+		if err != nil {
+			return stats, err
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			stats.Removed++
+		}
+	}
+	return stats, c.setLastSync(started)
+}
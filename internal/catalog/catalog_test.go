@@ -0,0 +1,144 @@
+package catalog
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+	drapi "twsati/internal/google/drive"
+	"twsati/internal/google/fake"
+	"twsati/internal/google/retry"
+)
+
+const clipName = "zh230114_[37.34-38.51]_生命中別投降別氣餒"
+
+func newCatalog(t *testing.T) (*Catalog, *drapi.Client, *fake.Server) {
+	srv := fake.NewServer()
+	t.Cleanup(srv.Close)
+	c, err := Open(filepath.Join(t.TempDir(), "sub", "catalog.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	d, err := drapi.NewClient(drapi.Options{HTTPClient: srv.HTTPClient(), Endpoint: srv.DriveEndpoint(),
+		Retry: &retry.Policy{MaxAttempts: 1, Sleep: func(time.Duration) {}}, Cache: c})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, d, srv
+}
+
+func TestSync(t *testing.T) {
+	c, d, srv := newCatalog(t)
+	folder := srv.AddFolder(clipName, map[string]string{drapi.VIDEO_ID: "vid1", drapi.PRIVACY: "unlisted"})
+	srv.AddFile(folder, clipName+".mp4", []byte("video"), time.Time{})
+	other := srv.AddFolder("zh230115_[01.00-02.00]_第二段", nil)
+	srv.AddFolder("not a clip", nil)
+
+	stats, err := c.Sync(d, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stats.Full || stats.Updated != 2 {
+		t.Errorf("first sync = %+v, want a full sync of 2 folders", stats)
+	}
+	entry, err := c.Get(clipName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.FolderId != folder || *entry.VideoId != "vid1" || entry.Title != "生命中別投降別氣餒" || entry.Smin != 37 {
+		t.Errorf("unexpected entry %+v", entry.VideoMeta)
+	}
+	if len(entry.Children) != 1 || entry.Children[0].Md5Checksum == "" || !entry.HasVideo() {
+		t.Errorf("files = %+v", entry.Children)
+	}
+
+	// a new file is picked up incrementally
+	srv.AddFile(other, "zh230115.srt", []byte("srt"), time.Time{})
+	if stats, err = c.Sync(d, false); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Full {
+		t.Error("second sync should be incremental")
+	}
+	if entry, err := c.Get("zh230115_[01.00-02.00]_第二段"); err != nil || !entry.HasCaption() {
+		t.Errorf("new caption not synced: %v", err)
+	}
+
+	// updates through the client are written through
+	vmeta, err := d.GetVideoMeta(clipName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public := "public"
+	vmeta.Privacy = &public
+	vmeta.MarkPushed(drapi.VIDEO_FILE)
+	vmeta.Tombstone("vid0")
+	vmeta.Captions = map[string]string{"en": "cap1"}
+	if err := d.UpdateVideoMeta(vmeta); err != nil {
+		t.Fatal(err)
+	}
+	if entry, err := c.Get(clipName); err != nil || !entry.Unchanged(drapi.VIDEO_FILE) || len(entry.Deleted) != 1 || entry.Captions["en"] != "cap1" {
+		t.Errorf("pushed checksums, tombstones or caption tracks not cached: %+v, %v", entry, err)
+	}
+	if entries, err := c.List(Query{Privacy: "public"}); err != nil || len(entries) != 1 || entries[0].FolderName() != clipName {
+		t.Errorf("public clips = %v, %v", entries, err)
+	}
+
+	// a deleted folder disappears with the next full sync
+	req, _ := http.NewRequest(http.MethodDelete, srv.DriveEndpoint()+"files/"+other, nil)
+	if resp, err := srv.HTTPClient().Do(req); err != nil {
+		t.Fatal(err)
+	} else {
+		resp.Body.Close()
+	}
+	if stats, err = c.Sync(d, true); err != nil || stats.Removed != 1 {
+		t.Errorf("full sync = %+v, %v", stats, err)
+	}
+	if _, err := c.Get("zh230115_[01.00-02.00]_第二段"); !errors.Is(err, ErrNotCached) {
+		t.Errorf("expected ErrNotCached, got %v", err)
+	}
+	if counts, err := c.Counts(); err != nil || counts["public"] != 1 || len(counts) != 1 {
+		t.Errorf("counts = %v, %v", counts, err)
+	}
+}
+
+func TestListAndFresh(t *testing.T) {
+	c, _, _ := newCatalog(t)
+	for i, name := range []string{clipName, "zh230115_[01.00-02.00]_100%專注", "zh230116_[01.00-02.00]_第三段"} {
+		vmeta, err := drapi.MetaFromName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vmeta.FolderId = name
+		if i == 1 {
+			series := "qa"
+			vmeta.Series = &series
+		}
+		if err := c.Store(vmeta); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := c.List(Query{})
+	if err != nil || len(entries) != 3 || entries[0].FolderName() != "zh230116_[01.00-02.00]_第三段" {
+		t.Fatalf("entries = %v, %v", entries, err)
+	}
+	for q, want := range map[Query]int{{Text: "別投降"}: 1, {Text: "%"}: 1, {Text: "_"}: 3, {Series: "qa"}: 1, {Text: "段", Series: "qa"}: 0} {
+		if entries, err := c.List(q); err != nil || len(entries) != want {
+			t.Errorf("List(%+v) = %d entries, %v; want %d", q, len(entries), err, want)
+		}
+	}
+
+	if c.Fresh(time.Hour) {
+		t.Error("never synced catalog reported fresh")
+	}
+	now := time.Now()
+	if err := c.setLastSync(now); err != nil {
+		t.Fatal(err)
+	}
+	c.now = func() time.Time { return now.Add(30 * time.Minute) }
+	if !c.Fresh(time.Hour) || c.Fresh(time.Minute) {
+		t.Error("freshness does not follow the last sync")
+	}
+}
@@ -0,0 +1,355 @@
+// Package config loads the ytmgr settings: the YouTube channel, upload
+// defaults, caption track, credential locations and description footer.
+//
+// Settings start from Defaults, are overlaid by the first config file
+// found (ytmgr.json in the working directory, then
+// ~/.config/ytmgr/config.json, or the file named by YTMGR_CONFIG) and
+// finally by YTMGR_* environment variables.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"twsati/internal/google/auth"
+	"twsati/internal/templates"
+)
+
+// Credentials locates the OAuth client secret and cached token of one API.
+type Credentials struct {
+	ClientSecretFile string `json:"clientSecretFile"`
+	TokenFile        string `json:"tokenFile"`
+}
+
+// Series lays out the titles and descriptions of one kind of video.
+// Templates are given inline or read from a file; see package templates
+// for the helper funcs.
+type Series struct {
+	// Prefix selects the series for folder names starting with it when
+	// the folder does not name a series itself.
+	Prefix          string `json:"prefix,omitempty"`
+	Title           string `json:"title,omitempty"`
+	TitleFile       string `json:"titleFile,omitempty"`
+	Description     string `json:"description,omitempty"`
+	DescriptionFile string `json:"descriptionFile,omitempty"`
+	// Playlist is the playlist release adds the videos to, at the start
+	// when PlaylistPosition is "first" and at the end otherwise.
+	Playlist         string `json:"playlist,omitempty"`
+	PlaylistPosition string `json:"playlistPosition,omitempty"`
+}
+
+// Caption names the caption track uploaded for each video and sets the
+// limits caption lint warns about.
+type Caption struct {
+	Language string `json:"language"`
+	Name     string `json:"name"`
+	// MaxCPS is the most characters a cue may show per second and
+	// MaxLineLength the longest line; MaxGap, a Go duration, is the
+	// longest stretch without captions.
+	MaxCPS        float64 `json:"maxCps"`
+	MaxLineLength int     `json:"maxLineLength"`
+	MaxGap        string  `json:"maxGap"`
+	// Simplified is the language of the track converted from the main
+	// track with OpenCC, empty for none, and SimplifiedName its name.
+	Simplified     string `json:"simplified"`
+	SimplifiedName string `json:"simplifiedName"`
+	// Tracks names the tracks uploaded from <name>.<lang>.srt files by
+	// language; other languages are named by their code.
+	Tracks map[string]string `json:"tracks"`
+}
+
+// TrackName is the name of the caption track in lang.
+func (c Caption) TrackName(lang string) string {
+	switch {
+	case lang == c.Language:
+		return c.Name
+	case lang == c.Simplified:
+		return c.SimplifiedName
+	case c.Tracks[lang] != "":
+		return c.Tracks[lang]
+	}
+	return lang
+}
+
+// Gap is MaxGap parsed; Load has checked it.
+func (c Caption) Gap() time.Duration {
+	d, _ := time.ParseDuration(c.MaxGap)
+	return d
+}
+
+// Watch says what the watch command does with a clip folder whose files
+// changed, once it has been left alone for Debounce.
+type Watch struct {
+	// Interval is how often the Drive changes feed is read and Debounce
+	// how long a folder has to stay unchanged; both are Go durations.
+	Interval string `json:"interval"`
+	Debounce string `json:"debounce"`
+	// Caption uploads a changed .srt, Description pushes the title and
+	// description rendered from a changed .txt.
+	Caption     bool `json:"caption"`
+	Description bool `json:"description"`
+	// Notify is a command run for every settled folder, with the folder
+	// name and the names of the changed files appended as arguments.
+	Notify []string `json:"notify,omitempty"`
+}
+
+// PollInterval is Interval parsed; Load has checked it.
+func (w Watch) PollInterval() time.Duration {
+	d, _ := time.ParseDuration(w.Interval)
+	return d
+}
+
+// QuietPeriod is Debounce parsed; Load has checked it.
+func (w Watch) QuietPeriod() time.Duration {
+	d, _ := time.ParseDuration(w.Debounce)
+	return d
+}
+
+type Config struct {
+	ChannelId         string      `json:"channelId"`
+	CategoryId        string      `json:"categoryId"`
+	Tags              []string    `json:"tags"`
+	Caption           Caption     `json:"caption"`
+	Drive             Credentials `json:"drive"`
+	YouTube           Credentials `json:"youtube"`
+	DescriptionFooter string      `json:"descriptionFooter"`
+	// Workers is how many folders a command processes at the same time.
+	Workers int `json:"workers"`
+	// Catalog is the local catalog database, by default catalog.db in the
+	// user cache directory; "off" disables it.
+	Catalog string `json:"catalog"`
+	// CatalogMaxAge is how long after a sync the catalog answers in place
+	// of Drive, as a Go duration such as "1h".
+	CatalogMaxAge string `json:"catalogMaxAge"`
+	Watch         Watch  `json:"watch"`
+
+	Series        map[string]Series `json:"series"`
+	DefaultSeries string            `json:"defaultSeries"`
+
+	// Source is the config file the settings were read from, empty when
+	// none was found.
+	Source string `json:"-"`
+	// Overrides lists the environment variables that were applied.
+	Overrides []string `json:"-"`
+}
+
+const FileName = "ytmgr.json"
+
+var ErrUnknownSeries = errors.New("unknown series")
+
+// Defaults returns the settings used when nothing is configured.
+func Defaults() *Config {
+	return &Config{
+		ChannelId:  "UCrCmgRwcNRhuMEtpoH-VVWg",
+		CategoryId: "27",
+		Tags:       []string{"meditation"},
+		Caption: Caption{Language: "zh-tw", Name: "繁體", MaxCPS: 9, MaxLineLength: 20, MaxGap: "30s",
+			Simplified: "zh-cn", SimplifiedName: "简体", Tracks: map[string]string{"en": "English", "th": "ไทย"}},
+		Drive: Credentials{
+			ClientSecretFile: auth.HomeFile("client_secret_drive.json"),
+			TokenFile:        auth.HomeFile(".credentials", "drive-go-quickstart.json"),
+		},
+		YouTube: Credentials{
+			ClientSecretFile: auth.HomeFile("client_secret.json"),
+			TokenFile:        auth.HomeFile(".credentials", "youtube-go-quickstart.json"),
+		},
+		DescriptionFooter: `
+本文內容是根據尊者直播視頻聽錄、整理而成，文字未經尊者及譯者審校，若內容有任何疏失，皆歸咎於聽錄、整理者的責任與過失。
+直播同聲翻譯｜坤能•禪窗
+文字整理｜台灣四念處學會`,
+		Workers:       4,
+		CatalogMaxAge: "1h",
+		Watch:         Watch{Interval: "1m", Debounce: "5m", Caption: true, Description: true},
+		Series: map[string]Series{
+			"micro": {
+				Title:       "微視頻-{{.Title}} (繁體中文) ｜ {{zhDate .Date}}",
+				Description: "【{{.Title}}】\n\n{{.Content}}\n\n聽錄、摘錄自{{zhDate .Date}}直播開示 {{timeRange .Smin .Ssec .Emin .Esec}}{{.Footer}}",
+			},
+		},
+		DefaultSeries: "micro",
+	}
+}
+
+// SearchPath lists the config files Load looks for, in order.
+func SearchPath() []string {
+	if path := os.Getenv("YTMGR_CONFIG"); path != "" {
+		return []string{path}
+	}
+	return []string{FileName, auth.HomeFile(".config", "ytmgr", "config.json")}
+}
+
+// Load returns the effective settings.
+func Load() (*Config, error) {
+	cfg := Defaults()
+	for _, path := range SearchPath() {
+		err := cfg.readFile(path)
+		if errors.Is(err, fs.ErrNotExist) && os.Getenv("YTMGR_CONFIG") == "" {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		cfg.Source = path
+		break
+	}
+	cfg.applyEnv(os.LookupEnv)
+	if _, err := time.ParseDuration(cfg.CatalogMaxAge); err != nil {
+		return nil, fmt.Errorf("config catalogMaxAge: %w", err)
+	}
+	if _, err := time.ParseDuration(cfg.Caption.MaxGap); err != nil {
+		return nil, fmt.Errorf("config caption.maxGap: %w", err)
+	}
+	if d, err := time.ParseDuration(cfg.Watch.Interval); err != nil || d <= 0 {
+		return nil, fmt.Errorf("config watch.interval: %q is not a positive duration", cfg.Watch.Interval)
+	}
+	if _, err := time.ParseDuration(cfg.Watch.Debounce); err != nil {
+		return nil, fmt.Errorf("config watch.debounce: %w", err)
+	}
+	return cfg, nil
+}
+
+// readFile overlays the fields present in the JSON file at path.
+func (cfg *Config) readFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(content, cfg); err != nil {
+		return fmt.Errorf("config %s: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	for _, p := range []*string{&cfg.Drive.ClientSecretFile, &cfg.Drive.TokenFile, &cfg.YouTube.ClientSecretFile, &cfg.YouTube.TokenFile} {
+		*p = expandPath(*p, dir)
+	}
+	if cfg.Catalog != "off" {
+		cfg.Catalog = expandPath(cfg.Catalog, dir)
+	}
+	for name, series := range cfg.Series {
+		series.TitleFile = expandPath(series.TitleFile, dir)
+		series.DescriptionFile = expandPath(series.DescriptionFile, dir)
+		cfg.Series[name] = series
+	}
+	return nil
+}
+
+// expandPath resolves ~/ against the home directory and relative paths
+// against the directory of the config file.
+func expandPath(path, dir string) string {
+	if path == "" {
+		return path
+	}
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, `~\`) {
+		return auth.HomeFile(path[1:])
+	}
+	if !filepath.IsAbs(path) {
+		return filepath.Join(dir, path)
+	}
+	return path
+}
+
+func (cfg *Config) applyEnv(lookup func(string) (string, bool)) {
+	vars := []struct {
+		name string
+		set  func(string)
+	}{
+		{"YTMGR_CHANNEL_ID", func(v string) { cfg.ChannelId = v }},
+		{"YTMGR_CATEGORY_ID", func(v string) { cfg.CategoryId = v }},
+		{"YTMGR_TAGS", func(v string) { cfg.Tags = splitList(v) }},
+		{"YTMGR_CAPTION_LANGUAGE", func(v string) { cfg.Caption.Language = v }},
+		{"YTMGR_CAPTION_NAME", func(v string) { cfg.Caption.Name = v }},
+		{"YTMGR_DRIVE_CLIENT_SECRET", func(v string) { cfg.Drive.ClientSecretFile = expandPath(v, ".") }},
+		{"YTMGR_DRIVE_TOKEN", func(v string) { cfg.Drive.TokenFile = expandPath(v, ".") }},
+		{"YTMGR_YOUTUBE_CLIENT_SECRET", func(v string) { cfg.YouTube.ClientSecretFile = expandPath(v, ".") }},
+		{"YTMGR_YOUTUBE_TOKEN", func(v string) { cfg.YouTube.TokenFile = expandPath(v, ".") }},
+		{"YTMGR_DESCRIPTION_FOOTER", func(v string) { cfg.DescriptionFooter = v }},
+		{"YTMGR_DEFAULT_SERIES", func(v string) { cfg.DefaultSeries = v }},
+		{"YTMGR_CATALOG", func(v string) {
+			if v != "off" {
+				v = expandPath(v, ".")
+			}
+			cfg.Catalog = v
+		}},
+	}
+	for _, v := range vars {
+		if value, ok := lookup(v.name); ok {
+			v.set(value)
+			cfg.Overrides = append(cfg.Overrides, v.name)
+		}
+	}
+}
+
+func splitList(str string) []string {
+	var ret []string
+	for _, e := range strings.Split(str, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			ret = append(ret, e)
+		}
+	}
+	return ret
+}
+
+// MaxCatalogAge is CatalogMaxAge parsed; Load has checked it.
+func (cfg *Config) MaxCatalogAge() time.Duration {
+	d, _ := time.ParseDuration(cfg.CatalogMaxAge)
+	return d
+}
+
+// Keywords joins the tags the way the YouTube client expects them.
+func (cfg *Config) Keywords() string {
+	return strings.Join(cfg.Tags, ",")
+}
+
+// SeriesFor picks the series of a folder: the one the folder names, else
+// the one whose prefix the folder name starts with (longest wins), else
+// DefaultSeries.
+func (cfg *Config) SeriesFor(folderName, folderSeries string) string {
+	if folderSeries != "" {
+		return folderSeries
+	}
+	best := ""
+	for name, series := range cfg.Series {
+		if series.Prefix != "" && strings.HasPrefix(folderName, series.Prefix) && len(series.Prefix) > len(cfg.Series[best].Prefix) {
+			best = name
+		}
+	}
+	if best != "" {
+		return best
+	}
+	return cfg.DefaultSeries
+}
+
+// Templates compiles the templates of series name.
+func (cfg *Config) Templates(name string) (*templates.Set, error) {
+	series, ok := cfg.Series[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownSeries, name)
+	}
+	title, err := series.source(series.Title, series.TitleFile)
+	if err != nil {
+		return nil, err
+	}
+	description, err := series.source(series.Description, series.DescriptionFile)
+	if err != nil {
+		return nil, err
+	}
+	set, err := templates.Parse(name, title, description)
+	if err != nil {
+		return nil, fmt.Errorf("series %s: %w", name, err)
+	}
+	return set, nil
+}
+
+func (series Series) source(inline, file string) (string, error) {
+	if file == "" {
+		return inline, nil
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
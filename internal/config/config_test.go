@@ -0,0 +1,71 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "custom.json")
+	content := `{"channelId": "UCfile", "tags": ["a", "b"], "caption": {"language": "en"}, "drive": {"tokenFile": "tokens/drive.json"}}`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("YTMGR_CONFIG", path)
+	t.Setenv("YTMGR_CATEGORY_ID", "22")
+	t.Setenv("YTMGR_TAGS", "x, y,")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	def := Defaults()
+	if cfg.Source != path || cfg.ChannelId != "UCfile" || cfg.CategoryId != "22" {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+	if !reflect.DeepEqual(cfg.Tags, []string{"x", "y"}) {
+		t.Errorf("tags = %q", cfg.Tags)
+	}
+	// fields missing from the file keep their defaults
+	if cfg.Caption.Language != "en" || cfg.Caption.Name != def.Caption.Name || cfg.DescriptionFooter != def.DescriptionFooter {
+		t.Errorf("caption %+v, footer %q", cfg.Caption, cfg.DescriptionFooter)
+	}
+	if want := filepath.Join(dir, "tokens", "drive.json"); cfg.Drive.TokenFile != want {
+		t.Errorf("drive token = %q, want %q", cfg.Drive.TokenFile, want)
+	}
+	if !reflect.DeepEqual(cfg.Overrides, []string{"YTMGR_CATEGORY_ID", "YTMGR_TAGS"}) {
+		t.Errorf("overrides = %v", cfg.Overrides)
+	}
+}
+
+func TestLoadMissingExplicitFile(t *testing.T) {
+	t.Setenv("YTMGR_CONFIG", filepath.Join(t.TempDir(), "missing.json"))
+	if _, err := Load(); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected a not exist error, got %v", err)
+	}
+}
+
+func TestSeriesFor(t *testing.T) {
+	cfg := Defaults()
+	cfg.Series["qa"] = Series{Prefix: "zhQA"}
+	cfg.Series["talk"] = Series{Prefix: "zh"}
+	cases := []struct{ folder, property, want string }{
+		{"zhQA230114_[00.00-01.00]_問答", "", "qa"},
+		{"zh230114_[00.00-01.00]_開示", "", "talk"},
+		{"en230114_[00.00-01.00]_talk", "", "micro"},
+		{"zh230114_[00.00-01.00]_開示", "micro", "micro"},
+	}
+	for _, c := range cases {
+		if got := cfg.SeriesFor(c.folder, c.property); got != c.want {
+			t.Errorf("SeriesFor(%q, %q) = %q, want %q", c.folder, c.property, got, c.want)
+		}
+	}
+	if _, err := cfg.Templates("nope"); !errors.Is(err, ErrUnknownSeries) {
+		t.Errorf("expected ErrUnknownSeries, got %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+// Package apierr classifies errors returned by the Google API client
+// libraries into sentinel errors the commands can test with errors.Is.
+package apierr
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+
+	"google.golang.org/api/googleapi"
+)
+
+var (
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	ErrRateLimited   = errors.New("rate limited")
+	ErrNotFound      = errors.New("not found")
+	ErrForbidden     = errors.New("forbidden")
+	ErrUnauthorized  = errors.New("unauthorized")
+	ErrServer        = errors.New("server error")
+)
+
+// Error annotates a failed API call with the operation that failed and,
+// when recognised, one of the sentinel errors above.
+type Error struct {
+	Op   string
+	Kind error
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Kind != nil {
+		return fmt.Sprintf("%s: %v: %v", e.Op, e.Kind, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Op, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	return e.Kind != nil && e.Kind == target
+}
+
+// Wrap returns nil for a nil err, otherwise an *Error for op.
+func Wrap(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Op: op, Kind: Kind(err), Err: err}
+}
+
+// Kind maps a *googleapi.Error to one of the sentinel errors, or nil when
+// the error is not recognised.
+func Kind(err error) error {
+	var gerr *googleapi.Error
+	if !errors.As(err, &gerr) {
+		return nil
+	}
+	for _, item := range gerr.Errors {
+		switch item.Reason {
+		case "quotaExceeded", "dailyLimitExceeded":
+			return ErrQuotaExceeded
+		case "rateLimitExceeded", "userRateLimitExceeded":
+			return ErrRateLimited
+		}
+	}
+	switch {
+	case gerr.Code == http.StatusNotFound:
+		return ErrNotFound
+	case gerr.Code == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case gerr.Code == http.StatusForbidden:
+		return ErrForbidden
+	case gerr.Code == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case gerr.Code >= 500:
+		return ErrServer
+	}
+	return nil
+}
+
+// Retryable reports whether err is worth another attempt: rate limiting,
+// 5xx responses and dropped connections are; exhausted quota, auth and
+// not-found errors are not.
+func Retryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch Kind(err) {
+	case ErrRateLimited, ErrServer:
+		return true
+	case nil:
+	default:
+		return false
+	}
+	var nerr net.Error
+	if errors.As(err, &nerr) && nerr.Timeout() {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
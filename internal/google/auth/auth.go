@@ -0,0 +1,284 @@
+// Package auth runs the OAuth 2.0 loopback-redirect flow shared by the
+// Drive and YouTube clients and manages the cached tokens under
+// ~/.credentials.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// DefaultTimeout bounds how long Login waits for the browser redirect.
+const DefaultTimeout = 5 * time.Minute
+
+const revokeURL = "https://oauth2.googleapis.com/revoke"
+
+// Config describes one OAuth client and where its token is cached.
+type Config struct {
+	ClientSecretFile string
+	TokenFile        string
+	Scopes           []string
+	// Timeout defaults to DefaultTimeout.
+	Timeout time.Duration
+	// OpenURL is called with the consent page URL; it defaults to
+	// launching the system browser.
+	OpenURL func(string) error
+}
+
+// ErrStateMismatch is returned when the redirect carries a state value
+// other than the one sent with the consent request.
+var ErrStateMismatch = errors.New("oauth state mismatch")
+
+// HomeFile returns name joined to the current user's home directory.
+func HomeFile(name ...string) string {
+	dir := ""
+	if usr, err := user.Current(); err == nil {
+		dir = usr.HomeDir
+	}
+	return filepath.Join(append([]string{dir}, name...)...)
+}
+
+// Client returns an HTTP client authorized with the cached token, running
+// the browser login first when no token is cached yet.
+func Client(ctx context.Context, cfg Config) (*http.Client, error) {
+	config, err := cfg.oauthConfig()
+	if err != nil {
+		return nil, err
+	}
+	tok, err := tokenFromFile(cfg.TokenFile)
+	if err != nil {
+		tok, err = authorize(ctx, config, cfg)
+		if err != nil {
+			return nil, err
+		}
+		if err := saveToken(cfg.TokenFile, tok); err != nil {
+			return nil, err
+		}
+	}
+	return config.Client(ctx, tok), nil
+}
+
+// Login always runs the browser flow and replaces the cached token.
+func Login(ctx context.Context, cfg Config) error {
+	config, err := cfg.oauthConfig()
+	if err != nil {
+		return err
+	}
+	tok, err := authorize(ctx, config, cfg)
+	if err != nil {
+		return err
+	}
+	return saveToken(cfg.TokenFile, tok)
+}
+
+// Logout revokes the cached token with Google and removes it from disk.
+// A missing token file is not an error.
+func Logout(ctx context.Context, cfg Config) error {
+	tok, err := tokenFromFile(cfg.TokenFile)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	revoke := tok.RefreshToken
+	if revoke == "" {
+		revoke = tok.AccessToken
+	}
+	if revoke != "" {
+		if err := revokeToken(ctx, revoke); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Removing credential file: %s\n", cfg.TokenFile)
+	return os.Remove(cfg.TokenFile)
+}
+
+func (cfg Config) oauthConfig() (*oauth2.Config, error) {
+	b, err := os.ReadFile(cfg.ClientSecretFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read client secret file: %w", err)
+	}
+	config, err := google.ConfigFromJSON(b, cfg.Scopes...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse client secret file to config: %w", err)
+	}
+	return config, nil
+}
+
+// authorize sends the user to the consent page with a PKCE challenge and
+// a random state, and exchanges the code delivered to a one-shot listener
+// on a random 127.0.0.1 port.
+func authorize(ctx context.Context, config *oauth2.Config, cfg Config) (*oauth2.Token, error) {
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	defer listener.Close()
+
+	local := *config
+	local.RedirectURL = fmt.Sprintf("http://%s/", listener.Addr().String())
+
+	state, err := randomString()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := randomString()
+	if err != nil {
+		return nil, err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+
+	type result struct {
+		code string
+		err  error
+	}
+	resultCh := make(chan result, 1)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the browser's /favicon.ico and local probes must not end the login
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		q := r.URL.Query()
+		res := result{code: q.Get("code")}
+		switch {
+		case q.Get("state") != state:
+			res.err = ErrStateMismatch
+		case q.Get("error") != "":
+			res.err = fmt.Errorf("authorization denied: %s", q.Get("error"))
+		case res.code == "":
+			res.err = errors.New("authorization response without code")
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if res.err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "Authorization failed: %v\r\n", res.err)
+		} else {
+			fmt.Fprint(w, "Authorization received.\r\nYou can now safely close this browser window.")
+		}
+		select {
+		case resultCh <- res:
+		default:
+		}
+	})}
+	go srv.Serve(listener)
+	defer srv.Close()
+
+	authURL := local.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce,
+		oauth2.SetAuthURLParam("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:])),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"))
+	fmt.Printf("Go to the following link in your browser to authorize access:\n%v\n", authURL)
+	openURL := cfg.OpenURL
+	if openURL == nil {
+		openURL = openBrowser
+	}
+	if err := openURL(authURL); err != nil {
+		fmt.Println("unable to open browser:", err)
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for authorization: %w", ctx.Err())
+	case res := <-resultCh:
+		if res.err != nil {
+			return nil, res.err
+		}
+		tok, err := local.Exchange(ctx, res.code, oauth2.SetAuthURLParam("code_verifier", verifier))
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve token from web: %w", err)
+		}
+		return tok, nil
+	}
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func openBrowser(u string) error {
+	switch runtime.GOOS {
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", u).Start()
+	case "darwin":
+		return exec.Command("open", u).Start()
+	default:
+		return exec.Command("xdg-open", u).Start()
+	}
+}
+
+func revokeToken(ctx context.Context, token string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, revokeURL,
+		strings.NewReader(url.Values{"token": {token}}.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// an already revoked or expired token is reported as invalid_token,
+	// which leaves nothing to revoke
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest {
+		return fmt.Errorf("revoke token: %s", resp.Status)
+	}
+	return nil
+}
+
+// tokenFromFile retrieves a Token from a given file path.
+// It returns the retrieved Token and any read error encountered.
+func tokenFromFile(file string) (*oauth2.Token, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	t := &oauth2.Token{}
+	err = json.NewDecoder(f).Decode(t)
+	return t, err
+}
+
+// saveToken uses a file path to create a file and store the
+// token in it.
+func saveToken(file string, token *oauth2.Token) error {
+	fmt.Printf("Saving credential file to: %s\n", file)
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("unable to cache oauth token: %w", err)
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(token)
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// fakeTokenServer checks the PKCE verifier against the challenge sent on
+// the consent URL before handing out a token.
+func fakeTokenServer(t *testing.T, challenge *string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(sum[:]) != *challenge {
+			t.Errorf("code_verifier does not match challenge")
+		}
+		if r.Form.Get("code") != "the-code" {
+			t.Errorf("unexpected code %q", r.Form.Get("code"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token":"at","refresh_token":"rt","token_type":"Bearer","expires_in":3600}`)
+	}))
+}
+
+func redirectWith(t *testing.T, challenge *string, state func(string) string) func(string) error {
+	return func(authURL string) error {
+		u, err := url.Parse(authURL)
+		if err != nil {
+			return err
+		}
+		q := u.Query()
+		*challenge = q.Get("code_challenge")
+		if q.Get("code_challenge_method") != "S256" {
+			t.Errorf("missing S256 challenge method")
+		}
+		redirect := q.Get("redirect_uri") + "?" + url.Values{
+			"code":  {"the-code"},
+			"state": {state(q.Get("state"))},
+		}.Encode()
+		go http.Get(redirect)
+		return nil
+	}
+}
+
+func Test_authorize(t *testing.T) {
+	var challenge string
+	srv := fakeTokenServer(t, &challenge)
+	defer srv.Close()
+	config := &oauth2.Config{ClientID: "id", Endpoint: oauth2.Endpoint{AuthURL: srv.URL + "/auth", TokenURL: srv.URL + "/token"}}
+
+	cfg := Config{Timeout: 5 * time.Second, OpenURL: redirectWith(t, &challenge, func(s string) string { return s })}
+	tok, err := authorize(context.Background(), config, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.RefreshToken != "rt" {
+		t.Errorf("refresh token = %q", tok.RefreshToken)
+	}
+}
+
+func Test_authorizeStateMismatch(t *testing.T) {
+	var challenge string
+	srv := fakeTokenServer(t, &challenge)
+	defer srv.Close()
+	config := &oauth2.Config{ClientID: "id", Endpoint: oauth2.Endpoint{AuthURL: srv.URL + "/auth", TokenURL: srv.URL + "/token"}}
+
+	cfg := Config{Timeout: 5 * time.Second, OpenURL: redirectWith(t, &challenge, func(string) string { return "forged" })}
+	_, err := authorize(context.Background(), config, cfg)
+	if !errors.Is(err, ErrStateMismatch) {
+		t.Fatalf("expected state mismatch, got %v", err)
+	}
+}
+
+func Test_authorizeOtherPath(t *testing.T) {
+	var challenge string
+	srv := fakeTokenServer(t, &challenge)
+	defer srv.Close()
+	config := &oauth2.Config{ClientID: "id", Endpoint: oauth2.Endpoint{AuthURL: srv.URL + "/auth", TokenURL: srv.URL + "/token"}}
+
+	redirect := redirectWith(t, &challenge, func(s string) string { return s })
+	cfg := Config{Timeout: 5 * time.Second, OpenURL: func(authURL string) error {
+		u, err := url.Parse(authURL)
+		if err != nil {
+			return err
+		}
+		resp, err := http.Get(u.Query().Get("redirect_uri") + "favicon.ico")
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("favicon status = %d", resp.StatusCode)
+		}
+		return redirect(authURL)
+	}}
+	if _, err := authorize(context.Background(), config, cfg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_authorizeTimeout(t *testing.T) {
+	config := &oauth2.Config{ClientID: "id"}
+	cfg := Config{Timeout: 50 * time.Millisecond, OpenURL: func(string) error { return nil }}
+	_, err := authorize(context.Background(), config, cfg)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+}
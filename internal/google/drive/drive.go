@@ -1,33 +1,107 @@
 package drapi
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"net/http"
-	"net/url"
 	"os"
-	"os/user"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
+	"twsati/internal/audit"
+	"twsati/internal/google/apierr"
+	"twsati/internal/google/auth"
+	"twsati/internal/google/retry"
 	"twsati/internal/naming"
-	"twsati/internal/sys"
+	"twsati/internal/plan"
+	"twsati/internal/subtitle"
 
-	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
 	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
 )
 
 const (
 	VIDEO_ID   = "videoId"
 	CAPTION_ID = "captionId"
 	PRIVACY    = "privacy"
+	SERIES     = "series"
+	// SCHEDULED is when YouTube publishes a scheduled private video, in
+	// RFC 3339.
+	SCHEDULED = "scheduled"
+	// RELEASE_PREFIX marks the properties recording completed release
+	// steps, e.g. release_upload.
+	RELEASE_PREFIX = "release_"
+	// PUSHED_PREFIX marks the properties holding the md5 checksum of the
+	// file last pushed to YouTube, e.g. md5_caption.
+	PUSHED_PREFIX = "md5_"
+	// CAPTION_PREFIX marks the properties holding the caption id of each
+	// extra language track, e.g. caption_en; CAPTION_ID is the main track.
+	CAPTION_PREFIX = "caption_"
+	// DELETED lists the ids of deleted videos, oldest first, separated by
+	// commas.
+	DELETED = "deletedVideoIds"
 )
 
+const folderMimeType = "application/vnd.google-apps.folder"
+
+// The kinds of files pushed to YouTube.
+const (
+	VIDEO_FILE       = "video"
+	CAPTION_FILE     = "caption"
+	DESCRIPTION_FILE = "description"
+	THUMBNAIL_FILE   = "thumbnail"
+)
+
+// pushedExts are the extensions of each kind of file, as the Path methods
+// pick them.
+var pushedExts = map[string][]string{
+	VIDEO_FILE:       {".mp4"},
+	CAPTION_FILE:     subtitle.Exts(),
+	DESCRIPTION_FILE: {".txt"},
+	THUMBNAIL_FILE:   {".png", ".jpg"},
+}
+
+// IsFolder reports whether f is a Drive folder.
+func IsFolder(f *drive.File) bool {
+	return f.MimeType == folderMimeType
+}
+
+// ArchiveFolder is the subfolder of a clip folder that keeps the
+// snapshots of deleted videos.
+const ArchiveFolder = "archive"
+
+// maxProperty is how many bytes Drive allows for the key and value of one
+// app property together.
+const maxProperty = 124
+
+var (
+	ErrFolderNotFound  = errors.New("folder not found")
+	ErrFolderAmbiguous = errors.New("folder name not unique")
+	ErrFileNotFound    = errors.New("no file with extension")
+)
+
+// FolderError reports which clip folder an operation failed on.
+type FolderError struct {
+	Name string
+	Err  error
+}
+
+func (e *FolderError) Error() string {
+	return e.Name + ": " + e.Err.Error()
+}
+
+func (e *FolderError) Unwrap() error {
+	return e.Err
+}
+
 type VideoMeta struct {
 	Title     string
 	Date      time.Time
@@ -38,9 +112,23 @@ type VideoMeta struct {
 	VideoId   *string
 	Privacy   *string
 	CaptionId *string
+	Series    *string
+	Scheduled *string
+	// Deleted are the ids of videos deleted from the folder, oldest first.
+	Deleted []string
+	// Release maps each completed release step to when it completed; an
+	// empty value means the step has to run (again).
+	Release map[string]string
+	// Pushed maps each kind of file to the md5 checksum of the one last
+	// pushed to YouTube.
+	Pushed map[string]string
+	// Captions maps the language of each extra caption track to its
+	// caption id; CaptionId is the track in the configured language.
+	Captions map[string]string
 
 	FolderId            string
 	folderName          string
+	client              *Client       `json:"-"`
 	Children            []*drive.File `json:"-"`
 	descriptionFilePath string        `json:"-"`
 	videoFilePath       string        `json:"-"`
@@ -55,53 +143,219 @@ type VideoMeta struct {
 	// Suffix     string
 }
 
-func (vmeta *VideoMeta) CleanUp() {
-	os.RemoveAll(vmeta.tempDir)
+func (vmeta *VideoMeta) CleanUp() error {
+	if vmeta.client != nil && vmeta.client.plan.Active() {
+		return nil
+	}
+	return os.RemoveAll(vmeta.tempDir)
 }
 
-func (vmeta *VideoMeta) ThumbnailPath() string {
+func (vmeta *VideoMeta) ThumbnailPath() (string, error) {
 
 	if vmeta.thumbnailFilePath == "" {
-		vmeta.thumbnailFilePath = vmeta.downloadFile(".png", ".jpg")
+		path, err := vmeta.downloadFile(".png", ".jpg")
+		if err != nil {
+			return "", err
+		}
+		vmeta.thumbnailFilePath = path
 	}
-	return vmeta.thumbnailFilePath
+	return vmeta.thumbnailFilePath, nil
 }
 
-func (vmeta *VideoMeta) CaptionPath() string {
+func (vmeta *VideoMeta) CaptionPath() (string, error) {
 
 	if vmeta.captionFilePath == "" {
-		vmeta.captionFilePath = vmeta.downloadFile(".srt")
+		path, err := vmeta.TrackPath("")
+		if err != nil {
+			return "", err
+		}
+		vmeta.captionFilePath = path
+	}
+	return vmeta.captionFilePath, nil
+}
+
+// trackLanguage matches the language tags of caption file names.
+var trackLanguage = regexp.MustCompile(`^[a-z]{2,3}(-[a-z]{2,4})?$`)
+
+// TrackLanguage is the language of a caption file named
+// <name>.<lang>.<ext>, such as en for clip.en.srt; it is empty for the
+// main caption file and for other files.
+func TrackLanguage(name string) string {
+	if !hasSuffix(name, subtitle.Exts()) {
+		return ""
+	}
+	ext := filepath.Ext(name)
+	lang := strings.ToLower(strings.TrimPrefix(filepath.Ext(strings.TrimSuffix(name, ext)), "."))
+	if !trackLanguage.MatchString(lang) {
+		return ""
+	}
+	return lang
+}
+
+// pulledName matches the names PulledName makes.
+var pulledName = regexp.MustCompile(`\.yt-\d{8}T\d{6}Z\.srt$`)
+
+// PulledName names the SubRip copy of a YouTube caption track in lang
+// downloaded at t, e.g. clip.en.yt-20261018T150405Z.srt.
+func PulledName(base, lang string, t time.Time) string {
+	return fmt.Sprintf("%s.%s.yt-%s.srt", base, lang, t.UTC().Format("20060102T150405Z"))
+}
+
+// IsPulled reports whether name was made by PulledName. Pulled copies are
+// never picked as a caption to upload; rename one to use it.
+func IsPulled(name string) bool {
+	return pulledName.MatchString(name)
+}
+
+// track is the newest caption file in lang, "" for the main one.
+func (vmeta *VideoMeta) track(lang string) *drive.File {
+	var newest *drive.File
+	for _, f := range vmeta.Children {
+		if !hasSuffix(f.Name, subtitle.Exts()) || IsPulled(f.Name) || TrackLanguage(f.Name) != lang {
+			continue
+		}
+		if newest == nil || f.ModifiedTime > newest.ModifiedTime {
+			newest = f
+		}
+	}
+	return newest
+}
+
+func hasSuffix(name string, suffixes []string) bool {
+	for _, s := range suffixes {
+		if strings.HasSuffix(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Tracks lists the languages of the extra caption files in the folder.
+func (vmeta *VideoMeta) Tracks() []string {
+	seen := make(map[string]bool)
+	var langs []string
+	for _, f := range vmeta.Children {
+		if lang := TrackLanguage(f.Name); lang != "" && !seen[lang] {
+			seen[lang] = true
+			langs = append(langs, lang)
+		}
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// TrackPath downloads the caption file in lang, the main one for "".
+func (vmeta *VideoMeta) TrackPath(lang string) (string, error) {
+	f := vmeta.track(lang)
+	if f == nil {
+		return "", vmeta.noTrack(lang)
+	}
+	if err := vmeta.makeTempDir(); err != nil {
+		return "", err
 	}
-	return vmeta.captionFilePath
+	return vmeta.client.downloadFileTo(vmeta.tempDir, f)
 }
 
-func (vmeta *VideoMeta) DescriptionPath() string {
+func (vmeta *VideoMeta) noTrack(lang string) error {
+	exts := strings.Join(subtitle.Exts(), ",")
+	if lang != "" {
+		exts = "." + lang + "{" + exts + "}"
+	}
+	return &FolderError{Name: vmeta.folderName, Err: fmt.Errorf("%w: %s", ErrFileNotFound, exts)}
+}
+
+// WriteTemp writes a file the folder's uploads are made from, such as a
+// converted caption, next to the downloaded files; a dry run only
+// returns its path.
+func (vmeta *VideoMeta) WriteTemp(name string, content []byte) (string, error) {
+	if err := vmeta.makeTempDir(); err != nil {
+		return "", err
+	}
+	path := filepath.Join(vmeta.tempDir, name)
+	if vmeta.client.plan.Active() {
+		return path, nil
+	}
+	return path, os.WriteFile(path, content, 0600)
+}
+
+func (vmeta *VideoMeta) DescriptionPath() (string, error) {
 
 	if vmeta.descriptionFilePath == "" {
-		vmeta.descriptionFilePath = vmeta.downloadFile(".txt")
+		path, err := vmeta.downloadFile(".txt")
+		if err != nil {
+			return "", err
+		}
+		vmeta.descriptionFilePath = path
 	}
-	return vmeta.descriptionFilePath
+	return vmeta.descriptionFilePath, nil
 }
-func (vmeta *VideoMeta) VideoFilePath() string {
+func (vmeta *VideoMeta) VideoFilePath() (string, error) {
 
 	if vmeta.videoFilePath == "" {
-		vmeta.videoFilePath = vmeta.downloadFile(".mp4")
+		path, err := vmeta.downloadFile(".mp4")
+		if err != nil {
+			return "", err
+		}
+		vmeta.videoFilePath = path
 	}
-	return vmeta.videoFilePath
+	return vmeta.videoFilePath, nil
 }
 
-func (vmeta *VideoMeta) DescriptionContent() string {
+func (vmeta *VideoMeta) DescriptionContent() (string, error) {
 	if !vmeta.HasDescription() {
-		return ""
+		return "", nil
+	}
+	if vmeta.client.plan.Active() {
+		// a dry run downloads nothing, read the text straight into memory
+		f, err := vmeta.candidate(".txt")
+		if err != nil {
+			return "", err
+		}
+		payload, err := vmeta.client.fetch(f)
+		return string(payload), err
+	}
+	path, err := vmeta.DescriptionPath()
+	if err != nil {
+		return "", err
 	}
-	path := vmeta.DescriptionPath()
-	f, err := os.Open(path)
-	handleError(err, "open description file: "+path)
-	defer f.Close()
-	payload, err := ioutil.ReadAll(f)
-	handleError(err, "load description contenbt: "+path)
-	return string(payload)
+	payload, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("load description content: %w", err)
+	}
+	return string(payload), nil
 }
+
+// CaptionContent reads the newest caption file, without downloading it
+// in a dry run.
+func (vmeta *VideoMeta) CaptionContent() ([]byte, error) {
+	if vmeta.client.plan.Active() {
+		f := vmeta.track("")
+		if f == nil {
+			return nil, vmeta.noTrack("")
+		}
+		return vmeta.client.fetch(f)
+	}
+	path, err := vmeta.CaptionPath()
+	if err != nil {
+		return nil, err
+	}
+	payload, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("load caption content: %w", err)
+	}
+	return payload, nil
+}
+
+// CaptionFile names the caption file CaptionPath and CaptionContent
+// read, the newest in any of the subtitle formats; empty when the folder
+// has none.
+func (vmeta *VideoMeta) CaptionFile() string {
+	if f := vmeta.track(""); f != nil {
+		return f.Name
+	}
+	return ""
+}
+
 func prettyPrint(i interface{}) string {
 	s, _ := json.MarshalIndent(i, "", "\t")
 	return string(s)
@@ -112,7 +366,11 @@ func (vmeta *VideoMeta) HasDescription() bool {
 }
 
 func (vmeta *VideoMeta) HasCaption() bool {
-	return vmeta.HasExt(".srt")
+	return vmeta.CaptionFile() != ""
+}
+
+func (vmeta *VideoMeta) HasThumbnail() bool {
+	return vmeta.HasExt(".png") || vmeta.HasExt(".jpg")
 }
 
 func (vmeta *VideoMeta) HasVideo() bool {
@@ -129,45 +387,186 @@ func (vmeta *VideoMeta) HasExt(ext string) bool {
 
 }
 
+// ReleaseDone reports whether release step has completed.
+func (vmeta *VideoMeta) ReleaseDone(step string) bool {
+	return vmeta.Release[step] != ""
+}
+
+// MarkRelease records step as completed at t.
+func (vmeta *VideoMeta) MarkRelease(step string, t time.Time) {
+	if vmeta.Release == nil {
+		vmeta.Release = make(map[string]string)
+	}
+	vmeta.Release[step] = t.UTC().Format(time.RFC3339)
+}
+
+// Checksum is the md5 checksum of the file of kind that would be pushed
+// now, or "" when the folder has none or Drive keeps no checksum for it.
+func (vmeta *VideoMeta) Checksum(kind string) string {
+	f := vmeta.newest(pushedExts[kind]...)
+	if kind == CAPTION_FILE {
+		f = vmeta.track("")
+	} else if strings.HasPrefix(kind, CAPTION_FILE+"_") {
+		f = vmeta.track(strings.TrimPrefix(kind, CAPTION_FILE+"_"))
+	}
+	if f == nil {
+		return ""
+	}
+	return f.Md5Checksum
+}
+
+// MarkPushed records the current file of kind as pushed to YouTube.
+func (vmeta *VideoMeta) MarkPushed(kind string) {
+	sum := vmeta.Checksum(kind)
+	if sum == "" && vmeta.Pushed[kind] == "" {
+		return
+	}
+	if vmeta.Pushed == nil {
+		vmeta.Pushed = make(map[string]string)
+	}
+	vmeta.Pushed[kind] = sum
+}
+
+// Unchanged reports whether the file of kind is the one last pushed.
+func (vmeta *VideoMeta) Unchanged(kind string) bool {
+	sum := vmeta.Checksum(kind)
+	return sum != "" && vmeta.Pushed[kind] == sum
+}
+
+// Changed lists the kinds of files that differ from the ones last pushed,
+// in the order of the Pushed kinds.
+func (vmeta *VideoMeta) Changed() []string {
+	var kinds []string
+	for _, kind := range []string{VIDEO_FILE, CAPTION_FILE, DESCRIPTION_FILE, THUMBNAIL_FILE} {
+		if vmeta.Pushed[kind] != "" && !vmeta.Unchanged(kind) {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds
+}
+
+// TrackKind is the kind of file Pushed records the caption in lang
+// under, e.g. caption_en.
+func TrackKind(lang string) string {
+	return CAPTION_FILE + "_" + lang
+}
+
+// ResetCaptions forgets the extra caption tracks, e.g. after they were
+// deleted with the video.
+func (vmeta *VideoMeta) ResetCaptions() {
+	for lang := range vmeta.Captions {
+		vmeta.Captions[lang] = ""
+	}
+}
+
+// ResetPushed forgets the pushed files, e.g. after the video was deleted.
+func (vmeta *VideoMeta) ResetPushed() {
+	for kind := range vmeta.Pushed {
+		vmeta.Pushed[kind] = ""
+	}
+}
+
+// Tombstone remembers videoId as deleted. The oldest ids are dropped when
+// the list no longer fits in one app property; the archive folder keeps
+// every snapshot.
+func (vmeta *VideoMeta) Tombstone(videoId string) {
+	vmeta.Deleted = append(vmeta.Deleted, videoId)
+	for len(vmeta.Deleted) > 1 && len(DELETED)+len(strings.Join(vmeta.Deleted, ",")) > maxProperty {
+		vmeta.Deleted = vmeta.Deleted[1:]
+	}
+}
+
+// ResetRelease marks every recorded release step as not done, e.g. after
+// the video was deleted.
+func (vmeta *VideoMeta) ResetRelease() {
+	for step := range vmeta.Release {
+		vmeta.Release[step] = ""
+	}
+}
+
 func (vmeta *VideoMeta) SetTempDir(dir string) {
 	vmeta.tempDir = dir
 }
 
-func (vmeta *VideoMeta) downloadFile(exts ...string) string {
+func (vmeta *VideoMeta) downloadFile(exts ...string) (string, error) {
+	candidateFile, err := vmeta.candidate(exts...)
+	if err != nil {
+		return "", err
+	}
 
-	if vmeta.tempDir == "" {
-		// creating temp dir
-		dir, err := ioutil.TempDir(os.TempDir(), vmeta.Title)
-		handleError(err, "creating tmp dir:  "+dir)
-		vmeta.tempDir = dir
+	if err := vmeta.makeTempDir(); err != nil {
+		return "", err
 	}
+	// bingo, load description
+	return vmeta.client.downloadFileTo(vmeta.tempDir, candidateFile)
+}
 
+func (vmeta *VideoMeta) makeTempDir() error {
+	if vmeta.tempDir != "" {
+		return nil
+	}
+	if vmeta.client.plan.Active() {
+		vmeta.tempDir = filepath.Join(os.TempDir(), vmeta.Title)
+		return nil
+	}
+	// creating temp dir
+	dir, err := ioutil.TempDir(os.TempDir(), vmeta.Title)
+	if err != nil {
+		return fmt.Errorf("creating tmp dir: %w", err)
+	}
+	vmeta.tempDir = dir
+	return nil
+}
+
+// candidate picks the most recently modified child with one of exts.
+func (vmeta *VideoMeta) candidate(exts ...string) (*drive.File, error) {
+	candidateFile := vmeta.newest(exts...)
+	if candidateFile == nil {
+		return nil, &FolderError{Name: vmeta.folderName, Err: fmt.Errorf("%w: %s", ErrFileNotFound, strings.Join(exts, ","))}
+	}
+	fmt.Println("found better candidate :", candidateFile.Name, candidateFile.ModifiedTime)
+	return candidateFile, nil
+}
+
+// newest is the most recently modified child with one of exts, or nil.
+func (vmeta *VideoMeta) newest(exts ...string) *drive.File {
 	lastModTime := ""
-	var candidateFile *drive.File
+	var newest *drive.File
 	for _, f := range vmeta.Children {
 		for _, ext := range exts {
 			if /*strings.HasPrefix(f.Name, vmeta.folderName) &&*/ strings.HasSuffix(f.Name, ext) {
 				if f.ModifiedTime > lastModTime {
-					candidateFile = f
+					newest = f
 					lastModTime = f.ModifiedTime
-					fmt.Println("found better candidate :", f.Name, f.ModifiedTime)
-
 				}
 			}
 		}
 	}
-	if candidateFile != nil {
-		// bingo, load description
-		path := downloadFileTo(vmeta.tempDir, candidateFile)
-		return path
-	} else {
-		panic("failed to download for ext: " + strings.Join(exts, ","))
+	return newest
+}
+
+// MetaFromName builds the metadata encoded in a clip folder name without
+// looking the folder up on Drive.
+func MetaFromName(name string) (*VideoMeta, error) {
+	vmeta, err := fromString(name)
+	if err != nil {
+		return nil, &FolderError{Name: name, Err: err}
 	}
+	vmeta.folderName = name
+	return vmeta, nil
 }
 
-func fromString(str string) *VideoMeta {
+// FolderName is the Drive folder name the metadata was loaded from.
+func (vmeta *VideoMeta) FolderName() string {
+	return vmeta.folderName
+}
 
-	info := naming.ExtractName2(str)
+func fromString(str string) (*VideoMeta, error) {
+
+	info, err := naming.ExtractName2(str)
+	if err != nil {
+		return nil, err
+	}
 	meta := &VideoMeta{}
 	meta.Date = info.Date
 	meta.Title = info.Title
@@ -175,180 +574,219 @@ func fromString(str string) *VideoMeta {
 	meta.Ssec = info.Ssec
 	meta.Emin = info.Emin
 	meta.Esec = info.Esec
-	return meta
+	return meta, nil
 }
 
-var service *drive.Service
-
-func init() {
-
-	var err error
-	cli := getClient(drive.DriveScope)
-	service, err = drive.New(cli)
-	handleError(err, "drive cli initialization")
+// Options configures a Client. The zero value reads the OAuth client
+// secret and cached token from the user's home directory.
+type Options struct {
+	// HTTPClient is used as-is when set, skipping the OAuth setup.
+	HTTPClient *http.Client
+	// Endpoint overrides the Drive API base URL, e.g. for a local fake.
+	Endpoint string
+	// ClientSecretFile defaults to ~/client_secret_drive.json.
+	ClientSecretFile string
+	// TokenFile defaults to ~/.credentials/drive-go-quickstart.json.
+	TokenFile string
+	// Retry defaults to retry.Default.
+	Retry *retry.Policy
+	// Plan, when set, turns the client into a dry run: reads still reach
+	// Drive but downloads and updates are only recorded.
+	Plan *plan.Plan
+	// Cache, when set, is handed every folder the client loads or
+	// updates.
+	Cache Cache
+}
 
+// Cache keeps a copy of folder metadata, e.g. a local catalog.
+type Cache interface {
+	Store(vmeta *VideoMeta) error
 }
 
-func getClient(scope string) *http.Client {
-	ctx := context.Background()
-	usr, err := user.Current()
-	sys.CheckErr(err)
-	// driveService, err := drive.NewService(ctx)
+// Client wraps the Drive service used to read and write clip folders.
+type Client struct {
+	service *drive.Service
+	retry   retry.Policy
+	plan    *plan.Plan
+	cache   Cache
+}
 
-	b, err := ioutil.ReadFile(filepath.Join(usr.HomeDir, "client_secret_drive.json"))
-	if err != nil {
-		log.Fatalf("Unable to read client secret file: %v", err)
+// NewClient builds a Drive client from opts.
+func NewClient(opts Options) (*Client, error) {
+	cli := opts.HTTPClient
+	if cli == nil {
+		var err error
+		cli, err = auth.Client(context.Background(), opts.authConfig())
+		if err != nil {
+			return nil, err
+		}
 	}
-
-	// If modifying these scopes, delete your previously saved credentials
-	// at ~/.credentials/youtube-go-quickstart.json
-	config, err := google.ConfigFromJSON(b, scope)
-	if err != nil {
-		log.Fatalf("Unable to parse client secret file to config: %v", err)
+	svcOpts := []option.ClientOption{option.WithHTTPClient(cli)}
+	if opts.Endpoint != "" {
+		svcOpts = append(svcOpts, option.WithEndpoint(opts.Endpoint))
 	}
-	cacheFile, err := tokenCacheFile()
+	service, err := drive.NewService(context.Background(), svcOpts...)
 	if err != nil {
-		log.Fatalf("Unable to get path to cached credential file. %v", err)
+		return nil, err
 	}
-	tok, err := tokenFromFile(cacheFile)
-	if err != nil {
-		tok = getTokenFromWeb(config)
-		saveToken(cacheFile, tok)
+	policy := retry.Default
+	if opts.Retry != nil {
+		policy = *opts.Retry
 	}
-	return config.Client(ctx, tok)
-
-	// token, err := config.Exchange(ctx, ...)
-	// driveService, err := drive.NewService(ctx, option.WithTokenSource(config.TokenSource(ctx, token)))
+	return &Client{service: service, retry: policy, plan: opts.Plan, cache: opts.Cache}, nil
 }
 
-// tokenCacheFile generates credential file path/filename.
-// It returns the generated credential path/filename.
-func tokenCacheFile() (string, error) {
-	usr, err := user.Current()
-	if err != nil {
-		return "", err
-	}
-	tokenCacheDir := filepath.Join(usr.HomeDir, ".credentials")
-	os.MkdirAll(tokenCacheDir, 0700)
-	return filepath.Join(tokenCacheDir,
-		url.QueryEscape("drive-go-quickstart.json")), err
+// do runs fn under the client's retry policy and wraps its error for op.
+func (c *Client) do(op string, fn func() error) error {
+	return c.retry.Do(op, func(int) error {
+		return apierr.Wrap(op, fn())
+	})
 }
 
-// tokenFromFile retrieves a Token from a given file path.
-// It returns the retrieved Token and any read error encountered.
-func tokenFromFile(file string) (*oauth2.Token, error) {
-	f, err := os.Open(file)
-	if err != nil {
-		return nil, err
-	}
-	t := &oauth2.Token{}
-	err = json.NewDecoder(f).Decode(t)
-	defer f.Close()
-	return t, err
+// Login runs the browser authorization and caches a fresh Drive token.
+func Login(opts Options) error {
+	return auth.Login(context.Background(), opts.authConfig())
 }
 
-// saveToken uses a file path to create a file and store the
-// token in it.
-func saveToken(file string, token *oauth2.Token) {
-	fmt.Printf("Saving credential file to: %s\n", file)
-	f, err := os.OpenFile(file, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
-	if err != nil {
-		log.Fatalf("Unable to cache oauth token: %v", err)
-	}
-	defer f.Close()
-	json.NewEncoder(f).Encode(token)
+// Logout revokes and removes the cached Drive token.
+func Logout(opts Options) error {
+	return auth.Logout(context.Background(), opts.authConfig())
 }
 
-func handleError(err error, message string) {
-	if message == "" {
-		message = "Error making API call"
-	}
-	if err != nil {
-		log.Fatalf(message+": %v", err.Error())
+func (opts Options) authConfig() auth.Config {
+	cfg := auth.Config{
+		ClientSecretFile: opts.ClientSecretFile,
+		TokenFile:        opts.TokenFile,
+		Scopes:           []string{drive.DriveScope},
 	}
-}
-
-// getTokenFromWeb uses Config to request a Token.
-// It returns the retrieved Token.
-func getTokenFromWeb(config *oauth2.Config) *oauth2.Token {
-	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
-	fmt.Printf("Go to the following link in your browser then type the "+
-		"authorization code: \n%v\n", authURL)
-
-	var code string
-	if _, err := fmt.Scan(&code); err != nil {
-		log.Fatalf("Unable to read authorization code %v", err)
+	if cfg.ClientSecretFile == "" {
+		cfg.ClientSecretFile = auth.HomeFile("client_secret_drive.json")
 	}
-
-	tok, err := config.Exchange(oauth2.NoContext, code)
-	if err != nil {
-		log.Fatalf("Unable to retrieve token from web %v", err)
+	// If modifying these scopes, delete your previously saved credentials
+	// at ~/.credentials/drive-go-quickstart.json
+	if cfg.TokenFile == "" {
+		cfg.TokenFile = auth.HomeFile(".credentials", "drive-go-quickstart.json")
 	}
-	return tok
+	return cfg
 }
 
-func DriveFolders() {
+func (c *Client) DriveFolders() error {
 
-	call := service.Files.List().
+	call := c.service.Files.List().
 		// Q("mimeType='application/vnd.google-apps.folder'").
 		// Q("name='zh221001_[34.20-37.14]_分離五蘊，看見“我”不存在'").
 		Q(fmt.Sprintf("name='%s'", "zh221001_[34.20-37.14]_分離五蘊，看見“我”不存在")).
 		Fields("files/*").
 		Spaces("drive")
-	resp, err := call.Do()
-	handleError(err, "list call()")
+	var resp *drive.FileList
+	err := c.do("list folders", func() (err error) {
+		resp, err = call.Do()
+		return err
+	})
+	if err != nil {
+		return err
+	}
 	for _, f := range resp.Files {
 		fmt.Println(f.Name)
 
 	}
+	return nil
 }
-func driveFolderListByName(name string) (*drive.File, []*drive.File) {
+func (c *Client) driveFolderListByName(name string) (*drive.File, []*drive.File, error) {
 	fmt.Println("query for folder: ", name)
-	call := service.Files.List().
+	call := c.service.Files.List().
 		Q(fmt.Sprintf("name='%s'", name)).
 		// Fields("id", "name", "description", "appProperties").
 		Fields("files/*").
 		Spaces("drive")
 
-	resp, err := call.Do()
-	handleError(err, "list call()")
+	var resp *drive.FileList
+	err := c.do("list folder "+name, func() (err error) {
+		resp, err = call.Do()
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
 	if len(resp.Files) > 1 {
-		panic("folder name not unique " + name)
+		return nil, nil, &FolderError{Name: name, Err: ErrFolderAmbiguous}
 	} else if len(resp.Files) == 0 {
-		panic("folder name not found" + name)
-
+		return nil, nil, &FolderError{Name: name, Err: ErrFolderNotFound}
 	}
-	return resp.Files[0], driveFolderListById(resp.Files[0].Id)
+	children, err := c.driveFolderListById(resp.Files[0].Id)
+	return resp.Files[0], children, err
 }
 
-func driveFolderListById(folderId string) []*drive.File {
-	call := service.Files.List().
+func (c *Client) driveFolderListById(folderId string) ([]*drive.File, error) {
+	call := c.service.Files.List().
 		// Q("title='zh230114_[37.34-38.51]_生命中別投降別氣餒'").
 		Q(fmt.Sprintf("'%s' in parents", folderId)).
 		Fields("files/*")
 		// Fields("files/name", "files/trashed", "files/id")
 		// Spaces("drive")
 
-	resp, err := call.Do()
-	handleError(err, "list call()")
-	return resp.Files
+	var resp *drive.FileList
+	err := c.do("list folder children", func() (err error) {
+		resp, err = call.Do()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Files, nil
 }
 
-func HelloDrive() {
-	resp, err := service.About.Get().Fields("user").Do()
-	handleError(err, "drive about()")
+func (c *Client) HelloDrive() error {
+	var resp *drive.About
+	err := c.do("drive about", func() (err error) {
+		resp, err = c.service.About.Get().Fields("user").Do()
+		return err
+	})
+	if err != nil {
+		return err
+	}
 	fmt.Printf("This drive is owned by: %s, and email: %s\n", resp.User.DisplayName, resp.User.EmailAddress)
+	return nil
 }
 
-func downloadFileTo(dir string, f *drive.File) string {
-	resp, err := service.Files.Get(f.Id).Download()
-	handleError(err, "drive download")
-	defer resp.Body.Close()
+func (c *Client) downloadFileTo(dir string, f *drive.File) (string, error) {
 	newF := filepath.Join(dir, f.Name)
-	descF, err := os.Create(newF)
-	handleError(err, "create description file")
-	io.Copy(descF, resp.Body)
-	return newF
+	if c.plan.Record("drive", "download", f.Name, fmt.Sprintf("%d bytes to %s", f.Size, dir)) {
+		return newF, nil
+	}
+	err := c.do("drive download "+f.Name, func() error {
+		resp, err := c.service.Files.Get(f.Id).Download()
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		descF, err := os.Create(newF)
+		if err != nil {
+			return err
+		}
+		defer descF.Close()
+		_, err = io.Copy(descF, resp.Body)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return newF, nil
+}
+
+// fetch reads the content of f into memory.
+func (c *Client) fetch(f *drive.File) ([]byte, error) {
+	var payload []byte
+	err := c.do("drive read "+f.Name, func() error {
+		resp, err := c.service.Files.Get(f.Id).Download()
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		payload, err = io.ReadAll(resp.Body)
+		return err
+	})
+	return payload, err
 }
 
 func setSptr(ptr **string, rvalue string) {
@@ -357,8 +795,154 @@ func setSptr(ptr **string, rvalue string) {
 	}
 	**ptr = rvalue
 }
-func GetVideoMeta(name string) *VideoMeta {
+func (c *Client) GetVideoMeta(name string) (*VideoMeta, error) {
+
+	vmeta, err := MetaFromName(name)
+	if err != nil {
+		return nil, err
+	}
+	vmeta.client = c
+	folder, children, err := c.driveFolderListByName(name)
+	if err != nil {
+		return nil, err
+	}
+	// fmt.Printf("%+v\n", folder)
+	vmeta.FolderId = folder.Id
+	// fmt.Println(folder.Description, folder.AppProperties)
+	vmeta.loadProperties(folder.AppProperties)
+	for _, f := range children {
+		if !f.Trashed {
+			vmeta.Children = append(vmeta.Children, f)
+		}
+	}
+	c.store(vmeta)
+
+	return vmeta, nil
+}
+
+// AppendAudit appends e to the folder's audit log, creating the log on
+// first use. A dry run changes nothing, so it logs nothing either.
+func (c *Client) AppendAudit(vmeta *VideoMeta, e *audit.Entry) error {
+	if c.plan.Active() {
+		return nil
+	}
+	line, err := e.Line()
+	if err != nil {
+		return err
+	}
+	logFile := vmeta.child(audit.FileName)
+	if logFile == nil {
+		created := &drive.File{Name: audit.FileName, Parents: []string{vmeta.FolderId}, MimeType: "application/x-ndjson"}
+		err := c.do("create audit log", func() (err error) {
+			created, err = c.service.Files.Create(created).Media(bytes.NewReader(line)).Do()
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		vmeta.Children = append(vmeta.Children, created)
+		return nil
+	}
+	content, err := c.fetch(logFile)
+	if err != nil {
+		return err
+	}
+	content = append(content, line...)
+	return c.do("append audit log", func() error {
+		_, err := c.service.Files.Update(logFile.Id, &drive.File{}).Media(bytes.NewReader(content)).Do()
+		return err
+	})
+}
+
+// ReadAudit returns the entries of the folder's audit log, oldest first.
+func (c *Client) ReadAudit(vmeta *VideoMeta) ([]audit.Entry, error) {
+	logFile := vmeta.child(audit.FileName)
+	if logFile == nil {
+		return nil, nil
+	}
+	content, err := c.fetch(logFile)
+	if err != nil {
+		return nil, err
+	}
+	return audit.Parse(bytes.NewReader(content))
+}
+
+// ArchiveFile is one file of a snapshot.
+type ArchiveFile struct {
+	Name    string
+	Content []byte
+}
+
+// Archive writes files into the archive subfolder of the clip folder,
+// creating the subfolder on first use.
+func (c *Client) Archive(vmeta *VideoMeta, files []ArchiveFile) error {
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name)
+	}
+	if c.plan.Record("drive", "archive", vmeta.folderName+"/"+ArchiveFolder, strings.Join(names, ", ")) {
+		return nil
+	}
+	archive := vmeta.child(ArchiveFolder)
+	if archive == nil || archive.MimeType != folderMimeType {
+		archive = &drive.File{Name: ArchiveFolder, Parents: []string{vmeta.FolderId}, MimeType: folderMimeType}
+		err := c.do("create archive folder", func() (err error) {
+			archive, err = c.service.Files.Create(archive).Do()
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		vmeta.Children = append(vmeta.Children, archive)
+	}
+	for _, f := range files {
+		f := f
+		err := c.do("archive "+f.Name, func() error {
+			_, err := c.service.Files.Create(&drive.File{Name: f.Name, Parents: []string{archive.Id}}).Media(bytes.NewReader(f.Content)).Do()
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
+// SaveFile writes a new file named name into the clip folder.
+func (c *Client) SaveFile(vmeta *VideoMeta, name string, content []byte) error {
+	if c.plan.Record("drive", "save", vmeta.folderName+"/"+name, fmt.Sprintf("%d bytes", len(content))) {
+		return nil
+	}
+	return c.do("save "+name, func() error {
+		f, err := c.service.Files.Create(&drive.File{Name: name, Parents: []string{vmeta.FolderId}}).Media(bytes.NewReader(content)).Do()
+		if err == nil {
+			vmeta.Children = append(vmeta.Children, f)
+		}
+		return err
+	})
+}
+
+// child finds the folder file named name.
+func (vmeta *VideoMeta) child(name string) *drive.File {
+	for _, f := range vmeta.Children {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// store hands vmeta to the cache; a failing cache only costs freshness.
+func (c *Client) store(vmeta *VideoMeta) {
+	if c.cache == nil {
+		return
+	}
+	if err := c.cache.Store(vmeta); err != nil {
+		fmt.Fprintf(os.Stderr, "cache %s: %v\n", vmeta.folderName, err)
+	}
+}
+
+func (vmeta *VideoMeta) loadProperties(props map[string]string) {
 	var hasKey = func(dict map[string]string, key string) bool {
 		if dict != nil {
 			if _, ok := dict[key]; ok {
@@ -367,75 +951,316 @@ func GetVideoMeta(name string) *VideoMeta {
 		}
 		return false
 	}
-	vmeta := fromString(name)
-	vmeta.folderName = name
-	folder, children := driveFolderListByName(name)
-	// fmt.Printf("%+v\n", folder)
-	vmeta.FolderId = folder.Id
-	// fmt.Println(folder.Description, folder.AppProperties)
-	if hasKey(folder.AppProperties, VIDEO_ID) {
-		setSptr(&vmeta.VideoId, folder.AppProperties[VIDEO_ID])
+	if hasKey(props, VIDEO_ID) {
+		setSptr(&vmeta.VideoId, props[VIDEO_ID])
 	}
-	if hasKey(folder.AppProperties, CAPTION_ID) {
-		setSptr(&vmeta.CaptionId, folder.AppProperties[CAPTION_ID])
+	if hasKey(props, CAPTION_ID) {
+		setSptr(&vmeta.CaptionId, props[CAPTION_ID])
 	}
-	if hasKey(folder.AppProperties, PRIVACY) {
-		setSptr(&vmeta.Privacy, folder.AppProperties[PRIVACY])
+	if hasKey(props, PRIVACY) {
+		setSptr(&vmeta.Privacy, props[PRIVACY])
 	}
-	for _, f := range children {
-		if !f.Trashed {
-			vmeta.Children = append(vmeta.Children, f)
+	if hasKey(props, SERIES) {
+		setSptr(&vmeta.Series, props[SERIES])
+	}
+	if hasKey(props, SCHEDULED) {
+		setSptr(&vmeta.Scheduled, props[SCHEDULED])
+	}
+	if props[DELETED] != "" {
+		vmeta.Deleted = strings.Split(props[DELETED], ",")
+	}
+	for key, value := range props {
+		if strings.HasPrefix(key, RELEASE_PREFIX) {
+			if vmeta.Release == nil {
+				vmeta.Release = make(map[string]string)
+			}
+			vmeta.Release[strings.TrimPrefix(key, RELEASE_PREFIX)] = value
+		}
+		if strings.HasPrefix(key, PUSHED_PREFIX) {
+			if vmeta.Pushed == nil {
+				vmeta.Pushed = make(map[string]string)
+			}
+			vmeta.Pushed[strings.TrimPrefix(key, PUSHED_PREFIX)] = value
+		}
+		if strings.HasPrefix(key, CAPTION_PREFIX) {
+			if vmeta.Captions == nil {
+				vmeta.Captions = make(map[string]string)
+			}
+			vmeta.Captions[strings.TrimPrefix(key, CAPTION_PREFIX)] = value
 		}
 	}
+}
+
+// listFiles runs a query across every result page.
+func (c *Client) listFiles(op, q, fields string) ([]*drive.File, error) {
+	var files []*drive.File
+	err := c.do(op, func() error {
+		files = nil
+		return c.service.Files.List().Q(q).Fields("nextPageToken", googleapi.Field("files("+fields+")")).Spaces("drive").
+			Pages(context.Background(), func(resp *drive.FileList) error {
+				files = append(files, resp.Files...)
+				return nil
+			})
+	})
+	return files, err
+}
 
+// folderMeta builds the metadata of a clip folder from its listing, or
+// returns nil when the folder name is not a clip name.
+func (c *Client) folderMeta(folder *drive.File) *VideoMeta {
+	vmeta, err := MetaFromName(folder.Name)
+	if err != nil {
+		return nil
+	}
+	vmeta.client = c
+	vmeta.FolderId = folder.Id
+	vmeta.loadProperties(folder.AppProperties)
 	return vmeta
 }
 
-func UpdateVideoMeta(vmeta *VideoMeta) {
+// FindVideoMeta lists the clip folders whose app property key is value.
+// Only the folder properties are loaded; the folder contents are not.
+func (c *Client) FindVideoMeta(key, value string) ([]*VideoMeta, error) {
+	q := fmt.Sprintf("mimeType='%s' and trashed=false and appProperties has { key='%s' and value='%s' }", folderMimeType, key, value)
+	folders, err := c.listFiles("find folders "+key+"="+value, q, "id,name,appProperties")
+	if err != nil {
+		return nil, err
+	}
+	var ret []*VideoMeta
+	for _, folder := range folders {
+		if vmeta := c.folderMeta(folder); vmeta != nil {
+			ret = append(ret, vmeta)
+		}
+	}
+	return ret, nil
+}
 
-	// update meta
-	nf := &drive.File{Description: prettyPrint(vmeta)}
-	nf.AppProperties = make(map[string]string)
+// Folder fetches the name, type and app properties of the file id, which
+// callers expect to be a folder.
+func (c *Client) Folder(id string) (*drive.File, error) {
+	var folder *drive.File
+	err := c.do("get folder "+id, func() (err error) {
+		folder, err = c.service.Files.Get(id).Fields("id,name,mimeType,appProperties,trashed").Do()
+		return err
+	})
+	return folder, err
+}
+
+// StartPageToken is where the changes feed continues from now on.
+func (c *Client) StartPageToken() (string, error) {
+	var token *drive.StartPageToken
+	err := c.do("get changes start token", func() (err error) {
+		token, err = c.service.Changes.GetStartPageToken().Do()
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return token.StartPageToken, nil
+}
+
+// Changes lists the file changes after token across every result page
+// and returns the token to continue from next time.
+func (c *Client) Changes(token string) ([]*drive.Change, string, error) {
+	var changes []*drive.Change
+	for page := token; page != ""; {
+		var resp *drive.ChangeList
+		err := c.do("list changes", func() (err error) {
+			resp, err = c.service.Changes.List(page).Spaces("drive").
+				Fields("nextPageToken", "newStartPageToken", "changes(fileId,removed,time,file(id,name,mimeType,parents,md5Checksum,modifiedTime,trashed))").Do()
+			return err
+		})
+		if err != nil {
+			return nil, token, err
+		}
+		changes = append(changes, resp.Changes...)
+		if resp.NewStartPageToken != "" {
+			return changes, resp.NewStartPageToken, nil
+		}
+		page = resp.NextPageToken
+	}
+	return changes, token, nil
+}
+
+// ChangedVideoMeta loads the clip folders changed since the given time,
+// together with their contents; with a zero time it loads every clip
+// folder. A folder counts as changed when it or one of its files was
+// modified. The ids of changed folders that are now in the trash are
+// returned separately.
+func (c *Client) ChangedVideoMeta(since time.Time) ([]*VideoMeta, []string, error) {
+	q := "trashed=false"
+	if !since.IsZero() {
+		// trashing counts as a change, so trashed files are listed too
+		q = fmt.Sprintf("modifiedTime > '%s'", since.UTC().Format(time.RFC3339))
+	}
+	files, err := c.listFiles("list changed files", q, "id,name,mimeType,parents,appProperties,md5Checksum,size,modifiedTime,trashed")
+	if err != nil {
+		return nil, nil, err
+	}
+	folders := map[string]*drive.File{}
+	children := map[string][]*drive.File{}
+	var trashed []string
+	for _, f := range files {
+		switch {
+		case f.MimeType == folderMimeType && f.Trashed:
+			trashed = append(trashed, f.Id)
+		case f.MimeType == folderMimeType:
+			folders[f.Id] = f
+			children[f.Id] = children[f.Id]
+		default:
+			for _, parent := range f.Parents {
+				children[parent] = append(children[parent], f)
+			}
+		}
+	}
+	if !since.IsZero() {
+		// only the changes were listed, fetch the complete folders
+		for id := range children {
+			if folders[id] == nil {
+				folder, err := c.Folder(id)
+				if err != nil {
+					return nil, nil, err
+				}
+				if folder.MimeType != folderMimeType || folder.Trashed {
+					continue
+				}
+				folders[id] = folder
+			}
+			if children[id], err = c.driveFolderListById(id); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	var ret []*VideoMeta
+	for id, folder := range folders {
+		vmeta := c.folderMeta(folder)
+		if vmeta == nil {
+			continue
+		}
+		for _, f := range children[id] {
+			if !f.Trashed {
+				vmeta.Children = append(vmeta.Children, f)
+			}
+		}
+		ret = append(ret, vmeta)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].folderName < ret[j].folderName })
+	return ret, trashed, nil
+}
+
+// IsPattern reports whether name is a folder name pattern: * matches any
+// run of characters and ? a single one. Brackets are literal, as clip
+// folder names contain them.
+func IsPattern(name string) bool {
+	return strings.ContainsAny(name, "*?")
+}
+
+// MatchName reports whether name matches pattern, in which * stands for
+// any run of characters and ? for one.
+func MatchName(pattern, name string) bool {
+	p, n := []rune(pattern), []rune(name)
+	// backtrack to the last * on a mismatch
+	pi, ni, star, mark := 0, 0, -1, 0
+	for ni < len(n) {
+		switch {
+		case pi < len(p) && (p[pi] == '?' || p[pi] == n[ni]):
+			pi++
+			ni++
+		case pi < len(p) && p[pi] == '*':
+			star, mark = pi, ni
+			pi++
+		case star >= 0:
+			pi = star + 1
+			mark++
+			ni = mark
+		default:
+			return false
+		}
+	}
+	for pi < len(p) && p[pi] == '*' {
+		pi++
+	}
+	return pi == len(p)
+}
+
+// FindFolders lists the names of the folders matching pattern, sorted.
+func (c *Client) FindFolders(pattern string) ([]string, error) {
+	q := fmt.Sprintf("mimeType='%s' and trashed=false", folderMimeType)
+	// Drive can only narrow the search by the literal prefix
+	if prefix := pattern[:strings.IndexAny(pattern+"*", "*?")]; prefix != "" {
+		q += fmt.Sprintf(" and name contains '%s'", strings.ReplaceAll(prefix, "'", `\'`))
+	}
+	folders, err := c.listFiles("find folders "+pattern, q, "name")
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, f := range folders {
+		if MatchName(pattern, f.Name) {
+			names = append(names, f.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Properties are the app properties UpdateVideoMeta writes for vmeta.
+func (vmeta *VideoMeta) Properties() map[string]string {
+	props := make(map[string]string)
 	if vmeta.VideoId != nil {
-		nf.AppProperties[VIDEO_ID] = *vmeta.VideoId
+		props[VIDEO_ID] = *vmeta.VideoId
 	}
 
 	if vmeta.CaptionId != nil {
-		nf.AppProperties[CAPTION_ID] = *vmeta.CaptionId
+		props[CAPTION_ID] = *vmeta.CaptionId
 	}
 
 	if vmeta.Privacy != nil {
-		nf.AppProperties[PRIVACY] = *vmeta.Privacy
+		props[PRIVACY] = *vmeta.Privacy
 	}
 
+	if vmeta.Series != nil {
+		props[SERIES] = *vmeta.Series
+	}
+
+	if vmeta.Scheduled != nil {
+		props[SCHEDULED] = *vmeta.Scheduled
+	}
+
+	if len(vmeta.Deleted) > 0 {
+		props[DELETED] = strings.Join(vmeta.Deleted, ",")
+	}
+
+	for step, done := range vmeta.Release {
+		props[RELEASE_PREFIX+step] = done
+	}
+	for kind, sum := range vmeta.Pushed {
+		props[PUSHED_PREFIX+kind] = sum
+	}
+	for lang, id := range vmeta.Captions {
+		props[CAPTION_PREFIX+lang] = id
+	}
+	return props
+}
+
+func (c *Client) UpdateVideoMeta(vmeta *VideoMeta) error {
+
+	// update meta
+	nf := &drive.File{Description: prettyPrint(vmeta)}
+	nf.AppProperties = vmeta.Properties()
+
+	if c.plan.Record("drive", "update folder", vmeta.folderName, fmt.Sprint(nf.AppProperties)) {
+		return nil
+	}
 	fmt.Println("Writing App properties")
 	fmt.Println(nf.AppProperties)
-	_, err := service.Files.Update(vmeta.FolderId, nf).Do()
-	handleError(err, "write meta")
-}
-
-func main() {
-	// call := driveService.Files.List().Q("mimetype='application/vnd.google-apps.folder'")
-	// Q("mimeType='application/vnd.google-apps.folder'").
-	folderId := "1W1_eweowezPS-0rhUrN-TMEWaCK0HA38"
-	f, err := service.Files.Get(folderId).Fields("id", "name", "description", "appProperties").Do()
-	handleError(err, "read meta")
-	fmt.Println(f.Description, f.AppProperties)
-	nf := &drive.File{Description: "description=abcdef"}
-	nf.AppProperties = make(map[string]string)
-	nf.AppProperties["youtubeId"] = "abcdef"
-	f, err = service.Files.Update(folderId, nf).Do()
-	handleError(err, "write meta")
-
-	// zh230114_[37.34-38.51]_生命中別投降別氣餒
-	// zh230101_[08.33-10.19]_無論生活還是修行，必須小心自己的念頭，照顧好自己的心
-
-	// call := service.About
-	// resp, _ := call.Get().Do()
-	// fmt.Println(resp.StorageQuota.Usage)
-	// for _, file := range driveFolderListByName("zh230114_[37.34-38.51]_生命中別投降別氣餒") {
-	// 	fmt.Println(file.Id, file.Name, file.MimeType, file.Trashed)
-	// }
-
-	// fmt.Println(resp)
+	err := c.do("write meta", func() error {
+		_, err := c.service.Files.Update(vmeta.FolderId, nf).Do()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	c.store(vmeta)
+	return nil
 }
@@ -0,0 +1,163 @@
+package drapi
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+	"twsati/internal/google/fake"
+	"twsati/internal/google/retry"
+)
+
+const folderName = "zh230114_[37.34-38.51]_生命中別投降別氣餒"
+
+func newFakeClient(t *testing.T) (*Client, *fake.Server) {
+	srv := fake.NewServer()
+	t.Cleanup(srv.Close)
+	c, err := NewClient(Options{HTTPClient: srv.HTTPClient(), Endpoint: srv.DriveEndpoint(), Retry: &retry.Policy{MaxAttempts: 2, Sleep: func(time.Duration) {}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, srv
+}
+
+func TestGetVideoMeta(t *testing.T) {
+	c, srv := newFakeClient(t)
+	folder := srv.AddFolder(folderName, map[string]string{VIDEO_ID: "vid1", PRIVACY: "unlisted"})
+	old := time.Date(2023, 1, 14, 0, 0, 0, 0, time.UTC)
+	srv.AddFile(folder, "old.srt", []byte("old"), old)
+	srv.AddFile(folder, "new.srt", []byte("new"), old.Add(time.Hour))
+	srv.AddFile(folder, "desc.txt", []byte("description"), old)
+
+	vmeta, err := c.GetVideoMeta(folderName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vmeta.CleanUp()
+	if vmeta.FolderId != folder || vmeta.Title != "生命中別投降別氣餒" || vmeta.Smin != 37 {
+		t.Errorf("unexpected meta %+v", vmeta)
+	}
+	if vmeta.VideoId == nil || *vmeta.VideoId != "vid1" || vmeta.CaptionId != nil {
+		t.Errorf("app properties not loaded: %+v", vmeta)
+	}
+	if !vmeta.HasCaption() || !vmeta.HasDescription() || vmeta.HasVideo() {
+		t.Errorf("children not loaded: %v", vmeta.Children)
+	}
+
+	path, err := vmeta.CaptionPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "new" {
+		t.Errorf("downloaded %q, want the newest caption", b)
+	}
+	if desc, err := vmeta.DescriptionContent(); err != nil || desc != "description" {
+		t.Errorf("description = %q, %v", desc, err)
+	}
+	if _, err := vmeta.VideoFilePath(); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("expected ErrFileNotFound, got %v", err)
+	}
+
+	setSptr(&vmeta.CaptionId, "cap1")
+	if err := c.UpdateVideoMeta(vmeta); err != nil {
+		t.Fatal(err)
+	}
+	if props := srv.File(folder).AppProperties; props[CAPTION_ID] != "cap1" || props[VIDEO_ID] != "vid1" {
+		t.Errorf("app properties = %v", props)
+	}
+}
+
+func TestTracks(t *testing.T) {
+	c, srv := newFakeClient(t)
+	folder := srv.AddFolder(folderName, map[string]string{CAPTION_PREFIX + "en": "cap2"})
+	old := time.Date(2023, 1, 14, 0, 0, 0, 0, time.UTC)
+	srv.AddFile(folder, folderName+".srt", []byte("main"), old)
+	srv.AddFile(folder, folderName+".en.srt", []byte("english"), old.Add(time.Hour))
+	srv.AddFile(folder, folderName+".th.vtt", []byte("thai"), old)
+
+	vmeta, err := c.GetVideoMeta(folderName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vmeta.CleanUp()
+	if vmeta.CaptionFile() != folderName+".srt" {
+		t.Errorf("main caption = %q, want the file without a language", vmeta.CaptionFile())
+	}
+	if langs := vmeta.Tracks(); !reflect.DeepEqual(langs, []string{"en", "th"}) {
+		t.Errorf("tracks = %v", langs)
+	}
+	if path, err := vmeta.TrackPath("en"); err != nil {
+		t.Fatal(err)
+	} else if b, _ := os.ReadFile(path); string(b) != "english" {
+		t.Errorf("downloaded %q for en", b)
+	}
+	if _, err := vmeta.TrackPath("ja"); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("expected ErrFileNotFound, got %v", err)
+	}
+	if vmeta.Captions["en"] != "cap2" || vmeta.Properties()[CAPTION_PREFIX+"en"] != "cap2" {
+		t.Errorf("caption ids = %v", vmeta.Captions)
+	}
+	for name, want := range map[string]string{
+		"clip.en.srt": "en", "clip.zh-CN.ttml": "zh-cn", "clip.srt": "", "zh230114_[37.34-38.51]_標題.srt": "", "clip.en.txt": "",
+	} {
+		if got := TrackLanguage(name); got != want {
+			t.Errorf("TrackLanguage(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestGetVideoMetaFolderErrors(t *testing.T) {
+	c, srv := newFakeClient(t)
+	if _, err := c.GetVideoMeta(folderName); !errors.Is(err, ErrFolderNotFound) {
+		t.Errorf("expected ErrFolderNotFound, got %v", err)
+	}
+	srv.AddFolder(folderName, nil)
+	srv.AddFolder(folderName, nil)
+	if _, err := c.GetVideoMeta(folderName); !errors.Is(err, ErrFolderAmbiguous) {
+		t.Errorf("expected ErrFolderAmbiguous, got %v", err)
+	}
+}
+
+func TestGetVideoMetaRetries(t *testing.T) {
+	c, srv := newFakeClient(t)
+	srv.AddFolder(folderName, nil)
+	srv.FailNext("GET", "/drive/v3/files", 503, "backendError")
+	if _, err := c.GetVideoMeta(folderName); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindFolders(t *testing.T) {
+	c, srv := newFakeClient(t)
+	for _, name := range []string{"zh221001_[00.00-01.00]_一", "zh221002_[00.00-01.00]_二", "zh230114_[00.00-01.00]_三"} {
+		srv.AddFolder(name, nil)
+	}
+	// files are not folders even when the name matches
+	srv.AddFile("", "zh221003_[00.00-01.00]_四.mp4", nil, time.Time{})
+
+	names, err := c.FindFolders("zh2210*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "zh221001_[00.00-01.00]_一" || names[1] != "zh221002_[00.00-01.00]_二" {
+		t.Errorf("names = %q", names)
+	}
+
+	cases := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"zh2210*", "zh221001_[00.00-01.00]_一", true},
+		{"*_一", "zh221001_[00.00-01.00]_一", true},
+		{"zh22100?_[00.00-01.00]_*", "zh221001_[00.00-01.00]_一", true},
+		{"zh2210*_二", "zh221001_[00.00-01.00]_一", false},
+		{"zh22100?", "zh221001_[00.00-01.00]_一", false},
+		{"*", "", true},
+	}
+	for _, c := range cases {
+		if got := MatchName(c.pattern, c.name); got != c.want {
+			t.Errorf("MatchName(%q, %q) = %v, want %v", c.pattern, c.name, got, c.want)
+		}
+	}
+}
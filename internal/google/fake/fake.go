@@ -0,0 +1,1010 @@
+// Package fake is an in-process stand-in for the subset of the Drive v3
+// and YouTube Data v3 APIs used by drapi and ytapi. Tests point both
+// clients at Server.URL, seed folders and files, run a command, then
+// inspect the resulting state.
+package fake
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/youtube/v3"
+)
+
+const folderMimeType = "application/vnd.google-apps.folder"
+
+// Server holds the fake state behind an httptest.Server.
+type Server struct {
+	URL string
+
+	srv *httptest.Server
+	mu  sync.Mutex
+	seq int
+
+	files    map[string]*drive.File
+	contents map[string][]byte
+	// changes is the Drive changes feed; a page token is an index into it.
+	changes []*drive.Change
+
+	videos        map[string]*youtube.Video
+	captions      map[string]*youtube.Caption
+	captionData   map[string][]byte
+	thumbnails    map[string][]byte
+	playlistItems map[string][]*youtube.PlaylistItem
+	uploads       map[string]*pendingUpload
+
+	failures []failure
+	calls    []string
+}
+
+type pendingUpload struct {
+	video *youtube.Video
+	total int64
+	data  []byte
+}
+
+type failure struct {
+	method string
+	path   string
+	code   int
+	reason string
+}
+
+// NewServer starts an empty fake. Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		files:         map[string]*drive.File{},
+		contents:      map[string][]byte{},
+		videos:        map[string]*youtube.Video{},
+		captions:      map[string]*youtube.Caption{},
+		captionData:   map[string][]byte{},
+		thumbnails:    map[string][]byte{},
+		playlistItems: map[string][]*youtube.PlaylistItem{},
+		uploads:       map[string]*pendingUpload{},
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serve))
+	s.URL = s.srv.URL
+	return s
+}
+
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// HTTPClient returns a client that talks to the fake.
+func (s *Server) HTTPClient() *http.Client {
+	return s.srv.Client()
+}
+
+// DriveEndpoint is the value for drapi.Options.Endpoint.
+func (s *Server) DriveEndpoint() string {
+	return s.URL + "/drive/v3/"
+}
+
+// YouTubeEndpoint is the value for ytapi.Options.Endpoint.
+func (s *Server) YouTubeEndpoint() string {
+	return s.URL + "/"
+}
+
+// FailNext makes the next request whose method and path match respond with
+// a Google style error carrying code and reason. An empty method matches
+// any method; path is matched as a prefix.
+func (s *Server) FailNext(method, path string, code int, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, failure{method: method, path: path, code: code, reason: reason})
+}
+
+// Calls lists every request served so far as "METHOD /path".
+func (s *Server) Calls() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.calls...)
+}
+
+func (s *Server) nextId(prefix string) string {
+	s.seq++
+	return fmt.Sprintf("%s%d", prefix, s.seq)
+}
+
+// ---- seeding and inspection: Drive ----
+
+// AddFolder creates a folder and returns its id.
+func (s *Server) AddFolder(name string, appProperties map[string]string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.nextId("folder")
+	props := map[string]string{}
+	for k, v := range appProperties {
+		props[k] = v
+	}
+	s.files[id] = &drive.File{Id: id, Name: name, MimeType: folderMimeType, AppProperties: props,
+		ModifiedTime: s.now().Format(time.RFC3339)}
+	s.changed(id)
+	return id
+}
+
+// AddFile creates a file with content inside parent and returns its id.
+// A zero modified time means now.
+func (s *Server) AddFile(parent, name string, content []byte, modified time.Time) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.nextId("file")
+	if modified.IsZero() {
+		modified = s.now()
+	}
+	s.files[id] = &drive.File{Id: id, Name: name, Parents: []string{parent},
+		MimeType: mime.TypeByExtension(extOf(name)), ModifiedTime: modified.UTC().Format(time.RFC3339Nano)}
+	s.setContent(id, content)
+	s.changed(id)
+	return id
+}
+
+// UpdateFile replaces the content of a Drive file as an edit on Drive
+// would.
+func (s *Server) UpdateFile(id string, content []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f, ok := s.files[id]; ok {
+		f.ModifiedTime = s.now().Format(time.RFC3339Nano)
+		s.setContent(id, content)
+		s.changed(id)
+	}
+}
+
+// changed appends the current state of file id to the changes feed.
+func (s *Server) changed(id string) {
+	change := &drive.Change{Kind: "drive#change", ChangeType: "file", FileId: id, Time: s.now().Format(time.RFC3339Nano)}
+	if f, ok := s.files[id]; ok {
+		cp := *f
+		change.File = &cp
+	} else {
+		change.Removed = true
+	}
+	s.changes = append(s.changes, change)
+}
+
+// File returns a copy of the Drive file, or nil.
+func (s *Server) File(id string) *drive.File {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f, ok := s.files[id]; ok {
+		cp := *f
+		return &cp
+	}
+	return nil
+}
+
+// Content returns the bytes stored for a Drive file.
+func (s *Server) Content(id string) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.contents[id]
+}
+
+// Children lists the untrashed files inside parent sorted by name.
+func (s *Server) Children(parent string) []*drive.File {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ret []*drive.File
+	for _, f := range s.files {
+		if contains(f.Parents, parent) && !f.Trashed {
+			cp := *f
+			ret = append(ret, &cp)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret
+}
+
+// ---- seeding and inspection: YouTube ----
+
+// AddVideo stores a video as if it had been uploaded and returns its id.
+func (s *Server) AddVideo(title, privacy string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.nextId("video")
+	s.videos[id] = &youtube.Video{Id: id, Kind: "youtube#video",
+		Snippet:    &youtube.VideoSnippet{Title: title},
+		Status:     &youtube.VideoStatus{PrivacyStatus: privacy, UploadStatus: "processed"},
+		Statistics: &youtube.VideoStatistics{}}
+	return id
+}
+
+// Video returns a copy of the video, or nil when it does not exist.
+func (s *Server) Video(id string) *youtube.Video {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok := s.videos[id]; ok {
+		cp := *v
+		return &cp
+	}
+	return nil
+}
+
+// SetPublishAt sets the scheduled release time of a video.
+func (s *Server) SetPublishAt(videoId, publishAt string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.videos[videoId].Status.PublishAt = publishAt
+}
+
+// VideoIds lists all stored video ids, sorted.
+func (s *Server) VideoIds() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ids []string
+	for id := range s.videos {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// VideoContent returns the uploaded media of a video.
+func (s *Server) VideoContent(id string) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.contents["media:"+id]
+}
+
+// AddCaption stores a caption track for videoId and returns its id.
+func (s *Server) AddCaption(videoId, lang, name string, content []byte) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.nextId("caption")
+	s.captions[id] = &youtube.Caption{Id: id, Kind: "youtube#caption",
+		Snippet: &youtube.CaptionSnippet{VideoId: videoId, Language: lang, Name: name}}
+	s.captionData[id] = content
+	return id
+}
+
+// AddASRCaption adds a track generated by speech recognition. Like
+// YouTube, the server refuses to download it.
+func (s *Server) AddASRCaption(videoId, lang string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.nextId("caption")
+	s.captions[id] = &youtube.Caption{Id: id, Kind: "youtube#caption",
+		Snippet: &youtube.CaptionSnippet{VideoId: videoId, Language: lang, TrackKind: "asr"}}
+	return id
+}
+
+// Captions lists the caption tracks of a video sorted by id.
+func (s *Server) Captions(videoId string) []*youtube.Caption {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.captionsOf(videoId)
+}
+
+// CaptionContent returns the uploaded caption file.
+func (s *Server) CaptionContent(id string) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.captionData[id]
+}
+
+// Thumbnail returns the image set for videoId.
+func (s *Server) Thumbnail(videoId string) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.thumbnails[videoId]
+}
+
+// AddPlaylistItem appends videoId to playlistId and returns the item id.
+func (s *Server) AddPlaylistItem(playlistId, videoId string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item := &youtube.PlaylistItem{Snippet: &youtube.PlaylistItemSnippet{PlaylistId: playlistId,
+		ResourceId: &youtube.ResourceId{Kind: "youtube#video", VideoId: videoId}}}
+	s.insertPlaylistItem(item, len(s.playlistItems[playlistId]))
+	return item.Id
+}
+
+// PlaylistVideoIds lists the video ids of a playlist in order.
+func (s *Server) PlaylistVideoIds(playlistId string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ids []string
+	for _, item := range s.playlistItems[playlistId] {
+		ids = append(ids, item.Snippet.ResourceId.VideoId)
+	}
+	return ids
+}
+
+// ---- HTTP ----
+
+var (
+	driveFileRe   = regexp.MustCompile(`^/drive/v3/files/([^/]+)$`)
+	youtubeRe     = regexp.MustCompile(`^/youtube/v3/([A-Za-z]+)$`)
+	captionDataRe = regexp.MustCompile(`^/youtube/v3/captions/([^/]+)$`)
+)
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, r.Method+" "+r.URL.Path)
+	for i, f := range s.failures {
+		if (f.method == "" || f.method == r.Method) && strings.HasPrefix(r.URL.Path, f.path) {
+			s.failures = append(s.failures[:i], s.failures[i+1:]...)
+			writeError(w, f.code, f.reason, "injected failure")
+			return
+		}
+	}
+
+	path := r.URL.Path
+	switch {
+	case path == "/drive/v3/about":
+		writeJSON(w, &drive.About{User: &drive.User{DisplayName: "Fake User", EmailAddress: "fake@example.com"}})
+	case path == "/drive/v3/changes/startPageToken" && r.Method == http.MethodGet:
+		writeJSON(w, &drive.StartPageToken{StartPageToken: strconv.Itoa(len(s.changes))})
+	case path == "/drive/v3/changes" && r.Method == http.MethodGet:
+		s.changeList(w, r)
+	case path == "/drive/v3/files" && r.Method == http.MethodGet:
+		s.driveList(w, r)
+	case path == "/drive/v3/files" && r.Method == http.MethodPost,
+		path == "/upload/drive/v3/files" && r.Method == http.MethodPost:
+		s.driveCreate(w, r)
+	case driveFileRe.MatchString(path):
+		s.driveFile(w, r, driveFileRe.FindStringSubmatch(path)[1])
+	case strings.HasPrefix(path, "/upload/drive/v3/files/") && r.Method == http.MethodPatch:
+		s.driveFile(w, r, strings.TrimPrefix(path, "/upload/drive/v3/files/"))
+	case path == "/upload/youtube/v3/videos":
+		s.videoUpload(w, r)
+	case path == "/upload/youtube/v3/captions":
+		s.captionUpload(w, r)
+	case path == "/upload/youtube/v3/thumbnails/set":
+		s.thumbnailSet(w, r)
+	case captionDataRe.MatchString(path) && r.Method == http.MethodGet:
+		s.captionDownload(w, r, captionDataRe.FindStringSubmatch(path)[1])
+	case youtubeRe.MatchString(path):
+		s.youtube(w, r, youtubeRe.FindStringSubmatch(path)[1])
+	default:
+		writeError(w, http.StatusNotFound, "notFound", "fake: no handler for "+r.Method+" "+path)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, reason, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    code,
+			"message": message,
+			"errors":  []map[string]string{{"reason": reason, "message": message}},
+		},
+	})
+}
+
+func (s *Server) now() time.Time {
+	return time.Now().UTC()
+}
+
+func (s *Server) setContent(id string, content []byte) {
+	s.contents[id] = content
+	if f, ok := s.files[id]; ok {
+		sum := md5.Sum(content)
+		f.Md5Checksum = hex.EncodeToString(sum[:])
+		f.Size = int64(len(content))
+	}
+}
+
+// readUpload splits a multipart/related upload into its JSON metadata and
+// media parts; any other body is media only.
+func readUpload(r *http.Request) (meta []byte, media []byte, err error) {
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		media, err = io.ReadAll(r.Body)
+		return nil, media, err
+	}
+	mr := multipart.NewReader(r.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return meta, media, nil
+		} else if err != nil {
+			return nil, nil, err
+		}
+		b, err := io.ReadAll(part)
+		if err != nil {
+			return nil, nil, err
+		}
+		if meta == nil && strings.HasPrefix(part.Header.Get("Content-Type"), "application/json") {
+			meta = b
+		} else {
+			media = b
+		}
+	}
+}
+
+// ---- Drive handlers ----
+
+var (
+	appPropRe = regexp.MustCompile(`appProperties has \{ *key='((?:\\'|[^'])*)' and value='((?:\\'|[^'])*)' *\}`)
+	termRes   = []*regexp.Regexp{
+		regexp.MustCompile(`^name *= *'((?:\\'|[^'])*)'$`),
+		regexp.MustCompile(`^name contains '((?:\\'|[^'])*)'$`),
+		regexp.MustCompile(`^'((?:\\'|[^'])*)' in parents$`),
+		regexp.MustCompile(`^mimeType *= *'((?:\\'|[^'])*)'$`),
+		regexp.MustCompile(`^mimeType *!= *'((?:\\'|[^'])*)'$`),
+		regexp.MustCompile(`^trashed *= *(true|false)$`),
+		regexp.MustCompile(`^__appprop(\d+)__$`),
+		regexp.MustCompile(`^modifiedTime *> *'([^']*)'$`),
+	}
+)
+
+func unquote(s string) string {
+	return strings.ReplaceAll(s, `\'`, `'`)
+}
+
+// matcher compiles the subset of the Drive query language the clients
+// use: terms joined by "and".
+func matcher(q string) (func(*drive.File) bool, error) {
+	var props [][]string
+	q = appPropRe.ReplaceAllStringFunc(q, func(m string) string {
+		sub := appPropRe.FindStringSubmatch(m)
+		props = append(props, []string{unquote(sub[1]), unquote(sub[2])})
+		return fmt.Sprintf("__appprop%d__", len(props)-1)
+	})
+	var preds []func(*drive.File) bool
+	for _, term := range strings.Split(q, " and ") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		matched := false
+		for i, re := range termRes {
+			sub := re.FindStringSubmatch(term)
+			if sub == nil {
+				continue
+			}
+			matched = true
+			arg := unquote(sub[1])
+			switch i {
+			case 0:
+				preds = append(preds, func(f *drive.File) bool { return f.Name == arg })
+			case 1:
+				preds = append(preds, func(f *drive.File) bool { return strings.Contains(f.Name, arg) })
+			case 2:
+				preds = append(preds, func(f *drive.File) bool { return contains(f.Parents, arg) })
+			case 3:
+				preds = append(preds, func(f *drive.File) bool { return f.MimeType == arg })
+			case 4:
+				preds = append(preds, func(f *drive.File) bool { return f.MimeType != arg })
+			case 5:
+				want := arg == "true"
+				preds = append(preds, func(f *drive.File) bool { return f.Trashed == want })
+			case 6:
+				n, _ := strconv.Atoi(arg)
+				kv := props[n]
+				preds = append(preds, func(f *drive.File) bool { return f.AppProperties[kv[0]] == kv[1] })
+			case 7:
+				since, err := time.Parse(time.RFC3339, arg)
+				if err != nil {
+					return nil, fmt.Errorf("fake: bad time in %q", term)
+				}
+				preds = append(preds, func(f *drive.File) bool {
+					modified, _ := time.Parse(time.RFC3339Nano, f.ModifiedTime)
+					return modified.After(since)
+				})
+			}
+			break
+		}
+		if !matched {
+			return nil, fmt.Errorf("fake: unsupported query term %q", term)
+		}
+	}
+	return func(f *drive.File) bool {
+		for _, p := range preds {
+			if !p(f) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func (s *Server) driveList(w http.ResponseWriter, r *http.Request) {
+	match, err := matcher(r.URL.Query().Get("q"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid", err.Error())
+		return
+	}
+	var ids []string
+	for id, f := range s.files {
+		if match(f) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	resp := &drive.FileList{Files: []*drive.File{}}
+	for _, id := range ids {
+		cp := *s.files[id]
+		resp.Files = append(resp.Files, &cp)
+	}
+	writeJSON(w, resp)
+}
+
+// changeList serves the changes after pageToken, pageSize at a time.
+func (s *Server) changeList(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	start, err := strconv.Atoi(q.Get("pageToken"))
+	if err != nil || start < 0 || start > len(s.changes) {
+		writeError(w, http.StatusBadRequest, "invalid", "invalid page token "+q.Get("pageToken"))
+		return
+	}
+	size, _ := strconv.Atoi(q.Get("pageSize"))
+	if size <= 0 {
+		size = 100
+	}
+	end := start + size
+	resp := &drive.ChangeList{Kind: "drive#changeList", Changes: []*drive.Change{}}
+	if end < len(s.changes) {
+		resp.NextPageToken = strconv.Itoa(end)
+	} else {
+		end = len(s.changes)
+		resp.NewStartPageToken = strconv.Itoa(end)
+	}
+	resp.Changes = append(resp.Changes, s.changes[start:end]...)
+	writeJSON(w, resp)
+}
+
+func (s *Server) driveCreate(w http.ResponseWriter, r *http.Request) {
+	meta, media, err := readUpload(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "badContent", err.Error())
+		return
+	}
+	if r.URL.Path == "/drive/v3/files" {
+		meta, media = media, nil
+	}
+	f := &drive.File{}
+	if len(meta) > 0 {
+		if err := json.Unmarshal(meta, f); err != nil {
+			writeError(w, http.StatusBadRequest, "parseError", err.Error())
+			return
+		}
+	}
+	f.Id = s.nextId("file")
+	if f.MimeType == "" {
+		f.MimeType = mime.TypeByExtension(extOf(f.Name))
+	}
+	f.ModifiedTime = s.now().Format(time.RFC3339Nano)
+	s.files[f.Id] = f
+	s.setContent(f.Id, media)
+	s.changed(f.Id)
+	cp := *f
+	writeJSON(w, &cp)
+}
+
+func (s *Server) driveFile(w http.ResponseWriter, r *http.Request, id string) {
+	f, ok := s.files[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "notFound", "File not found: "+id)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		if r.URL.Query().Get("alt") == "media" {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(s.contents[id])
+			return
+		}
+		cp := *f
+		writeJSON(w, &cp)
+	case http.MethodPatch:
+		meta, media, err := readUpload(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "badContent", err.Error())
+			return
+		}
+		if !strings.HasPrefix(r.URL.Path, "/upload/") {
+			meta, media = media, nil
+		}
+		if len(meta) > 0 {
+			// a null appProperties value deletes the key, as in Drive
+			var update struct {
+				Name          *string            `json:"name"`
+				Description   *string            `json:"description"`
+				Trashed       *bool              `json:"trashed"`
+				AppProperties map[string]*string `json:"appProperties"`
+			}
+			if err := json.Unmarshal(meta, &update); err != nil {
+				writeError(w, http.StatusBadRequest, "parseError", err.Error())
+				return
+			}
+			if update.Name != nil {
+				f.Name = *update.Name
+			}
+			if update.Description != nil {
+				f.Description = *update.Description
+			}
+			if update.Trashed != nil {
+				f.Trashed = *update.Trashed
+			}
+			for k, v := range update.AppProperties {
+				if f.AppProperties == nil {
+					f.AppProperties = map[string]string{}
+				}
+				if v == nil {
+					delete(f.AppProperties, k)
+				} else {
+					f.AppProperties[k] = *v
+				}
+			}
+		}
+		if strings.HasPrefix(r.URL.Path, "/upload/") {
+			s.setContent(id, media)
+		}
+		f.ModifiedTime = s.now().Format(time.RFC3339Nano)
+		s.changed(id)
+		cp := *f
+		writeJSON(w, &cp)
+	case http.MethodDelete:
+		delete(s.files, id)
+		delete(s.contents, id)
+		s.changed(id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "badRequest", r.Method)
+	}
+}
+
+// ---- YouTube handlers ----
+
+func (s *Server) youtube(w http.ResponseWriter, r *http.Request, resource string) {
+	q := r.URL.Query()
+	switch resource + " " + r.Method {
+	case "channels GET":
+		resp := &youtube.ChannelListResponse{Items: []*youtube.Channel{}}
+		if id := q.Get("id"); id != "" {
+			resp.Items = append(resp.Items, &youtube.Channel{Id: id,
+				Snippet:    &youtube.ChannelSnippet{Title: "Fake Channel"},
+				Statistics: &youtube.ChannelStatistics{ViewCount: 42}})
+		}
+		writeJSON(w, resp)
+	case "videos GET":
+		resp := &youtube.VideoListResponse{Items: []*youtube.Video{}}
+		for _, id := range strings.Split(q.Get("id"), ",") {
+			if v, ok := s.videos[id]; ok {
+				cp := *v
+				resp.Items = append(resp.Items, &cp)
+			}
+		}
+		writeJSON(w, resp)
+	case "videos PUT":
+		update := &youtube.Video{}
+		if err := json.NewDecoder(r.Body).Decode(update); err != nil {
+			writeError(w, http.StatusBadRequest, "parseError", err.Error())
+			return
+		}
+		v, ok := s.videos[update.Id]
+		if !ok {
+			writeError(w, http.StatusNotFound, "videoNotFound", "video not found: "+update.Id)
+			return
+		}
+		if update.Snippet != nil {
+			// thumbnails are read-only, an update keeps them
+			if v.Snippet != nil {
+				update.Snippet.Thumbnails = v.Snippet.Thumbnails
+			}
+			v.Snippet = update.Snippet
+		}
+		if update.Status != nil && update.Status.PublishAt != "" && update.Status.PrivacyStatus != "private" {
+			writeError(w, http.StatusBadRequest, "invalidPublishAt", "publishAt requires privacy status private")
+			return
+		}
+		if update.Status != nil {
+			upload := v.Status.UploadStatus
+			v.Status = update.Status
+			v.Status.UploadStatus = upload
+		}
+		cp := *v
+		writeJSON(w, &cp)
+	case "videos DELETE":
+		id := q.Get("id")
+		if _, ok := s.videos[id]; !ok {
+			writeError(w, http.StatusNotFound, "videoNotFound", "video not found: "+id)
+			return
+		}
+		delete(s.videos, id)
+		delete(s.contents, "media:"+id)
+		delete(s.thumbnails, id)
+		for cid, c := range s.captions {
+			if c.Snippet.VideoId == id {
+				delete(s.captions, cid)
+				delete(s.captionData, cid)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case "captions GET":
+		writeJSON(w, &youtube.CaptionListResponse{Items: s.captionsOf(q.Get("videoId"))})
+	case "captions DELETE":
+		id := q.Get("id")
+		if _, ok := s.captions[id]; !ok {
+			writeError(w, http.StatusNotFound, "captionNotFound", "caption not found: "+id)
+			return
+		}
+		delete(s.captions, id)
+		delete(s.captionData, id)
+		w.WriteHeader(http.StatusNoContent)
+	case "playlistItems GET":
+		s.playlistList(w, r)
+	case "playlistItems POST":
+		item := &youtube.PlaylistItem{}
+		if err := json.NewDecoder(r.Body).Decode(item); err != nil {
+			writeError(w, http.StatusBadRequest, "parseError", err.Error())
+			return
+		}
+		s.insertPlaylistItem(item, int(item.Snippet.Position))
+		cp := *item
+		writeJSON(w, &cp)
+	case "playlistItems PUT":
+		item := &youtube.PlaylistItem{}
+		if err := json.NewDecoder(r.Body).Decode(item); err != nil {
+			writeError(w, http.StatusBadRequest, "parseError", err.Error())
+			return
+		}
+		pl, idx := s.findPlaylistItem(item.Id)
+		if idx < 0 {
+			writeError(w, http.StatusNotFound, "playlistItemNotFound", "playlist item not found: "+item.Id)
+			return
+		}
+		items := s.playlistItems[pl]
+		moved := items[idx]
+		items = append(items[:idx], items[idx+1:]...)
+		pos := int(item.Snippet.Position)
+		if pos > len(items) {
+			pos = len(items)
+		}
+		items = append(items[:pos], append([]*youtube.PlaylistItem{moved}, items[pos:]...)...)
+		s.playlistItems[pl] = items
+		s.renumber(pl)
+		cp := *moved
+		writeJSON(w, &cp)
+	case "playlistItems DELETE":
+		pl, idx := s.findPlaylistItem(q.Get("id"))
+		if idx < 0 {
+			writeError(w, http.StatusNotFound, "playlistItemNotFound", "playlist item not found: "+q.Get("id"))
+			return
+		}
+		s.playlistItems[pl] = append(s.playlistItems[pl][:idx], s.playlistItems[pl][idx+1:]...)
+		s.renumber(pl)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusNotFound, "notFound", "fake: no handler for "+r.Method+" "+r.URL.Path)
+	}
+}
+
+func (s *Server) captionsOf(videoId string) []*youtube.Caption {
+	ret := []*youtube.Caption{}
+	for _, c := range s.captions {
+		if c.Snippet.VideoId == videoId {
+			cp := *c
+			ret = append(ret, &cp)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Id < ret[j].Id })
+	return ret
+}
+
+func (s *Server) captionDownload(w http.ResponseWriter, r *http.Request, id string) {
+	if c, ok := s.captions[id]; ok && c.Snippet.TrackKind == "asr" {
+		writeError(w, http.StatusForbidden, "forbidden", "the caption track cannot be downloaded: "+id)
+		return
+	}
+	data, ok := s.captionData[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "captionNotFound", "caption not found: "+id)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write(data)
+}
+
+func (s *Server) videoUpload(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	switch {
+	case r.Method == http.MethodPost && q.Get("uploadType") == "resumable":
+		video := &youtube.Video{}
+		if err := json.NewDecoder(r.Body).Decode(video); err != nil {
+			writeError(w, http.StatusBadRequest, "parseError", err.Error())
+			return
+		}
+		total, _ := strconv.ParseInt(r.Header.Get("X-Upload-Content-Length"), 10, 64)
+		uploadId := s.nextId("upload")
+		s.uploads[uploadId] = &pendingUpload{video: video, total: total}
+		w.Header().Set("Location", s.URL+"/upload/youtube/v3/videos?uploadType=resumable&upload_id="+uploadId)
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodPut && q.Get("upload_id") != "":
+		up, ok := s.uploads[q.Get("upload_id")]
+		if !ok {
+			writeError(w, http.StatusNotFound, "notFound", "upload session not found")
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "badContent", err.Error())
+			return
+		}
+		var start int64
+		if len(body) > 0 {
+			fmt.Sscanf(r.Header.Get("Content-Range"), "bytes %d-", &start)
+			if start != int64(len(up.data)) {
+				writeError(w, http.StatusBadRequest, "badContent", "chunk does not continue the upload")
+				return
+			}
+			up.data = append(up.data, body...)
+		}
+		if int64(len(up.data)) < up.total {
+			if len(up.data) > 0 {
+				w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", len(up.data)-1))
+			}
+			w.WriteHeader(http.StatusPermanentRedirect)
+			return
+		}
+		delete(s.uploads, q.Get("upload_id"))
+		video := up.video
+		video.Id = s.nextId("video")
+		video.Kind = "youtube#video"
+		if video.Status == nil {
+			video.Status = &youtube.VideoStatus{}
+		}
+		video.Status.UploadStatus = "uploaded"
+		video.Statistics = &youtube.VideoStatistics{}
+		s.videos[video.Id] = video
+		s.contents["media:"+video.Id] = up.data
+		cp := *video
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, &cp)
+	default:
+		writeError(w, http.StatusBadRequest, "badRequest", "fake: only resumable video uploads are supported")
+	}
+}
+
+func (s *Server) captionUpload(w http.ResponseWriter, r *http.Request) {
+	meta, media, err := readUpload(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "badContent", err.Error())
+		return
+	}
+	caption := &youtube.Caption{}
+	if err := json.Unmarshal(meta, caption); err != nil {
+		writeError(w, http.StatusBadRequest, "parseError", err.Error())
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		if _, ok := s.videos[caption.Snippet.VideoId]; !ok {
+			writeError(w, http.StatusNotFound, "videoNotFound", "video not found: "+caption.Snippet.VideoId)
+			return
+		}
+		caption.Id = s.nextId("caption")
+		caption.Kind = "youtube#caption"
+		s.captions[caption.Id] = caption
+	case http.MethodPut:
+		prev, ok := s.captions[caption.Id]
+		if !ok {
+			writeError(w, http.StatusNotFound, "captionNotFound", "caption not found: "+caption.Id)
+			return
+		}
+		if caption.Snippet != nil {
+			prev.Snippet = caption.Snippet
+		}
+		caption = prev
+	}
+	if media != nil {
+		s.captionData[caption.Id] = media
+	}
+	cp := *caption
+	writeJSON(w, &cp)
+}
+
+func (s *Server) thumbnailSet(w http.ResponseWriter, r *http.Request) {
+	videoId := r.URL.Query().Get("videoId")
+	if _, ok := s.videos[videoId]; !ok {
+		writeError(w, http.StatusNotFound, "videoNotFound", "video not found: "+videoId)
+		return
+	}
+	_, media, err := readUpload(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "badContent", err.Error())
+		return
+	}
+	s.thumbnails[videoId] = media
+	details := &youtube.ThumbnailDetails{Default: &youtube.Thumbnail{Url: s.URL + "/thumb/" + videoId}}
+	if v := s.videos[videoId]; v.Snippet != nil {
+		v.Snippet.Thumbnails = details
+	}
+	writeJSON(w, &youtube.ThumbnailSetResponse{Items: []*youtube.ThumbnailDetails{details}})
+}
+
+func (s *Server) playlistList(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	items := s.playlistItems[q.Get("playlistId")]
+	start, _ := strconv.Atoi(q.Get("pageToken"))
+	size, _ := strconv.Atoi(q.Get("maxResults"))
+	if size <= 0 {
+		size = 5
+	}
+	end := start + size
+	if end > len(items) {
+		end = len(items)
+	}
+	resp := &youtube.PlaylistItemListResponse{Items: []*youtube.PlaylistItem{}}
+	for _, item := range items[start:end] {
+		cp := *item
+		resp.Items = append(resp.Items, &cp)
+	}
+	if end < len(items) {
+		resp.NextPageToken = strconv.Itoa(end)
+	}
+	writeJSON(w, resp)
+}
+
+func (s *Server) insertPlaylistItem(item *youtube.PlaylistItem, pos int) {
+	pl := item.Snippet.PlaylistId
+	item.Id = s.nextId("item")
+	item.Kind = "youtube#playlistItem"
+	if v, ok := s.videos[item.Snippet.ResourceId.VideoId]; ok && v.Snippet != nil {
+		item.Snippet.Title = v.Snippet.Title
+	}
+	items := s.playlistItems[pl]
+	if pos < 0 || pos > len(items) {
+		pos = len(items)
+	}
+	s.playlistItems[pl] = append(items[:pos], append([]*youtube.PlaylistItem{item}, items[pos:]...)...)
+	s.renumber(pl)
+}
+
+func (s *Server) findPlaylistItem(id string) (string, int) {
+	for pl, items := range s.playlistItems {
+		for i, item := range items {
+			if item.Id == id {
+				return pl, i
+			}
+		}
+	}
+	return "", -1
+}
+
+func (s *Server) renumber(pl string) {
+	for i, item := range s.playlistItems[pl] {
+		item.Snippet.Position = int64(i)
+	}
+}
+
+func contains(xs []string, x string) bool {
+	for _, e := range xs {
+		if e == x {
+			return true
+		}
+	}
+	return false
+}
+
+func extOf(name string) string {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[i:]
+	}
+	return ""
+}
@@ -0,0 +1,64 @@
+// Package retry runs Google API calls with exponential backoff and jitter,
+// retrying only the errors apierr classifies as transient.
+package retry
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"time"
+	"twsati/internal/google/apierr"
+)
+
+// Policy bounds how often and how long a call is retried.
+type Policy struct {
+	// MaxAttempts counts the first call; values below 1 mean one attempt.
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	// Sleep defaults to time.Sleep; tests replace it.
+	Sleep func(time.Duration)
+	// Log receives the retry notices; nil means os.Stderr so they stay
+	// out of command output.
+	Log io.Writer
+}
+
+// Default is used by the API clients unless configured otherwise.
+var Default = Policy{MaxAttempts: 6, BaseDelay: time.Second, MaxDelay: 32 * time.Second}
+
+// Do calls fn until it succeeds, fails with a non-retryable error, or the
+// attempt budget is spent, and returns the last error.
+func (p Policy) Do(op string, fn func(attempt int) error) error {
+	sleep := p.Sleep
+	if sleep == nil {
+		sleep = time.Sleep
+	}
+	log := p.Log
+	if log == nil {
+		log = os.Stderr
+	}
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = fn(attempt)
+		if err == nil || !apierr.Retryable(err) || attempt+1 >= p.MaxAttempts {
+			return err
+		}
+		delay := p.backoff(attempt)
+		fmt.Fprintf(log, "%s failed (%v), retrying in %s (attempt %d/%d)\n", op, err, delay.Round(time.Millisecond), attempt+2, p.MaxAttempts)
+		sleep(delay)
+	}
+}
+
+// backoff picks a random delay up to BaseDelay*2^attempt capped at
+// MaxDelay ("full jitter").
+func (p Policy) backoff(attempt int) time.Duration {
+	ceiling := p.BaseDelay << uint(attempt)
+	if ceiling <= 0 || (p.MaxDelay > 0 && ceiling > p.MaxDelay) {
+		ceiling = p.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling)) + 1)
+}
@@ -0,0 +1,61 @@
+package retry
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+	"twsati/internal/google/apierr"
+
+	"google.golang.org/api/googleapi"
+)
+
+func gerr(code int, reason string) error {
+	return apierr.Wrap("call", &googleapi.Error{Code: code, Errors: []googleapi.ErrorItem{{Reason: reason}}})
+}
+
+func TestDo(t *testing.T) {
+	var slept []time.Duration
+	var log bytes.Buffer
+	p := Policy{MaxAttempts: 4, BaseDelay: time.Second, MaxDelay: 3 * time.Second, Sleep: func(d time.Duration) { slept = append(slept, d) }, Log: &log}
+
+	cases := []struct {
+		name     string
+		errs     []error
+		attempts int
+		fail     error
+	}{
+		{"success after 503", []error{gerr(http.StatusServiceUnavailable, "backendError"), nil}, 2, nil},
+		{"rate limited then ok", []error{gerr(http.StatusForbidden, "rateLimitExceeded"), gerr(http.StatusTooManyRequests, ""), nil}, 3, nil},
+		{"quota is fatal", []error{gerr(http.StatusForbidden, "quotaExceeded"), nil}, 1, apierr.ErrQuotaExceeded},
+		{"not found is fatal", []error{gerr(http.StatusNotFound, "videoNotFound")}, 1, apierr.ErrNotFound},
+		{"budget exhausted", []error{gerr(500, ""), gerr(500, ""), gerr(500, ""), gerr(500, ""), nil}, 4, apierr.ErrServer},
+	}
+	for _, c := range cases {
+		slept = nil
+		log.Reset()
+		calls := 0
+		err := p.Do(c.name, func(attempt int) error {
+			if attempt != calls {
+				t.Errorf("%s: attempt %d on call %d", c.name, attempt, calls)
+			}
+			calls++
+			return c.errs[attempt]
+		})
+		if calls != c.attempts {
+			t.Errorf("%s: %d calls, want %d", c.name, calls, c.attempts)
+		}
+		if c.fail == nil && err != nil || c.fail != nil && !errors.Is(err, c.fail) {
+			t.Errorf("%s: err = %v, want %v", c.name, err, c.fail)
+		}
+		if n := bytes.Count(log.Bytes(), []byte("retrying in")); n != c.attempts-1 {
+			t.Errorf("%s: %d retry notices, want %d", c.name, n, c.attempts-1)
+		}
+		for _, d := range slept {
+			if d <= 0 || d > p.MaxDelay {
+				t.Errorf("%s: delay %s out of range", c.name, d)
+			}
+		}
+	}
+}
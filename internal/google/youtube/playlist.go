@@ -1,8 +1,8 @@
 package ytapi
 
 import (
+	"errors"
 	"fmt"
-	"log"
 	"strings"
 
 	"google.golang.org/api/youtube/v3"
@@ -63,10 +63,15 @@ func subsets(xs []string, ys []string) ([]string, []string, []string, []string)
 	return a, b, c, d
 }
 
-func assert(cond bool, msg string) {
+// ErrPlaylistRebuild reports an internal inconsistency while planning
+// playlist operations; nothing has been sent to YouTube when it occurs.
+var ErrPlaylistRebuild = errors.New("playlist rebuild")
+
+func assert(cond bool, msg string) error {
 	if !cond {
-		log.Panic(msg)
+		return fmt.Errorf("%w: %s", ErrPlaylistRebuild, msg)
 	}
+	return nil
 }
 
 type MoveOper struct {
@@ -113,12 +118,12 @@ func _move[T comparable](slice []T, to, from int) []T {
 }
 
 // hola -> leho
-func RebuildPlaylist(playlistId string, to []string, currentItems YtPlist) ([]DeleteOper, []InsertOper, []MoveOper) {
+func RebuildPlaylist(playlistId string, to []string, currentItems YtPlist) ([]DeleteOper, []InsertOper, []MoveOper, error) {
 	from := currentItems.VideoIds()
 	tgt, toAdd, src, toDel := subsets(to, from)
-	assert(len(tgt) == len(src), fmt.Sprintf("subset length mismatch: %v: %v", tgt, src))
-	fmt.Println(src, "-->", tgt)
-	fmt.Println(toAdd, ",", toDel)
+	if err := assert(len(tgt) == len(src), fmt.Sprintf("subset length mismatch: %v: %v", tgt, src)); err != nil {
+		return nil, nil, nil, err
+	}
 
 	var deleteOpers []DeleteOper
 	var insertOpers []InsertOper
@@ -147,15 +152,15 @@ func RebuildPlaylist(playlistId string, to []string, currentItems YtPlist) ([]De
 		return _insertAt(slice, elem, i)
 	}
 
-	_deleteOper := func(slice []string, i int) []string {
-		key := slice[i]
-		_idx := currentItems.IdxByVideoId(key, operMapping[key])
+	_deleteOper := func(slice []string, i int) ([]string, error) {
 		if i < 0 {
-			log.Panic("can't delete item ", _idx)
+			return slice, assert(false, fmt.Sprint("can't delete item ", i))
 		}
+		key := slice[i]
+		_idx := currentItems.IdxByVideoId(key, operMapping[key])
 		deleteOpers = append(deleteOpers, DeleteOper{PlaylistItemId: currentItems[_idx].ItemId, title: currentItems[_idx].Title})
 		operMapping[key] = _idx + 1
-		return _deleteAt(slice, i)
+		return _deleteAt(slice, i), nil
 	}
 
 	_moveOper := func(slice []string, to, from int) []string {
@@ -171,16 +176,23 @@ func RebuildPlaylist(playlistId string, to []string, currentItems YtPlist) ([]De
 	// first delete
 	for _, e := range toDel {
 		j := _find(from, e, 0)
-		from = _deleteOper(from, j)
+		var err error
+		if from, err = _deleteOper(from, j); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	if err := assert(len(src) == len(from), fmt.Sprintf("src and from should be same length after deletion, %v : %v", src, from)); err != nil {
+		return nil, nil, nil, err
 	}
-	assert(len(src) == len(from), fmt.Sprintf("src and from should be same length after deletion, %v : %v", src, from))
 
 	// insert
 	for i := range toAdd {
 		last := len(toAdd) - 1 - i
 		from = _insertOper(from, toAdd[last], 0)
 	}
-	assert(len(from) == len(to), "src and from should match after deletion")
+	if err := assert(len(from) == len(to), "src and from should match after deletion"); err != nil {
+		return nil, nil, nil, err
+	}
 
 	// reorder
 	searchPoint := make(map[string]int)
@@ -191,22 +203,24 @@ func RebuildPlaylist(playlistId string, to []string, currentItems YtPlist) ([]De
 		}
 		searchPoint[to[i]] = i + 1
 	}
-	assert(strings.Join(from, "") == strings.Join(to, ""), fmt.Sprintf("from and to should be identical after reorder: %v : %v", from, to))
-	fmt.Println("original", from)
-	// filter moveOpers
-	fmt.Println("delete", deleteOpers)
-	fmt.Println("insert", insertOpers)
-	fmt.Println("reorder", moveOpers)
-	return deleteOpers, insertOpers, moveOpers
+	if err := assert(strings.Join(from, "") == strings.Join(to, ""), fmt.Sprintf("from and to should be identical after reorder: %v : %v", from, to)); err != nil {
+		return nil, nil, nil, err
+	}
+	return deleteOpers, insertOpers, moveOpers, nil
 }
 
-func CommitPlaylist(currentItems YtPlist, deletes []DeleteOper, inserts []InsertOper, reorders []MoveOper) {
+func (c *Client) CommitPlaylist(currentItems YtPlist, deletes []DeleteOper, inserts []InsertOper, reorders []MoveOper) error {
 	targetItems := currentItems
 	for _, op := range deletes {
-		PlaylistsItemDelete(op.PlaylistItemId)
+		if err := c.PlaylistsItemDelete(op.PlaylistItemId); err != nil {
+			return err
+		}
 	}
 	for _, op := range inserts {
-		item := PlaylistsItemInsert(op.PlaylistId, op.VideoId, int64(op.position))
+		item, err := c.PlaylistsItemInsert(op.PlaylistId, op.VideoId, int64(op.position))
+		if err != nil {
+			return err
+		}
 		targetItems = _insertAt(targetItems, *ToYtPlItem(item), op.position)
 	}
 
@@ -218,7 +232,9 @@ func CommitPlaylist(currentItems YtPlist, deletes []DeleteOper, inserts []Insert
 		idx := targetItems.IdxByVideoId(op.VideoId, mapping[op.VideoId])
 		mapping[op.VideoId] = idx + 1
 		itemId := targetItems[idx].ItemId
-		PlaylistsItemUpdate(itemId, op.PlaylistId, op.VideoId, int64(op.toPosition))
+		if _, err := c.PlaylistsItemUpdate(itemId, op.PlaylistId, op.VideoId, int64(op.toPosition)); err != nil {
+			return err
+		}
 	}
-
+	return nil
 }
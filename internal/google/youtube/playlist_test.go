@@ -2,11 +2,14 @@ package ytapi
 
 import (
 	"encoding/json"
-	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"twsati/internal/google/fake"
+	"twsati/internal/google/retry"
 
 	"google.golang.org/api/youtube/v3"
 )
@@ -42,11 +45,57 @@ func loadJson(path string) []YtPlItem {
 }
 
 func Test_rebuild(t *testing.T) {
-	items := loadJson("list.json")
-	fmt.Println(items)
-	// rebuild(strings.Split("adcebfjihg", ""), items)
-	del, ins, upd := RebuildPlaylist("abc", strings.Split("h63qnV0B0jc,xz0L3KknMU0,Yq8t3sFao4s,SeZl4r8F6hM,s2BSE3LvLpc", ","), items)
-	fmt.Println(del, ins, upd)
-	// commit(items, del, ins, upd)
-	// rebuild(strings.Split("hello world", ""), strings.Split("aloha", ""))
+	items := loadJson(filepath.Join("testdata", "list.json"))
+	del, ins, upd, err := RebuildPlaylist("abc", strings.Split("h63qnV0B0jc,xz0L3KknMU0,Yq8t3sFao4s,SeZl4r8F6hM,s2BSE3LvLpc", ","), items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantDel := []DeleteOper{{title: items[2].Title, PlaylistItemId: "item2"}}
+	wantIns := []InsertOper{{VideoId: "SeZl4r8F6hM", PlaylistId: "abc", position: 0}}
+	wantUpd := []MoveOper{
+		{title: items[1].Title, VideoId: "h63qnV0B0jc", PlaylistId: "abc", fromPosition: 2, toPosition: 0},
+		{title: items[0].Title, VideoId: "xz0L3KknMU0", PlaylistId: "abc", fromPosition: 2, toPosition: 1},
+		{title: items[3].Title, VideoId: "Yq8t3sFao4s", PlaylistId: "abc", fromPosition: 3, toPosition: 2},
+	}
+	if !reflect.DeepEqual(del, wantDel) {
+		t.Errorf("delete = %+v, want %+v", del, wantDel)
+	}
+	if !reflect.DeepEqual(ins, wantIns) {
+		t.Errorf("insert = %+v, want %+v", ins, wantIns)
+	}
+	if !reflect.DeepEqual(upd, wantUpd) {
+		t.Errorf("reorder = %+v, want %+v", upd, wantUpd)
+	}
+}
+
+func Test_commitPlaylist(t *testing.T) {
+	srv := fake.NewServer()
+	defer srv.Close()
+	for _, e := range loadJson(filepath.Join("testdata", "list.json")) {
+		srv.AddPlaylistItem("abc", e.VideoId)
+	}
+	c, err := NewClient(Options{HTTPClient: srv.HTTPClient(), Endpoint: srv.YouTubeEndpoint(), Retry: &retry.Policy{MaxAttempts: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := c.PlaylistsItemsAll("snippet", "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var current YtPlist
+	for _, e := range items {
+		current = append(current, *ToYtPlItem(e))
+	}
+	want := strings.Split("h63qnV0B0jc,xz0L3KknMU0,Yq8t3sFao4s,newVideo01,s2BSE3LvLpc", ",")
+	del, ins, upd, err := RebuildPlaylist("abc", want, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CommitPlaylist(current, del, ins, upd); err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.PlaylistVideoIds("abc"); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("playlist = %v, want %v", got, want)
+	}
 }
@@ -0,0 +1,282 @@
+package ytapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"twsati/internal/google/apierr"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/youtube/v3"
+)
+
+// DefaultChunkSize is used when UploadOptions.ChunkSize is not set.
+const DefaultChunkSize = 8 << 20
+
+// chunkUnit is the granularity the resumable protocol requires for every
+// chunk but the last.
+const chunkUnit = 256 << 10
+
+// UploadOptions controls how UploadVideo sends the media file.
+type UploadOptions struct {
+	// ChunkSize is rounded up to a multiple of 256 KiB.
+	ChunkSize int64
+	// Progress is called after every chunk YouTube acknowledges.
+	Progress func(Progress)
+	// SessionFile persists the resumable session URI so an interrupted
+	// upload can continue from the last acknowledged byte in a later run.
+	// It is removed once the upload completes. Empty disables resuming.
+	SessionFile string
+}
+
+// Progress reports how far an upload has got.
+type Progress struct {
+	Sent  int64
+	Total int64
+	// Rate is the average throughput of the current run in bytes/second.
+	Rate float64
+	ETA  time.Duration
+}
+
+func (p Progress) String() string {
+	pct := 100.0
+	if p.Total > 0 {
+		pct = float64(p.Sent) * 100 / float64(p.Total)
+	}
+	return fmt.Sprintf("%.1f/%.1f MB (%.0f%%) %.2f MB/s ETA %s",
+		float64(p.Sent)/(1<<20), float64(p.Total)/(1<<20), pct, p.Rate/(1<<20), p.ETA.Round(time.Second))
+}
+
+// uploadSession is what gets persisted to UploadOptions.SessionFile.
+type uploadSession struct {
+	URI     string
+	Title   string
+	Size    int64
+	Created time.Time
+}
+
+func (opts UploadOptions) chunkSize() int64 {
+	size := opts.ChunkSize
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	return (size + chunkUnit - 1) / chunkUnit * chunkUnit
+}
+
+func loadSession(path string) (*uploadSession, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sess := &uploadSession{}
+	return sess, json.Unmarshal(b, sess)
+}
+
+func saveSession(path string, sess *uploadSession) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(sess, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0600)
+}
+
+// errSessionGone means YouTube no longer knows the persisted session.
+var errSessionGone = errors.New("upload session expired")
+
+// resumableUpload sends file as video through the resumable upload
+// protocol and returns the created video.
+func (c *Client) resumableUpload(video *youtube.Video, file *os.File, opts UploadOptions) (*youtube.Video, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	total := info.Size()
+
+	var sess *uploadSession
+	offset := int64(0)
+	if opts.SessionFile != "" {
+		if prev, err := loadSession(opts.SessionFile); err == nil && prev.Size == total && prev.Title == video.Snippet.Title {
+			var done *youtube.Video
+			err = c.do("query upload", func() (err error) {
+				offset, done, err = c.queryUpload(prev.URI, total)
+				return err
+			})
+			switch {
+			case err == nil && done != nil:
+				os.Remove(opts.SessionFile)
+				return done, nil
+			case err == nil:
+				fmt.Printf("Resuming upload at %d of %d bytes\n", offset, total)
+				sess = prev
+			case errors.Is(err, errSessionGone):
+				fmt.Println("Previous upload session expired, starting over")
+			default:
+				return nil, err
+			}
+		}
+	}
+	if sess == nil {
+		var uri string
+		err := c.do("start upload", func() (err error) {
+			uri, err = c.startUpload(video, total)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		sess = &uploadSession{URI: uri, Title: video.Snippet.Title, Size: total, Created: time.Now()}
+		offset = 0
+		if opts.SessionFile != "" {
+			if err := saveSession(opts.SessionFile, sess); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	chunk := opts.chunkSize()
+	start, startOffset := time.Now(), offset
+	for {
+		n := chunk
+		if offset+n > total {
+			n = total - offset
+		}
+		var done *youtube.Video
+		err := c.retry.Do("upload chunk", func(attempt int) error {
+			// a failed chunk may have partially arrived, so ask where to
+			// continue before sending again
+			if attempt > 0 {
+				next, fin, err := c.queryUpload(sess.URI, total)
+				if err != nil || fin != nil {
+					done = fin
+					return apierr.Wrap("query upload", err)
+				}
+				offset = next
+				n = chunk
+				if offset+n > total {
+					n = total - offset
+				}
+			}
+			next, fin, err := c.putChunk(sess.URI, io.NewSectionReader(file, offset, n), offset, n, total)
+			if err != nil {
+				return apierr.Wrap("upload chunk", err)
+			}
+			offset, done = next, fin
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if opts.Progress != nil {
+			p := Progress{Sent: offset, Total: total}
+			if elapsed := time.Since(start).Seconds(); elapsed > 0 {
+				p.Rate = float64(offset-startOffset) / elapsed
+			}
+			if p.Rate > 0 {
+				p.ETA = time.Duration(float64(total-offset) / p.Rate * float64(time.Second))
+			}
+			opts.Progress(p)
+		}
+		if done != nil {
+			if opts.SessionFile != "" {
+				os.Remove(opts.SessionFile)
+			}
+			return done, nil
+		}
+	}
+}
+
+func (c *Client) startUpload(video *youtube.Video, total int64) (string, error) {
+	body, err := json.Marshal(video)
+	if err != nil {
+		return "", err
+	}
+	urls := googleapi.ResolveRelative(c.service.BasePath, "/upload/youtube/v3/videos") +
+		"?uploadType=resumable&part=snippet,status&alt=json"
+	req, err := http.NewRequest(http.MethodPost, urls, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	req.Header.Set("X-Upload-Content-Type", "video/*")
+	req.Header.Set("X-Upload-Content-Length", strconv.FormatInt(total, 10))
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if err := googleapi.CheckResponse(resp); err != nil {
+		return "", err
+	}
+	uri := resp.Header.Get("Location")
+	if uri == "" {
+		return "", errors.New("start upload: no session location returned")
+	}
+	return uri, nil
+}
+
+// queryUpload asks YouTube how much of a session it has received. A
+// non-nil video means the upload had already completed.
+func (c *Client) queryUpload(uri string, total int64) (int64, *youtube.Video, error) {
+	req, err := http.NewRequest(http.MethodPut, uri, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Range", fmt.Sprintf("bytes */%d", total))
+	return c.doChunk(req, total)
+}
+
+func (c *Client) putChunk(uri string, r io.Reader, offset, n, total int64) (int64, *youtube.Video, error) {
+	req, err := http.NewRequest(http.MethodPut, uri, r)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.ContentLength = n
+	if n == 0 {
+		req.Header.Set("Content-Range", fmt.Sprintf("bytes */%d", total))
+	} else {
+		req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+n-1, total))
+	}
+	return c.doChunk(req, offset+n)
+}
+
+// doChunk interprets a resumable upload response: 308 carries the range
+// received so far, 200/201 the finished video after end bytes.
+func (c *Client) doChunk(req *http.Request, end int64) (int64, *youtube.Video, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusPermanentRedirect:
+		next := int64(0)
+		if rng := resp.Header.Get("Range"); rng != "" {
+			last, err := strconv.ParseInt(rng[strings.LastIndex(rng, "-")+1:], 10, 64)
+			if err != nil {
+				return 0, nil, fmt.Errorf("bad upload range %q", rng)
+			}
+			next = last + 1
+		}
+		return next, nil, nil
+	case http.StatusOK, http.StatusCreated:
+		video := &youtube.Video{}
+		if err := json.NewDecoder(resp.Body).Decode(video); err != nil {
+			return 0, nil, err
+		}
+		return end, video, nil
+	case http.StatusNotFound, http.StatusGone:
+		return 0, nil, errSessionGone
+	}
+	return 0, nil, googleapi.CheckResponse(resp)
+}
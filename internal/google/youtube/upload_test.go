@@ -0,0 +1,115 @@
+package ytapi
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"twsati/internal/google/retry"
+)
+
+// resumableServer implements just enough of the resumable upload protocol
+// to accept a video, failing the chunk PUT number failAt once.
+type resumableServer struct {
+	received []byte
+	puts     int
+	failAt   int
+}
+
+func (s *resumableServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/upload/youtube/v3/videos":
+		w.Header().Set("Location", "http://"+r.Host+"/session/1")
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodPut && r.URL.Path == "/session/1":
+		body, _ := io.ReadAll(r.Body)
+		if len(body) > 0 {
+			s.puts++
+			if s.puts == s.failAt {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			s.received = append(s.received, body...)
+		}
+		var total int
+		fmt.Sscanf(r.Header.Get("Content-Range")[strings.Index(r.Header.Get("Content-Range"), "/")+1:], "%d", &total)
+		if len(s.received) < total {
+			if len(s.received) > 0 {
+				w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", len(s.received)-1))
+			}
+			w.WriteHeader(http.StatusPermanentRedirect)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"vid123"}`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newUploadFixture(t *testing.T, fake *resumableServer, policy retry.Policy) (*Client, []byte, string) {
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	c, err := NewClient(Options{HTTPClient: srv.Client(), Endpoint: srv.URL + "/", Retry: &policy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := bytes.Repeat([]byte("0123456789abcdef"), 3*chunkUnit/16+100)
+	videoPath := filepath.Join(t.TempDir(), "clip.mp4")
+	if err := os.WriteFile(videoPath, payload, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return c, payload, videoPath
+}
+
+func TestUploadVideoResumes(t *testing.T) {
+	fake := &resumableServer{failAt: 2}
+	// a single attempt makes the failed chunk end the run, as if the
+	// process had been killed
+	c, payload, videoPath := newUploadFixture(t, fake, retry.Policy{MaxAttempts: 1})
+	dir := t.TempDir()
+	opts := UploadOptions{ChunkSize: chunkUnit, SessionFile: filepath.Join(dir, "session.json")}
+
+	if _, err := c.UploadVideo("title", "desc", "27", "", videoPath, opts); err == nil {
+		t.Fatal("expected the first run to fail")
+	}
+	if _, err := os.Stat(opts.SessionFile); err != nil {
+		t.Fatalf("session not persisted: %v", err)
+	}
+
+	var last Progress
+	opts.Progress = func(p Progress) { last = p }
+	id, err := c.UploadVideo("title", "desc", "27", "", videoPath, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "vid123" {
+		t.Errorf("video id = %q", id)
+	}
+	if !bytes.Equal(fake.received, payload) {
+		t.Errorf("received %d bytes, want %d", len(fake.received), len(payload))
+	}
+	if last.Sent != last.Total || last.Total != int64(len(payload)) {
+		t.Errorf("last progress %+v", last)
+	}
+	if _, err := os.Stat(opts.SessionFile); !os.IsNotExist(err) {
+		t.Errorf("session file should be removed after completion")
+	}
+}
+
+func TestUploadVideoRetriesChunk(t *testing.T) {
+	fake := &resumableServer{failAt: 2}
+	c, payload, videoPath := newUploadFixture(t, fake, retry.Policy{MaxAttempts: 3, Sleep: func(time.Duration) {}})
+	if _, err := c.UploadVideo("title", "desc", "27", "", videoPath, UploadOptions{ChunkSize: chunkUnit}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(fake.received, payload) {
+		t.Errorf("received %d bytes, want %d", len(fake.received), len(payload))
+	}
+}
@@ -1,21 +1,23 @@
 package ytapi
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"log"
+	"io"
 	"net/http"
-	"net/url"
 	"os"
-	"os/user"
 	"path/filepath"
 	"strings"
-	"twsati/internal/sys"
+	"time"
+	"twsati/internal/google/apierr"
+	"twsati/internal/google/auth"
+	"twsati/internal/google/retry"
+	"twsati/internal/plan"
+	"twsati/internal/subtitle"
 
 	"golang.org/x/net/context"
-	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
 	"google.golang.org/api/youtube/v3"
 )
 
@@ -23,160 +25,152 @@ const missingClientSecretsMessage = `
 Please configure OAuth 2.0
 `
 
-var service *youtube.Service
-
-func init() {
-	client := getClient(youtube.YoutubeForceSslScope)
-	var err error
-	service, err = youtube.New(client)
-	handleError(err, "Error creating YouTube client")
+// Errors callers can test for with errors.Is; failed calls are returned
+// as *apierr.Error carrying one of them when the cause is recognised.
+var (
+	ErrQuotaExceeded = apierr.ErrQuotaExceeded
+	ErrRateLimited   = apierr.ErrRateLimited
+	ErrNotFound      = apierr.ErrNotFound
+	ErrForbidden     = apierr.ErrForbidden
+)
 
+// Options configures a Client. The zero value reads the OAuth client
+// secret and cached token from the user's home directory.
+type Options struct {
+	// HTTPClient is used as-is when set, skipping the OAuth setup.
+	HTTPClient *http.Client
+	// Endpoint overrides the YouTube API base URL, e.g. for a local fake.
+	Endpoint string
+	// ClientSecretFile defaults to ~/client_secret.json.
+	ClientSecretFile string
+	// TokenFile defaults to ~/.credentials/youtube-go-quickstart.json.
+	TokenFile string
+	// Retry defaults to retry.Default.
+	Retry *retry.Policy
+	// Plan, when set, makes every call that changes the channel record
+	// itself there instead; listing still reaches YouTube.
+	Plan *plan.Plan
 }
 
-// getClient uses a Context and Config to retrieve a Token
-// then generate a Client. It returns the generated Client.
-func getClient(scope string) *http.Client {
-	ctx := context.Background()
-	usr, err := user.Current()
-	sys.CheckErr(err)
+// Client wraps the YouTube service used to publish clips.
+type Client struct {
+	service    *youtube.Service
+	httpClient *http.Client
+	retry      retry.Policy
+	plan       *plan.Plan
+}
 
-	b, err := ioutil.ReadFile(filepath.Join(usr.HomeDir, "client_secret.json"))
-	if err != nil {
-		log.Fatalf("Unable to read client secret file: %v", err)
+// NewClient builds a YouTube client from opts.
+func NewClient(opts Options) (*Client, error) {
+	cli := opts.HTTPClient
+	if cli == nil {
+		var err error
+		cli, err = auth.Client(context.Background(), opts.authConfig())
+		if err != nil {
+			return nil, err
+		}
 	}
-
-	// If modifying these scopes, delete your previously saved credentials
-	// at ~/.credentials/youtube-go-quickstart.json
-	config, err := google.ConfigFromJSON(b, scope)
-	if err != nil {
-		log.Fatalf("Unable to parse client secret file to config: %v", err)
+	svcOpts := []option.ClientOption{option.WithHTTPClient(cli)}
+	if opts.Endpoint != "" {
+		svcOpts = append(svcOpts, option.WithEndpoint(opts.Endpoint))
 	}
-	cacheFile, err := tokenCacheFile()
+	service, err := youtube.NewService(context.Background(), svcOpts...)
 	if err != nil {
-		log.Fatalf("Unable to get path to cached credential file. %v", err)
+		return nil, err
 	}
-	tok, err := tokenFromFile(cacheFile)
-	if err != nil {
-		tok = getTokenFromWeb(config)
-		saveToken(cacheFile, tok)
+	policy := retry.Default
+	if opts.Retry != nil {
+		policy = *opts.Retry
 	}
-	return config.Client(ctx, tok)
+	return &Client{service: service, httpClient: cli, retry: policy, plan: opts.Plan}, nil
 }
 
-// getTokenFromWeb uses Config to request a Token.
-// It returns the retrieved Token.
-func getTokenFromWeb(config *oauth2.Config) *oauth2.Token {
-	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
-	fmt.Printf("Go to the following link in your browser then type the "+
-		"authorization code: \n%v\n", authURL)
-
-	var code string
-	if _, err := fmt.Scan(&code); err != nil {
-		log.Fatalf("Unable to read authorization code %v", err)
-	}
-
-	tok, err := config.Exchange(oauth2.NoContext, code)
-	if err != nil {
-		log.Fatalf("Unable to retrieve token from web %v", err)
-	}
-	return tok
+// do runs fn under the client's retry policy and wraps its error for op.
+func (c *Client) do(op string, fn func() error) error {
+	return c.retry.Do(op, func(int) error {
+		return apierr.Wrap(op, fn())
+	})
 }
 
-// tokenCacheFile generates credential file path/filename.
-// It returns the generated credential path/filename.
-func tokenCacheFile() (string, error) {
-	usr, err := user.Current()
-	if err != nil {
-		return "", err
-	}
-	tokenCacheDir := filepath.Join(usr.HomeDir, ".credentials")
-	os.MkdirAll(tokenCacheDir, 0700)
-	return filepath.Join(tokenCacheDir,
-		url.QueryEscape("youtube-go-quickstart.json")), err
+// Login runs the browser authorization and caches a fresh YouTube token.
+func Login(opts Options) error {
+	return auth.Login(context.Background(), opts.authConfig())
 }
 
-// tokenFromFile retrieves a Token from a given file path.
-// It returns the retrieved Token and any read error encountered.
-func tokenFromFile(file string) (*oauth2.Token, error) {
-	f, err := os.Open(file)
-	if err != nil {
-		return nil, err
-	}
-	t := &oauth2.Token{}
-	err = json.NewDecoder(f).Decode(t)
-	defer f.Close()
-	return t, err
+// Logout revokes and removes the cached YouTube token.
+func Logout(opts Options) error {
+	return auth.Logout(context.Background(), opts.authConfig())
 }
 
-// saveToken uses a file path to create a file and store the
-// token in it.
-func saveToken(file string, token *oauth2.Token) {
-	fmt.Printf("Saving credential file to: %s\n", file)
-	f, err := os.OpenFile(file, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
-	if err != nil {
-		log.Fatalf("Unable to cache oauth token: %v", err)
+func (opts Options) authConfig() auth.Config {
+	cfg := auth.Config{
+		ClientSecretFile: opts.ClientSecretFile,
+		TokenFile:        opts.TokenFile,
+		Scopes:           []string{youtube.YoutubeForceSslScope},
 	}
-	defer f.Close()
-	json.NewEncoder(f).Encode(token)
-}
-
-func handleError(err error, message string) {
-	if message == "" {
-		message = "Error making API call"
+	if cfg.ClientSecretFile == "" {
+		cfg.ClientSecretFile = auth.HomeFile("client_secret.json")
 	}
-	if err != nil {
-		log.Fatalf(message+": %v", err.Error())
+	// If modifying these scopes, delete your previously saved credentials
+	// at ~/.credentials/youtube-go-quickstart.json
+	if cfg.TokenFile == "" {
+		cfg.TokenFile = auth.HomeFile(".credentials", "youtube-go-quickstart.json")
 	}
+	return cfg
 }
 
-// func startWebServer() (codeCh chan string, err error) {
-// 	listener, err := net.Listen("tcp", "127.0.0.1:8099")
-// 	if err != nil {
-// 		log.Panic(err)
-// 	}
-// 	codeCh = make(chan string)
-
-// 	go http.Serve(listener, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-// 		code := r.FormValue("code")
-// 		codeCh <- code // send code to OAuth flow
-// 		listener.Close()
-// 		w.Header().Set("Content-Type", "text/plain")
-// 		fmt.Fprintf(w, "Received code: %v\r\nYou can now safely close this browser window.", code)
-// 	}))
-
-// 	return codeCh, nil
-// }
-
-func ChannelsListById(part string, id string) {
-	call := service.Channels.List(strings.Split(part, ","))
+func (c *Client) ChannelsListById(part string, id string) error {
+	call := c.service.Channels.List(strings.Split(part, ","))
 	call = call.Id(id)
-	response, err := call.Do()
-	handleError(err, "")
+	var response *youtube.ChannelListResponse
+	err := c.do("list channel "+id, func() (err error) {
+		response, err = call.Do()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if len(response.Items) == 0 {
+		return &apierr.Error{Op: "list channel " + id, Kind: ErrNotFound, Err: errors.New("no such channel")}
+	}
 	fmt.Println(fmt.Sprintf("This channel's ID is %s. Its title is '%s', "+
 		"and it has %d views.",
 		response.Items[0].Id,
 		response.Items[0].Snippet.Title,
 		response.Items[0].Statistics.ViewCount))
+	return nil
 }
 
-func ChannelsListByUsername(part string, forUsername string) {
-	call := service.Channels.List(strings.Split(part, ","))
+func (c *Client) ChannelsListByUsername(part string, forUsername string) error {
+	call := c.service.Channels.List(strings.Split(part, ","))
 	call = call.ForUsername(forUsername)
-	response, err := call.Do()
-	handleError(err, "")
+	var response *youtube.ChannelListResponse
+	err := c.do("list channel "+forUsername, func() (err error) {
+		response, err = call.Do()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if len(response.Items) == 0 {
+		return &apierr.Error{Op: "list channel " + forUsername, Kind: ErrNotFound, Err: errors.New("no such channel")}
+	}
 	fmt.Println(fmt.Sprintf("This channel's ID is %s. Its title is '%s', "+
 		"and it has %d views.",
 		response.Items[0].Id,
 		response.Items[0].Snippet.Title,
 		response.Items[0].Statistics.ViewCount))
+	return nil
 }
 
-func PlaylistsItemsAll(part string, playlistId string) []*youtube.PlaylistItem {
+func (c *Client) PlaylistsItemsAll(part string, playlistId string) ([]*youtube.PlaylistItem, error) {
 	pageToken := ""
-	resp := PlaylistsItems(part, playlistId, pageToken)
 	var retItems []*youtube.PlaylistItem
 	for {
-
+		resp, err := c.PlaylistsItems(part, playlistId, pageToken)
+		if err != nil {
+			return nil, err
+		}
 		retItems = append(retItems, resp.Items...)
 		// for _, item := range resp.Items {
 		// 	fmt.Println(item.Id, item.Snippet.Title, item.Snippet.Description)
@@ -185,20 +179,23 @@ func PlaylistsItemsAll(part string, playlistId string) []*youtube.PlaylistItem {
 		if pageToken == "" {
 			break
 		}
-		resp = PlaylistsItems(part, playlistId, pageToken)
 	}
-	return retItems
+	return retItems, nil
 
 }
 
-func PlaylistsItemDelete(itemId string) {
+func (c *Client) PlaylistsItemDelete(itemId string) error {
 
-	call := service.PlaylistItems.Delete(itemId)
-	err := call.Do()
-	handleError(err, "error making playlist delete call")
+	if c.plan.Record("youtube", "delete playlist item", itemId, "") {
+		return nil
+	}
+	call := c.service.PlaylistItems.Delete(itemId)
+	return c.do("playlist delete", func() error {
+		return call.Do()
+	})
 }
 
-func PlaylistsItemUpdate(itemId string, playlistId string, videoId string, position int64) *youtube.PlaylistItem {
+func (c *Client) PlaylistsItemUpdate(itemId string, playlistId string, videoId string, position int64) (*youtube.PlaylistItem, error) {
 
 	item := &youtube.PlaylistItem{
 		Id: itemId,
@@ -212,13 +209,19 @@ func PlaylistsItemUpdate(itemId string, playlistId string, videoId string, posit
 		},
 	}
 
-	call := service.PlaylistItems.Update([]string{"snippet"}, item)
-	resp, err := call.Do()
-	handleError(err, "error making playlist update call")
-	return resp
+	if c.plan.Record("youtube", "move playlist item", itemId, fmt.Sprintf("video %s to position %d", videoId, position)) {
+		return item, nil
+	}
+	call := c.service.PlaylistItems.Update([]string{"snippet"}, item)
+	var resp *youtube.PlaylistItem
+	err := c.do("playlist update", func() (err error) {
+		resp, err = call.Do()
+		return err
+	})
+	return resp, err
 }
 
-func PlaylistsItemInsert(playlistId string, videoId string, position int64) *youtube.PlaylistItem {
+func (c *Client) PlaylistsItemInsert(playlistId string, videoId string, position int64) (*youtube.PlaylistItem, error) {
 	item := &youtube.PlaylistItem{
 		Snippet: &youtube.PlaylistItemSnippet{
 			PlaylistId: playlistId,
@@ -230,14 +233,21 @@ func PlaylistsItemInsert(playlistId string, videoId string, position int64) *you
 		},
 	}
 
-	call := service.PlaylistItems.Insert([]string{"snippet"}, item)
-	resp, err := call.Do()
-	handleError(err, "error making playlist insert call")
-	return resp
+	if c.plan.Record("youtube", "insert playlist item", playlistId, fmt.Sprintf("video %s at position %d", videoId, position)) {
+		item.Id = plan.Placeholder
+		return item, nil
+	}
+	call := c.service.PlaylistItems.Insert([]string{"snippet"}, item)
+	var resp *youtube.PlaylistItem
+	err := c.do("playlist insert", func() (err error) {
+		resp, err = call.Do()
+		return err
+	})
+	return resp, err
 }
 
-func PlaylistsItems(part string, playlistId string, pageToken string) *youtube.PlaylistItemListResponse {
-	call := service.PlaylistItems.List([]string{part})
+func (c *Client) PlaylistsItems(part string, playlistId string, pageToken string) (*youtube.PlaylistItemListResponse, error) {
+	call := c.service.PlaylistItems.List([]string{part})
 	call.MaxResults(50)
 	if pageToken != "" {
 		call = call.PageToken(pageToken)
@@ -245,13 +255,16 @@ func PlaylistsItems(part string, playlistId string, pageToken string) *youtube.P
 	if playlistId != "" {
 		call = call.PlaylistId(playlistId)
 	}
-	response, err := call.Do()
-	handleError(err, "")
-	return response
+	var response *youtube.PlaylistItemListResponse
+	err := c.do("list playlist items", func() (err error) {
+		response, err = call.Do()
+		return err
+	})
+	return response, err
 }
 
-func PlaylistsList(part string, channelId string, maxResults int64) *youtube.PlaylistListResponse {
-	call := service.Playlists.List([]string{part})
+func (c *Client) PlaylistsList(part string, channelId string, maxResults int64) (*youtube.PlaylistListResponse, error) {
+	call := c.service.Playlists.List([]string{part})
 	if channelId != "" {
 		call = call.ChannelId(channelId)
 	} else {
@@ -270,47 +283,142 @@ func PlaylistsList(part string, channelId string, maxResults int64) *youtube.Pla
 	// if playlistId != "" {
 	// 	call = call.Id(playlistId)
 	// }
-	response, err := call.Do()
-	handleError(err, "")
-	return response
+	var response *youtube.PlaylistListResponse
+	err := c.do("list playlists", func() (err error) {
+		response, err = call.Do()
+		return err
+	})
+	return response, err
 }
 
-func UpdateVideo(videoId string, title string, description string, privacy string, keywords string) string {
+func (c *Client) UpdateVideo(videoId string, title string, description string, category string, privacy string, keywords string) (string, error) {
 
 	// privacy := "unlisted"
+	status := &youtube.VideoStatus{Embeddable: true, PrivacyStatus: privacy, SelfDeclaredMadeForKids: false, MadeForKids: false}
+	return c.updateVideo(videoId, title, description, category, keywords, status, "privacy "+privacy)
+}
+
+// ScheduleVideo updates the video like UpdateVideo and keeps it private
+// until YouTube publishes it at publishAt.
+func (c *Client) ScheduleVideo(videoId string, title string, description string, category string, keywords string, publishAt time.Time) (string, error) {
+	at := publishAt.UTC().Format(time.RFC3339)
+	status := &youtube.VideoStatus{Embeddable: true, PrivacyStatus: "private", PublishAt: at}
+	return c.updateVideo(videoId, title, description, category, keywords, status, "publish at "+at)
+}
+
+// UpdateSnippet replaces the title, description and tags of a video and
+// leaves its privacy and release time as they are.
+func (c *Client) UpdateSnippet(videoId string, title string, description string, category string, keywords string) (string, error) {
+	return c.updateVideo(videoId, title, description, category, keywords, nil, "status unchanged")
+}
+
+// updateVideo writes the snippet, and the status unless it is nil.
+func (c *Client) updateVideo(videoId string, title string, description string, category string, keywords string, status *youtube.VideoStatus, detail string) (string, error) {
 	update := &youtube.Video{
 		Id: videoId,
 		Snippet: &youtube.VideoSnippet{
 			Title:       title,
 			Description: description,
-			CategoryId:  "27",
+			CategoryId:  category,
 		},
-		Status: &youtube.VideoStatus{Embeddable: true, PrivacyStatus: privacy, SelfDeclaredMadeForKids: false, MadeForKids: false},
+		Status: status,
 	}
 	if strings.Trim(keywords, "") != "" {
 		update.Snippet.Tags = strings.Split(keywords, ",")
 	}
-	call := service.Videos.Update([]string{"snippet", "status"}, update)
-	response, err := call.Do()
-	handleError(err, "")
+	if c.plan.Record("youtube", "update video", videoId, fmt.Sprintf("title %q, %s", title, detail)) {
+		return videoId, nil
+	}
+	parts := []string{"snippet"}
+	if status != nil {
+		parts = append(parts, "status")
+	}
+	call := c.service.Videos.Update(parts, update)
+	var response *youtube.Video
+	err := c.do("update video "+videoId, func() (err error) {
+		response, err = call.Do()
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
 	fmt.Printf("Update successful! Video ID: %v\n", response)
-	return response.Id
+	return response.Id, nil
+}
+
+// GetVideo fetches the snippet and status of a video; a video that does
+// not exist (any more) is reported as ErrNotFound.
+func (c *Client) GetVideo(videoId string) (*youtube.Video, error) {
+	return c.getVideo(videoId, "snippet", "status")
 }
 
-func DeleteCaption(captionId string) {
-	call := service.Captions.Delete(captionId)
-	err := call.Do()
-	handleError(err, "error deleting caption Id: "+captionId)
+// VideoSnapshot fetches every part of a video a delete would lose,
+// including its statistics.
+func (c *Client) VideoSnapshot(videoId string) (*youtube.Video, error) {
+	return c.getVideo(videoId, "snippet", "status", "statistics", "contentDetails", "recordingDetails", "localizations")
 }
 
-func ListCaption(videoId string) *youtube.CaptionListResponse {
-	call := service.Captions.List([]string{"snippet"}, videoId)
-	resp, err := call.Do()
-	handleError(err, "error listing captions for video Id"+videoId)
-	return resp
+func (c *Client) getVideo(videoId string, parts ...string) (*youtube.Video, error) {
+	call := c.service.Videos.List(parts).Id(videoId)
+	var resp *youtube.VideoListResponse
+	err := c.do("get video "+videoId, func() (err error) {
+		resp, err = call.Do()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Items) == 0 {
+		return nil, fmt.Errorf("get video %s: %w", videoId, ErrNotFound)
+	}
+	return resp.Items[0], nil
 }
 
-func UploadCaption(captionId string, videoId string, lang string, name string, captionFilePath string) string {
+func (c *Client) DeleteCaption(captionId string) error {
+	if c.plan.Record("youtube", "delete caption", captionId, "") {
+		return nil
+	}
+	call := c.service.Captions.Delete(captionId)
+	return c.do("delete caption "+captionId, func() error {
+		return call.Do()
+	})
+}
+
+// DownloadCaption returns the caption track in SRT.
+func (c *Client) DownloadCaption(captionId string) ([]byte, error) {
+	call := c.service.Captions.Download(captionId).Tfmt("srt")
+	var data []byte
+	err := c.do("download caption "+captionId, func() error {
+		resp, err := call.Download()
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		data, err = io.ReadAll(resp.Body)
+		return err
+	})
+	return data, err
+}
+
+func (c *Client) ListCaption(videoId string) (*youtube.CaptionListResponse, error) {
+	call := c.service.Captions.List([]string{"snippet"}, videoId)
+	var resp *youtube.CaptionListResponse
+	err := c.do("list captions for video "+videoId, func() (err error) {
+		resp, err = call.Do()
+		return err
+	})
+	return resp, err
+}
+
+// UploadCaption inserts a caption track for videoId, or replaces the
+// track captionId, from a SubRip, WebVTT, SBV or TTML file; the format
+// follows the file extension.
+func (c *Client) UploadCaption(captionId string, videoId string, lang string, name string, captionFilePath string) (string, error) {
+	format, err := subtitle.FormatOf(captionFilePath)
+	if err != nil {
+		return "", err
+	}
+	media := googleapi.ContentType(format.ContentType())
 	upload := &youtube.Caption{
 		Snippet: &youtube.CaptionSnippet{
 			VideoId:  videoId,
@@ -319,39 +427,73 @@ func UploadCaption(captionId string, videoId string, lang string, name string, c
 		},
 	}
 
+	if len(strings.TrimSpace(captionId)) > 0 {
+		if c.plan.Record("youtube", "replace caption", captionId, fmt.Sprintf("%s %s from %s", lang, name, filepath.Base(captionFilePath))) {
+			return captionId, nil
+		}
+	} else if c.plan.Record("youtube", "insert caption", videoId, fmt.Sprintf("%s %s from %s", lang, name, filepath.Base(captionFilePath))) {
+		return plan.Placeholder, nil
+	}
+
 	file, err := os.Open(captionFilePath)
-	handleError(err, "can't open media file")
+	if err != nil {
+		return "", err
+	}
 	defer file.Close()
 
 	var response *youtube.Caption
-	if len(strings.TrimSpace(captionId)) > 0 {
-		upload.Id = captionId
-		call := service.Captions.Update([]string{"snippet"}, upload)
-		response, err = call.Media(file).Do()
-	} else {
-		call := service.Captions.Insert([]string{"snippet"}, upload)
-		response, err = call.Media(file).Do()
+	err = c.do("upload caption for video "+videoId, func() (err error) {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if len(strings.TrimSpace(captionId)) > 0 {
+			upload.Id = captionId
+			call := c.service.Captions.Update([]string{"snippet"}, upload)
+			response, err = call.Media(file, media).Do()
+		} else {
+			call := c.service.Captions.Insert([]string{"snippet"}, upload)
+			response, err = call.Media(file, media).Do()
+		}
+		return err
+	})
+	if err != nil {
+		return "", err
 	}
-
-	handleError(err, "")
 	fmt.Printf("Update successful! Caption ID: %v\n", response)
-	return response.Id
+	return response.Id, nil
 
 }
 
-func UploadCover(videoId string, filePath string) {
+func (c *Client) UploadCover(videoId string, filePath string) error {
 
-	call := service.Thumbnails.Set(videoId)
+	if c.plan.Record("youtube", "set thumbnail", videoId, filepath.Base(filePath)) {
+		return nil
+	}
+	call := c.service.Thumbnails.Set(videoId)
 	file, err := os.Open(filePath)
-	handleError(err, "can't open media file")
+	if err != nil {
+		return err
+	}
 	defer file.Close()
 
-	resp, err := call.Media(file).Do()
-	handleError(err, "error updating thumbnail")
-	println(resp.ServerResponse.Header)
+	var resp *youtube.ThumbnailSetResponse
+	err = c.do("set thumbnail for video "+videoId, func() (err error) {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		resp, err = call.Media(file).Do()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println("Thumbnail updated:", resp.ServerResponse.HTTPStatusCode)
+	return nil
 }
 
-func UploadVideo(title string, description string, category string, keywords string, filePath string) string {
+// UploadVideo uploads filePath as a new unlisted video in resumable
+// chunks and returns its id.
+func (c *Client) UploadVideo(title string, description string, category string, keywords string, filePath string, opts UploadOptions) (string, error) {
 
 	privacy := "unlisted"
 	upload := &youtube.Video{
@@ -365,57 +507,34 @@ func UploadVideo(title string, description string, category string, keywords str
 	if strings.Trim(keywords, "") != "" {
 		upload.Snippet.Tags = strings.Split(keywords, ",")
 	}
-	call := service.Videos.Insert([]string{"snippet", "status"}, upload)
+	if c.plan.Record("youtube", "upload video", title, filepath.Base(filePath)+", "+privacy) {
+		return plan.Placeholder, nil
+	}
 	file, err := os.Open(filePath)
-	handleError(err, "can't open media file")
+	if err != nil {
+		return "", err
+	}
 	defer file.Close()
-	response, err := call.Media(file).Do()
-	handleError(err, "")
+	response, err := c.resumableUpload(upload, file, opts)
+	if err != nil {
+		return "", err
+	}
 	fmt.Printf("Upload successful! Video ID: https://www.youtube.com/watch?v=%v\n", response.Id)
-	return response.Id
+	return response.Id, nil
 }
 
-func DeleteVideo(ytVideoId string) {
-
-	call := service.Videos.Delete(ytVideoId)
-	err := call.Do()
-	handleError(err, "")
-	fmt.Printf("Delete successful! Video ID: %v\n", ytVideoId)
-}
-
-func main() {
-	// ch, err := startWebServer()
-	// code := <-ch
-	// fmt.Println("code is: ", code)
-
-	ChannelsListByUsername("snippet,contentDetails,statistics", "GoogleDevelopers")
-
-	// method = flag.String("method", "list", "The API method to execute. (List is the only method that this sample currently supports.")
-
-	part := "snippet"
-	pageToken := ""
+func (c *Client) DeleteVideo(ytVideoId string) error {
 
-	// jstr, err := json.MarshalIndent(resp, "", "    ")
-	// if err != nil {
-	// 	log.Panic(err)
-	// }
-	// file, err := os.OpenFile("play.json", os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0660)
-	// if err != nil {
-	// 	log.Panic(err)
-	// }
-
-	// fmt.Fprint(file, string(jstr))
-
-	resp := PlaylistsItems(part, "PLNFPO1g9Ma2cXa_9pCD85rzY2ISS4koGf", pageToken)
-	for {
-		for _, item := range resp.Items {
-			fmt.Println(item.Id, item.Snippet.Title, item.Snippet.Description)
-		}
-		pageToken = resp.NextPageToken
-		if pageToken == "" {
-			break
-		}
-		resp = PlaylistsItems(part, "PLNFPO1g9Ma2cXa_9pCD85rzY2ISS4koGf", pageToken)
+	if c.plan.Record("youtube", "delete video", ytVideoId, "") {
+		return nil
 	}
-
+	call := c.service.Videos.Delete(ytVideoId)
+	err := c.do("delete video "+ytVideoId, func() error {
+		return call.Do()
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Delete successful! Video ID: %v\n", ytVideoId)
+	return nil
 }
@@ -3,7 +3,6 @@ package naming
 import (
 	"errors"
 	"fmt"
-	"log"
 	"regexp"
 	"strconv"
 	"strings"
@@ -12,6 +11,11 @@ import (
 	"unicode/utf8"
 )
 
+var (
+	ErrUnknownNameFormat = errors.New("unknown file name format")
+	ErrUnknownTimeFormat = errors.New("can't match video time")
+)
+
 type Info struct {
 	Date  time.Time
 	Title string
@@ -21,7 +25,7 @@ type Info struct {
 	Esec  int
 }
 
-func ProperName(name string, ext string) string {
+func ProperName(name string, ext string) (string, error) {
 	if !strings.ContainsAny(name, "()[]") {
 		rs := []rune(name)
 		first := ""
@@ -39,7 +43,10 @@ func ProperName(name string, ext string) string {
 		}
 
 	}
-	info := ExtractName2(name)
+	info, err := ExtractName2(name)
+	if err != nil {
+		return "", err
+	}
 	var bldr strings.Builder
 	dateStr := info.Date.Format("zh060102")
 	bldr.WriteString(dateStr)
@@ -47,7 +54,7 @@ func ProperName(name string, ext string) string {
 	bldr.WriteString(tstr)
 	bldr.WriteString(info.Title)
 	bldr.WriteString(ext)
-	return bldr.String()
+	return bldr.String(), nil
 
 }
 
@@ -58,7 +65,7 @@ func ArchiveMonth(tm time.Time) string {
 
 // 當父母生病時我們要如何做？- zh220731（07_40--14_20）)
 // 我們捫心自問修行是為了離苦還是快樂 - zh220813( 00_00--04_07)
-func ExtractName2(str string) Info {
+func ExtractName2(str string) (Info, error) {
 	str = strings.ReplaceAll(str, "（", "(")
 	str = strings.ReplaceAll(str, "）", ")")
 	ret := Info{}
@@ -88,58 +95,60 @@ func ExtractName2(str string) Info {
 		goto RETURN
 	}
 
-	log.Panic(errors.New("unknown file name format: " + str))
+	return ret, fmt.Errorf("%w: %s", ErrUnknownNameFormat, str)
 
 RETURN:
 	// fmt.Println("name is: ", str)
 	// fmt.Println("matches is: ", matches)
-	ret.Date = extractDate(dateStr)
+	ret.Date, err = extractDate(dateStr)
+	if err != nil {
+		return ret, fmt.Errorf("%w: %s: %v", ErrUnknownNameFormat, str, err)
+	}
 	ret.Title = titleStr
-	ret.Smin, ret.Ssec, ret.Emin, ret.Esec = extractTime(timeStr)
-	return ret
+	ret.Smin, ret.Ssec, ret.Emin, ret.Esec, err = extractTime(timeStr)
+	if err != nil {
+		return ret, fmt.Errorf("%w: %s", err, str)
+	}
+	return ret, nil
 }
 
-func extractDate(str string) time.Time {
+func extractDate(str string) (time.Time, error) {
 	tm, err := time.Parse(layout, str)
 	if err != nil {
 		tm, err = time.Parse(layout2, str)
-		if err != nil {
-			log.Panic(err)
-		}
 	}
-	return tm
+	return tm, err
 }
 
-func extractTime(str string) (int, int, int, int) {
+// extractTime only feeds digit groups to atoi, so conversions cannot fail
+func extractTime(str string) (int, int, int, int, error) {
+	atoi := func(s string) int {
+		n, _ := strconv.Atoi(s)
+		return n
+	}
 	matches, err := extract2(str, `\D*(\d+)(?:\D)+(\d+)(?:\D+)(\d+)(?:\D+)(\d+)(?:\D+)(\d+)(?:\D+)(\d+)`)
 	if err == nil {
-		return Atoi(matches[1])*60 + Atoi(matches[2]), Atoi(matches[3]), Atoi(matches[4])*60 + Atoi(matches[5]), Atoi(matches[6])
+		return atoi(matches[1])*60 + atoi(matches[2]), atoi(matches[3]), atoi(matches[4])*60 + atoi(matches[5]), atoi(matches[6]), nil
 	}
 
 	matches, err = extract2(str, `\D*(\d+)(?:\D)+(\d+)(?:\D+)(\d+)(?:\D+)(\d+)(?:\D+)(\d+)`)
 	if err == nil {
-		return Atoi(matches[1]), Atoi(matches[2]), Atoi(matches[3])*60 + Atoi(matches[4]), Atoi(matches[5])
+		return atoi(matches[1]), atoi(matches[2]), atoi(matches[3])*60 + atoi(matches[4]), atoi(matches[5]), nil
 	}
 
 	matches, err = extract2(str, `\D*(\d+)(?:\D)+(\d+)(?:\D+)(\d+)(?:\D+)(\d+)`)
 	if err == nil {
-		return Atoi(matches[1]), Atoi(matches[2]), Atoi(matches[3]), Atoi(matches[4])
+		return atoi(matches[1]), atoi(matches[2]), atoi(matches[3]), atoi(matches[4]), nil
 	}
 
-	log.Panic(errors.New("can't match video time"), str)
-
-	return 0, 0, 0, 0
+	return 0, 0, 0, 0, ErrUnknownTimeFormat
 }
 
 const layout = "zh060102"
 const layout2 = "zh2006.01.02"
 
-func Atoi(str string) int {
-	ret, err := strconv.Atoi(str)
-	if err != nil {
-		log.Fatal(err)
-	}
-	return ret
+func Atoi(str string) (int, error) {
+	return strconv.Atoi(str)
 }
 
 func extract2(str string, regex string) ([]string, error) {
@@ -0,0 +1,35 @@
+package naming
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExtractName2(t *testing.T) {
+	cases := []struct {
+		name                   string
+		title                  string
+		smin, ssec, emin, esec int
+	}{
+		{"zh230114_[37.34-38.51]_生命中別投降別氣餒", "生命中別投降別氣餒", 37, 34, 38, 51},
+		{"zh220813我們捫心自問( 1_00_00--1_04_07)", "我們捫心自問", 60, 0, 64, 7},
+	}
+	for _, c := range cases {
+		info, err := ExtractName2(c.name)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if info.Title != c.title || info.Smin != c.smin || info.Ssec != c.ssec || info.Emin != c.emin || info.Esec != c.esec {
+			t.Errorf("%s: got %+v", c.name, info)
+		}
+	}
+}
+
+func TestExtractName2Unknown(t *testing.T) {
+	if _, err := ExtractName2("not a clip"); !errors.Is(err, ErrUnknownNameFormat) {
+		t.Fatalf("expected ErrUnknownNameFormat, got %v", err)
+	}
+	if _, err := ExtractName2("zh230114_[37.34]_只有開始"); !errors.Is(err, ErrUnknownTimeFormat) {
+		t.Fatalf("expected ErrUnknownTimeFormat, got %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+// Package plan records the changes a dry run would make. Code that
+// mutates Drive, YouTube or the local filesystem asks its *Plan to
+// Record the change first and skips it when Record reports a dry run; a
+// nil *Plan records nothing, so normal runs pass nil.
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Placeholder stands in for ids the API would have assigned.
+const Placeholder = "(dry-run)"
+
+// Step is one recorded change.
+type Step struct {
+	Service string `json:"service"` // drive, youtube or fs
+	Op      string `json:"op"`
+	Target  string `json:"target"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+type Plan struct {
+	mu    sync.Mutex
+	steps []Step
+}
+
+func New() *Plan {
+	return &Plan{}
+}
+
+// Active reports whether changes are being recorded instead of made.
+func (p *Plan) Active() bool {
+	return p != nil
+}
+
+// Record adds a step and reports whether the caller must skip the change.
+func (p *Plan) Record(service, op, target, detail string) bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.steps = append(p.steps, Step{Service: service, Op: op, Target: target, Detail: detail})
+	return true
+}
+
+// Steps returns a copy of the recorded steps in order.
+func (p *Plan) Steps() []Step {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Step(nil), p.steps...)
+}
+
+// WriteText prints one numbered line per step.
+func (p *Plan) WriteText(w io.Writer) error {
+	steps := p.Steps()
+	if len(steps) == 0 {
+		_, err := fmt.Fprintln(w, "dry run: nothing to do")
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "dry run: %d change(s) planned\n", len(steps)); err != nil {
+		return err
+	}
+	for i, s := range steps {
+		line := fmt.Sprintf("%3d. [%s] %s %s", i+1, s.Service, s.Op, s.Target)
+		if s.Detail != "" {
+			line += " (" + s.Detail + ")"
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON prints the steps as a JSON array.
+func (p *Plan) WriteJSON(w io.Writer) error {
+	steps := p.Steps()
+	if steps == nil {
+		steps = []Step{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(steps)
+}
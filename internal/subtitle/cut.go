@@ -0,0 +1,29 @@
+package subtitle
+
+import "time"
+
+// Cut returns the part of cues between start and end with times relative
+// to start, numbered from 1. Cues running over either edge are trimmed to
+// it; cues outside, or left without any length, are dropped.
+func Cut(cues []Cue, start, end time.Duration) []Cue {
+	var clip []Cue
+	for _, c := range cues {
+		if c.End <= start || c.Start >= end {
+			continue
+		}
+		if c.Start < start {
+			c.Start = start
+		}
+		if c.End > end {
+			c.End = end
+		}
+		if c.End <= c.Start {
+			continue
+		}
+		c.Start -= start
+		c.End -= start
+		c.Index = len(clip) + 1
+		clip = append(clip, c)
+	}
+	return clip
+}
@@ -0,0 +1,30 @@
+package subtitle
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCut(t *testing.T) {
+	cues := []Cue{
+		{Index: 1, Start: ms(1000), End: ms(4000), Lines: []string{"之前"}},
+		{Index: 2, Start: ms(9000), End: ms(11000), Lines: []string{"跨過開頭"}},
+		{Index: 3, Start: ms(12000), End: ms(13000), Lines: []string{"中間", "兩行"}},
+		{Index: 4, Start: ms(19500), End: ms(21000), Lines: []string{"跨過結尾"}},
+		{Index: 5, Start: ms(20000), End: ms(22000), Lines: []string{"之後"}},
+	}
+	want := []Cue{
+		{Index: 1, Start: 0, End: ms(1000), Lines: []string{"跨過開頭"}},
+		{Index: 2, Start: ms(2000), End: ms(3000), Lines: []string{"中間", "兩行"}},
+		{Index: 3, Start: ms(9500), End: ms(10000), Lines: []string{"跨過結尾"}},
+	}
+	if got := Cut(cues, ms(10000), ms(20000)); !reflect.DeepEqual(got, want) {
+		t.Errorf("Cut = %+v", got)
+	}
+	if cues[1].Start != ms(9000) {
+		t.Error("Cut changed its input")
+	}
+	if got := Cut(cues, ms(30000), ms(40000)); len(got) != 0 {
+		t.Errorf("Cut past the end = %+v", got)
+	}
+}
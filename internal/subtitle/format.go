@@ -0,0 +1,98 @@
+package subtitle
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Format is a caption file format, named by its file extension without
+// the dot.
+type Format string
+
+const (
+	SRT  Format = "srt"
+	VTT  Format = "vtt"
+	SBV  Format = "sbv"
+	TTML Format = "ttml"
+)
+
+// Formats lists every format, SubRip first.
+var Formats = []Format{SRT, VTT, SBV, TTML}
+
+var ErrUnknownFormat = errors.New("unknown caption format")
+
+// Ext is the file extension of the format, with the dot.
+func (f Format) Ext() string {
+	return "." + string(f)
+}
+
+// ContentType is the MIME type of the format.
+func (f Format) ContentType() string {
+	switch f {
+	case SRT:
+		return "application/x-subrip"
+	case VTT:
+		return "text/vtt"
+	case TTML:
+		return "application/ttml+xml"
+	}
+	return "text/plain"
+}
+
+// ParseFormat reads a format name such as vtt or .vtt.
+func ParseFormat(name string) (Format, error) {
+	name = strings.ToLower(strings.TrimPrefix(name, "."))
+	for _, f := range Formats {
+		if string(f) == name {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownFormat, name)
+}
+
+// FormatOf tells the format of a caption file from its extension.
+func FormatOf(path string) (Format, error) {
+	return ParseFormat(filepath.Ext(path))
+}
+
+// Exts lists the file extensions of every format.
+func Exts() []string {
+	exts := make([]string, len(Formats))
+	for i, f := range Formats {
+		exts[i] = f.Ext()
+	}
+	return exts
+}
+
+// Parse reads cues in format f.
+func Parse(f Format, r io.Reader) ([]Cue, error) {
+	switch f {
+	case SRT:
+		return ParseSRT(r)
+	case VTT:
+		return ParseVTT(r)
+	case SBV:
+		return ParseSBV(r)
+	case TTML:
+		return ParseTTML(r)
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, f)
+}
+
+// Write writes cues in format f.
+func Write(f Format, w io.Writer, cues []Cue) error {
+	switch f {
+	case SRT:
+		return WriteSRT(w, cues)
+	case VTT:
+		return WriteVTT(w, cues)
+	case SBV:
+		return WriteSBV(w, cues)
+	case TTML:
+		return WriteTTML(w, cues)
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownFormat, f)
+}
@@ -0,0 +1,114 @@
+package subtitle
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var sample = []Cue{
+	{Index: 1, Start: ms(1000), End: ms(2500), Lines: []string{"你好"}},
+	{Index: 2, Start: ms(3000), End: ms(5000), Lines: []string{"生命中別投降", "別氣餒 & <笑>"}},
+	{Index: 3, Start: time.Hour + 2*time.Minute + ms(3004), End: time.Hour + 2*time.Minute + ms(4000), Lines: []string{"完"}},
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	for _, f := range Formats {
+		var out bytes.Buffer
+		if err := Write(f, &out, sample); err != nil {
+			t.Fatal(err)
+		}
+		cues, err := Parse(f, bytes.NewReader(out.Bytes()))
+		if err != nil {
+			t.Fatalf("%s: %v in\n%s", f, err, out.String())
+		}
+		if !reflect.DeepEqual(cues, sample) {
+			t.Errorf("%s: cues = %+v from\n%s", f, cues, out.String())
+		}
+	}
+}
+
+func TestParseVTT(t *testing.T) {
+	in := "WEBVTT - 直播開示\r\nKind: captions\r\n\r\n" +
+		"NOTE 這段是註解\r\n不是字幕\r\n\r\n" +
+		"STYLE\r\n::cue { color: yellow }\r\n\r\n" +
+		"intro\r\n00:01.500 --> 00:02.000 align:start position:10%\r\n你好\r\n\r\n" +
+		"00:00:03.000 --> 00:00:04.000\r\n第一行\r\n第二行\r\n"
+	cues, err := ParseVTT(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Cue{
+		{Index: 1, Start: ms(1500), End: ms(2000), Lines: []string{"你好"}},
+		{Index: 2, Start: ms(3000), End: ms(4000), Lines: []string{"第一行", "第二行"}},
+	}
+	if !reflect.DeepEqual(cues, want) {
+		t.Errorf("cues = %+v", cues)
+	}
+	if _, err := ParseVTT(strings.NewReader("1\n00:00:01.000 --> 00:00:02.000\n你好\n")); !errors.Is(err, ErrSyntax) {
+		t.Errorf("no header: %v", err)
+	}
+}
+
+func TestParseSBV(t *testing.T) {
+	in := "0:00:01.500,0:00:02.000\n你好\n\n\n0:00:03.000,0:00:04.000\n第一行\n第二行"
+	cues, err := ParseSBV(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Cue{
+		{Index: 1, Start: ms(1500), End: ms(2000), Lines: []string{"你好"}},
+		{Index: 2, Start: ms(3000), End: ms(4000), Lines: []string{"第一行", "第二行"}},
+	}
+	if !reflect.DeepEqual(cues, want) {
+		t.Errorf("cues = %+v", cues)
+	}
+	if _, err := ParseSBV(strings.NewReader("0:00:01.500 --> 0:00:02.000\n你好\n")); !errors.Is(err, ErrSyntax) {
+		t.Errorf("SRT timing: %v", err)
+	}
+}
+
+func TestParseTTML(t *testing.T) {
+	in := `<?xml version="1.0" encoding="utf-8"?>
+<tt xmlns="http://www.w3.org/ns/ttml" xmlns:tts="http://www.w3.org/ns/ttml#styling" xml:lang="zh-TW">
+  <body>
+    <div>
+      <p begin="1.5s" dur="500ms">你好</p>
+      <p begin="00:00:03.000" end="00:00:04:15">
+        第一行<br/>
+        <span tts:fontStyle="italic">第二</span>行
+      </p>
+      <p begin="5s" end="6s"/>
+    </div>
+  </body>
+</tt>`
+	cues, err := ParseTTML(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Cue{
+		{Index: 1, Start: ms(1500), End: ms(2000), Lines: []string{"你好"}},
+		{Index: 2, Start: ms(3000), End: ms(4500), Lines: []string{"第一行", "第二行"}},
+		{Index: 3, Start: ms(5000), End: ms(6000)},
+	}
+	if !reflect.DeepEqual(cues, want) {
+		t.Errorf("cues = %+v", cues)
+	}
+	if _, err := ParseTTML(strings.NewReader(`<tt><body><p end="1s">你好</p></body></tt>`)); !errors.Is(err, ErrSyntax) {
+		t.Errorf("no begin: %v", err)
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	for path, want := range map[string]Format{"a/b.srt": SRT, "c.VTT": VTT, "d.sbv": SBV, "e.ttml": TTML} {
+		if got, err := FormatOf(path); err != nil || got != want {
+			t.Errorf("FormatOf(%q) = %q, %v", path, got, err)
+		}
+	}
+	if _, err := FormatOf("f.txt"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("FormatOf(.txt) = %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package subtitle
+
+import (
+	"fmt"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Severity tells whether an issue should stop a caption from being
+// uploaded.
+type Severity string
+
+const (
+	Error   Severity = "error"
+	Warning Severity = "warning"
+)
+
+// Issue is one problem Lint found. Cue is the 1-based position of the
+// cue in the file, 0 for the file as a whole.
+type Issue struct {
+	Severity Severity `json:"severity"`
+	Cue      int      `json:"cue"`
+	Check    string   `json:"check"`
+	Message  string   `json:"message"`
+}
+
+func (i Issue) String() string {
+	if i.Cue == 0 {
+		return fmt.Sprintf("%s: %s: %s", i.Severity, i.Check, i.Message)
+	}
+	return fmt.Sprintf("%s: cue %d: %s: %s", i.Severity, i.Cue, i.Check, i.Message)
+}
+
+// LintOptions are the limits Lint checks against; a zero limit is not
+// checked.
+type LintOptions struct {
+	// MaxCPS is the most characters per second a cue may ask viewers to
+	// read. Only letters and digits count, so a CJK cue is measured by
+	// its characters and punctuation and spaces are free.
+	MaxCPS float64
+	// MaxLineLength is the longest line in characters.
+	MaxLineLength int
+	// MaxGap is the longest silence between two cues.
+	MaxGap time.Duration
+	// ClipLength is the length of the video; no cue may end after it.
+	ClipLength time.Duration
+}
+
+// clipSlack is how far past ClipLength the last cue may run: the clip
+// times are only known to the second.
+const clipSlack = time.Second
+
+// Lint checks cues for the mistakes that show on YouTube. Ordering,
+// overlaps, cues that end before they start and cues past the end of the
+// clip are errors; empty cues, fast cues, long lines and long gaps are
+// warnings.
+func Lint(cues []Cue, opts LintOptions) []Issue {
+	var issues []Issue
+	add := func(sev Severity, cue int, check, format string, args ...interface{}) {
+		issues = append(issues, Issue{Severity: sev, Cue: cue, Check: check, Message: fmt.Sprintf(format, args...)})
+	}
+	if len(cues) == 0 {
+		add(Warning, 0, "empty", "the file has no cues")
+		return issues
+	}
+	for i, c := range cues {
+		n := i + 1
+		length := c.End - c.Start
+		if length <= 0 {
+			add(Error, n, "duration", "%s --> %s lasts %v", FormatTimestamp(c.Start), FormatTimestamp(c.End), length)
+		}
+		if i > 0 {
+			prev := cues[i-1]
+			switch {
+			case c.Start < prev.Start:
+				add(Error, n, "order", "starts at %s, before cue %d at %s", FormatTimestamp(c.Start), n-1, FormatTimestamp(prev.Start))
+			case c.Start < prev.End:
+				add(Error, n, "overlap", "starts at %s, before cue %d ends at %s", FormatTimestamp(c.Start), n-1, FormatTimestamp(prev.End))
+			case opts.MaxGap > 0 && c.Start-prev.End > opts.MaxGap:
+				add(Warning, n, "gap", "%v without captions after cue %d", c.Start-prev.End, n-1)
+			}
+		}
+		if opts.ClipLength > 0 && c.End > opts.ClipLength+clipSlack {
+			add(Error, n, "clip end", "ends at %s, after the clip ends at %s", FormatTimestamp(c.End), FormatTimestamp(opts.ClipLength))
+		}
+		chars := 0
+		for _, line := range c.Lines {
+			chars += readable(line)
+			if opts.MaxLineLength > 0 && utf8.RuneCountInString(line) > opts.MaxLineLength {
+				add(Warning, n, "line length", "%q is %d characters, more than %d", line, utf8.RuneCountInString(line), opts.MaxLineLength)
+			}
+		}
+		if chars == 0 {
+			add(Warning, n, "empty", "the cue has no text")
+			continue
+		}
+		if opts.MaxCPS > 0 && length > 0 {
+			if cps := float64(chars) / length.Seconds(); cps > opts.MaxCPS {
+				add(Warning, n, "speed", "%d characters in %v is %.1f per second, more than %g", chars, length, cps, opts.MaxCPS)
+			}
+		}
+	}
+	return issues
+}
+
+// readable counts the characters of s a viewer has to read.
+func readable(s string) int {
+	n := 0
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			n++
+		}
+	}
+	return n
+}
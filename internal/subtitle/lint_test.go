@@ -0,0 +1,69 @@
+package subtitle
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLint(t *testing.T) {
+	in := `1
+00:00:01,000 --> 00:00:03,000
+你好
+
+2
+00:00:02,500 --> 00:00:04,000
+重疊了
+
+3
+00:00:05,000 --> 00:00:04,000
+倒過來
+
+4
+00:00:04,500 --> 00:00:05,000
+一秒說不完這麼多字吧
+
+5
+00:00:30,000 --> 00:00:31,000
+，
+
+6
+00:00:31,000 --> 00:00:33,000
+這一行實在是太長了不好讀
+超過片尾
+`
+	cues, err := ParseSRT(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues := Lint(cues, LintOptions{MaxCPS: 9, MaxLineLength: 10, MaxGap: 10 * time.Second, ClipLength: 31 * time.Second})
+	var got []string
+	for _, i := range issues {
+		got = append(got, string(i.Severity)+" "+i.Check)
+		if i.Cue < 1 || i.Cue > len(cues) || i.Message == "" {
+			t.Errorf("issue %+v", i)
+		}
+	}
+	want := []string{
+		"error overlap",
+		"error duration",
+		"error order",
+		"warning speed",
+		"warning gap",
+		"warning empty",
+		"error clip end",
+		"warning line length",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("issues:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	clean := []Cue{
+		{Start: ms(0), End: ms(2000), Lines: []string{"生命中別投降，"}},
+		{Start: ms(2000), End: ms(3000), Lines: []string{"別氣餒"}},
+	}
+	if issues := Lint(clean, LintOptions{MaxCPS: 9, MaxLineLength: 10, MaxGap: time.Second, ClipLength: 2 * time.Second}); len(issues) != 0 {
+		t.Errorf("clean cues: %v", issues)
+	}
+}
@@ -0,0 +1,77 @@
+package subtitle
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// FormatSBVTimestamp writes d as a YouTube SBV timestamp, 0:01:02.345.
+func FormatSBVTimestamp(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+func parseSBVTiming(line string) (start, end time.Duration, ok bool) {
+	parts := strings.Split(strings.TrimSpace(line), ",")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	start, err := ParseTimestamp(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	end, err = ParseTimestamp(parts[1])
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, end, true
+}
+
+// ParseSBV reads the SubViewer format YouTube exports: a "start,end"
+// line followed by the text lines of the cue, cues separated by blank
+// lines.
+func ParseSBV(r io.Reader) ([]Cue, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	lines := splitLines(string(content))
+	var cues []Cue
+	for i := 0; i < len(lines); {
+		if isBlank(lines[i]) {
+			i++
+			continue
+		}
+		cue := Cue{Index: len(cues) + 1}
+		var ok bool
+		if cue.Start, cue.End, ok = parseSBVTiming(lines[i]); !ok {
+			return nil, syntaxError(i+1, "expected a cue timing, got %q", lines[i])
+		}
+		for i++; i < len(lines) && !isBlank(lines[i]); i++ {
+			cue.Lines = append(cue.Lines, strings.TrimRight(lines[i], " \t"))
+		}
+		cues = append(cues, cue)
+	}
+	return cues, nil
+}
+
+// WriteSBV writes cues as SBV.
+func WriteSBV(w io.Writer, cues []Cue) error {
+	bw := bufio.NewWriter(w)
+	for i, c := range cues {
+		if i > 0 {
+			bw.WriteString("\n")
+		}
+		fmt.Fprintf(bw, "%s,%s\n", FormatSBVTimestamp(c.Start), FormatSBVTimestamp(c.End))
+		for _, line := range c.Lines {
+			fmt.Fprintf(bw, "%s\n", line)
+		}
+	}
+	return bw.Flush()
+}
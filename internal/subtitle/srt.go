@@ -0,0 +1,143 @@
+package subtitle
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// srtTimestamp matches 00:01:02,345 as well as the variants seen in the
+// wild: a dot before the milliseconds, fewer millisecond digits and no
+// hours.
+var srtTimestamp = regexp.MustCompile(`^(?:(\d+):)?(\d{1,2}):(\d{1,2})(?:[,.](\d{1,3}))?$`)
+
+// ParseTimestamp reads an SRT timestamp.
+func ParseTimestamp(s string) (time.Duration, error) {
+	m := srtTimestamp.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, fmt.Errorf("%w: timestamp %q", ErrSyntax, s)
+	}
+	// the pattern only captures digits
+	h, _ := strconv.Atoi("0" + m[1])
+	min, _ := strconv.Atoi(m[2])
+	sec, _ := strconv.Atoi(m[3])
+	ms := 0
+	if m[4] != "" {
+		// ,5 is half a second
+		ms, _ = strconv.Atoi((m[4] + "00")[:3])
+	}
+	if min > 59 || sec > 59 {
+		return 0, fmt.Errorf("%w: timestamp %q", ErrSyntax, s)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(min)*time.Minute +
+		time.Duration(sec)*time.Second + time.Duration(ms)*time.Millisecond, nil
+}
+
+// FormatTimestamp writes d as an SRT timestamp, 00:01:02,345.
+func FormatTimestamp(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// parseTiming reads "start --> end", ignoring any position settings after
+// the end time.
+func parseTiming(line string) (start, end time.Duration, ok bool) {
+	parts := strings.SplitN(line, "-->", 2)
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	fields := strings.Fields(parts[1])
+	if len(fields) == 0 {
+		return 0, 0, false
+	}
+	start, err := ParseTimestamp(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	end, err = ParseTimestamp(fields[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, end, true
+}
+
+func isIndex(line string) bool {
+	_, err := strconv.Atoi(strings.TrimSpace(line))
+	return err == nil
+}
+
+// ParseSRT reads SubRip cues. It accepts a byte order mark, any line
+// endings, cues with several or no text lines, missing or repeated blank
+// lines between cues and cues without an index. Cue timings are not
+// checked; see Lint.
+func ParseSRT(r io.Reader) ([]Cue, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	lines := splitLines(string(content))
+	// startsCue reports whether a cue begins at lines[i], and at which line
+	// its timing is
+	startsCue := func(i int) (int, bool) {
+		if i >= len(lines) {
+			return 0, false
+		}
+		if _, _, ok := parseTiming(lines[i]); ok {
+			return i, true
+		}
+		if i+1 < len(lines) && isIndex(lines[i]) {
+			if _, _, ok := parseTiming(lines[i+1]); ok {
+				return i + 1, true
+			}
+		}
+		return 0, false
+	}
+
+	var cues []Cue
+	for i := 0; i < len(lines); {
+		if isBlank(lines[i]) {
+			i++
+			continue
+		}
+		timing, ok := startsCue(i)
+		if !ok {
+			return nil, syntaxError(i+1, "expected a cue number or timing, got %q", lines[i])
+		}
+		cue := Cue{Index: len(cues) + 1}
+		if timing > i {
+			cue.Index, _ = strconv.Atoi(strings.TrimSpace(lines[i]))
+		}
+		cue.Start, cue.End, _ = parseTiming(lines[timing])
+		i = timing + 1
+		for i < len(lines) && !isBlank(lines[i]) {
+			if _, next := startsCue(i); next {
+				break
+			}
+			cue.Lines = append(cue.Lines, strings.TrimRight(lines[i], " \t"))
+			i++
+		}
+		cues = append(cues, cue)
+	}
+	return cues, nil
+}
+
+// WriteSRT writes cues in canonical SubRip: numbered from 1, with comma
+// milliseconds, \n line endings and one blank line after every cue.
+func WriteSRT(w io.Writer, cues []Cue) error {
+	bw := bufio.NewWriter(w)
+	for i, c := range cues {
+		fmt.Fprintf(bw, "%d\n%s --> %s\n", i+1, FormatTimestamp(c.Start), FormatTimestamp(c.End))
+		for _, line := range c.Lines {
+			fmt.Fprintf(bw, "%s\n", line)
+		}
+		bw.WriteString("\n")
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,101 @@
+package subtitle
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func ms(n int) time.Duration {
+	return time.Duration(n) * time.Millisecond
+}
+
+const canonical = `1
+00:00:01,000 --> 00:00:02,500
+你好
+
+2
+00:00:03,000 --> 00:00:05,000
+生命中別投降
+別氣餒
+
+3
+01:02:03,004 --> 01:02:04,000
+
+`
+
+func TestSRTRoundTrip(t *testing.T) {
+	cues, err := ParseSRT(strings.NewReader(canonical))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Cue{
+		{Index: 1, Start: ms(1000), End: ms(2500), Lines: []string{"你好"}},
+		{Index: 2, Start: ms(3000), End: ms(5000), Lines: []string{"生命中別投降", "別氣餒"}},
+		{Index: 3, Start: time.Hour + 2*time.Minute + ms(3004), End: time.Hour + 2*time.Minute + ms(4000)},
+	}
+	if !reflect.DeepEqual(cues, want) {
+		t.Fatalf("cues = %+v", cues)
+	}
+	var out bytes.Buffer
+	if err := WriteSRT(&out, cues); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != canonical {
+		t.Errorf("written:\n%q\nwant:\n%q", out.String(), canonical)
+	}
+}
+
+func TestParseSRTTolerant(t *testing.T) {
+	// a byte order mark, CRLF, extra blank lines, a dot before the
+	// milliseconds, position settings, a cue without an index and a
+	// missing blank line between cues
+	in := "\uFEFF1\r\n00:00:01.5 --> 00:00:02,000 X1:10 X2:20\r\n你好  \r\n\r\n\r\n\r\n" +
+		"00:00:03,000 --> 00:00:04,000\r\n沒有編號\r\n" +
+		"7\r\n00:00:05,000 --> 00:00:06,000\r\n第一行\r\n100\r\n\r\n"
+	cues, err := ParseSRT(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Cue{
+		{Index: 1, Start: ms(1500), End: ms(2000), Lines: []string{"你好"}},
+		{Index: 2, Start: ms(3000), End: ms(4000), Lines: []string{"沒有編號"}},
+		{Index: 7, Start: ms(5000), End: ms(6000), Lines: []string{"第一行", "100"}},
+	}
+	if !reflect.DeepEqual(cues, want) {
+		t.Errorf("cues = %+v", cues)
+	}
+}
+
+func TestParseSRTErrors(t *testing.T) {
+	cases := []struct{ in, line string }{
+		{"前言\n1\n00:00:01,000 --> 00:00:02,000\n你好\n", "line 1:"},
+		{"1\n00:00:01,000 --> 00:00:02,000\n你好\n\n不是字幕\n", "line 5:"},
+		{"1\n00:00:01,000 --> 00:61:00,000\n你好\n", "line 1:"},
+	}
+	for _, c := range cases {
+		_, err := ParseSRT(strings.NewReader(c.in))
+		if !errors.Is(err, ErrSyntax) || !strings.HasPrefix(err.Error(), c.line) {
+			t.Errorf("ParseSRT(%q) = %v, want a syntax error at %s", c.in, err, c.line)
+		}
+	}
+}
+
+func TestTimestamp(t *testing.T) {
+	for in, want := range map[string]time.Duration{
+		"00:00:01,000": ms(1000),
+		"1:02:03,4":    time.Hour + 2*time.Minute + ms(3400),
+		"02:03.045":    2*time.Minute + ms(3045),
+		"00:00:07":     ms(7000),
+	} {
+		if got, err := ParseTimestamp(in); err != nil || got != want {
+			t.Errorf("ParseTimestamp(%q) = %v, %v, want %v", in, got, err, want)
+		}
+	}
+	if got := FormatTimestamp(time.Hour + ms(61001)); got != "01:01:01,001" {
+		t.Errorf("FormatTimestamp = %q", got)
+	}
+}
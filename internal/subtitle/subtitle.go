@@ -0,0 +1,44 @@
+// Package subtitle reads and writes caption files as a list of typed
+// cues.
+package subtitle
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrSyntax is wrapped by every parse error.
+var ErrSyntax = errors.New("subtitle syntax error")
+
+// Cue is one timed caption.
+type Cue struct {
+	// Index is the cue number read from the file; writers renumber cues
+	// from 1.
+	Index      int
+	Start, End time.Duration
+	Lines      []string
+}
+
+// Text joins the lines of the cue.
+func (c Cue) Text() string {
+	return strings.Join(c.Lines, "\n")
+}
+
+func syntaxError(line int, format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %w: %s", line, ErrSyntax, fmt.Sprintf(format, args...))
+}
+
+// splitLines drops a UTF-8 byte order mark and splits on any of \r\n, \n
+// and \r.
+func splitLines(content string) []string {
+	content = strings.TrimPrefix(content, "\uFEFF")
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	content = strings.ReplaceAll(content, "\r", "\n")
+	return strings.Split(content, "\n")
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
@@ -0,0 +1,156 @@
+package subtitle
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ttmlClock matches the clock times of TTML, 00:01:02.345 or with frames
+// as 00:01:02:10, and ttmlOffset the offset times such as 1.5s or 200ms.
+var (
+	ttmlClock  = regexp.MustCompile(`^(\d+):(\d{2}):(\d{2})(?:\.(\d+)|:(\d+))?$`)
+	ttmlOffset = regexp.MustCompile(`^(\d+(?:\.\d+)?)(h|m|s|ms|f|t)$`)
+	ttmlSpace  = regexp.MustCompile(`\s+`)
+)
+
+// ttmlFrameRate is the frame rate TTML assumes when the document does not
+// give one.
+const ttmlFrameRate = 30
+
+func parseTTMLTime(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if m := ttmlClock.FindStringSubmatch(s); m != nil {
+		h, _ := strconv.Atoi(m[1])
+		min, _ := strconv.Atoi(m[2])
+		sec, _ := strconv.Atoi(m[3])
+		d := time.Duration(h)*time.Hour + time.Duration(min)*time.Minute + time.Duration(sec)*time.Second
+		if m[4] != "" {
+			frac, _ := strconv.ParseFloat("0."+m[4], 64)
+			d += time.Duration(frac * float64(time.Second))
+		} else if m[5] != "" {
+			frames, _ := strconv.Atoi(m[5])
+			d += time.Duration(frames) * time.Second / ttmlFrameRate
+		}
+		return d.Round(time.Millisecond), nil
+	}
+	if m := ttmlOffset.FindStringSubmatch(s); m != nil {
+		n, _ := strconv.ParseFloat(m[1], 64)
+		unit := map[string]float64{
+			"h": float64(time.Hour), "m": float64(time.Minute), "s": float64(time.Second),
+			"ms": float64(time.Millisecond), "f": float64(time.Second) / ttmlFrameRate,
+			// TTML ticks default to one per millisecond
+			"t": float64(time.Millisecond),
+		}[m[2]]
+		return time.Duration(n * unit).Round(time.Millisecond), nil
+	}
+	return 0, fmt.Errorf("%w: time %q", ErrSyntax, s)
+}
+
+// ParseTTML reads the <p> elements of a TTML document as cues, each with
+// begin and end or dur. <br/> breaks lines; the text of <span> and other
+// inline elements is kept without their styling. Timing on enclosing
+// <div> and <body> elements is not applied.
+func ParseTTML(r io.Reader) ([]Cue, error) {
+	dec := xml.NewDecoder(r)
+	var cues []Cue
+	var cue *Cue
+	var line strings.Builder
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSyntax, err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch {
+			case t.Name.Local == "p":
+				c, err := ttmlCue(t, len(cues)+1)
+				if err != nil {
+					return nil, err
+				}
+				cue = &c
+				line.Reset()
+			case t.Name.Local == "br" && cue != nil:
+				cue.Lines = append(cue.Lines, strings.TrimSpace(line.String()))
+				line.Reset()
+			}
+		case xml.CharData:
+			if cue != nil {
+				// newlines in the document are layout, not line breaks
+				line.WriteString(ttmlSpace.ReplaceAllString(string(t), " "))
+			}
+		case xml.EndElement:
+			if t.Name.Local == "p" && cue != nil {
+				if text := strings.TrimSpace(line.String()); text != "" || len(cue.Lines) > 0 {
+					cue.Lines = append(cue.Lines, text)
+				}
+				cues = append(cues, *cue)
+				cue = nil
+			}
+		}
+	}
+	if cues == nil {
+		return nil, fmt.Errorf("%w: no <p> elements", ErrSyntax)
+	}
+	return cues, nil
+}
+
+func ttmlCue(p xml.StartElement, index int) (Cue, error) {
+	cue := Cue{Index: index}
+	var begin, end, dur string
+	for _, a := range p.Attr {
+		switch a.Name.Local {
+		case "begin":
+			begin = a.Value
+		case "end":
+			end = a.Value
+		case "dur":
+			dur = a.Value
+		}
+	}
+	if begin == "" || end == "" && dur == "" {
+		return cue, fmt.Errorf("%w: <p> %d without begin and end", ErrSyntax, index)
+	}
+	var err error
+	if cue.Start, err = parseTTMLTime(begin); err != nil {
+		return cue, err
+	}
+	if end != "" {
+		cue.End, err = parseTTMLTime(end)
+	} else {
+		var d time.Duration
+		d, err = parseTTMLTime(dur)
+		cue.End = cue.Start + d
+	}
+	return cue, err
+}
+
+// WriteTTML writes cues as a TTML document with one <p> per cue.
+func WriteTTML(w io.Writer, cues []Cue) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(xml.Header)
+	bw.WriteString("<tt xmlns=\"http://www.w3.org/ns/ttml\">\n  <body>\n    <div>\n")
+	for _, c := range cues {
+		fmt.Fprintf(bw, "      <p begin=\"%s\" end=\"%s\">", FormatVTTTimestamp(c.Start), FormatVTTTimestamp(c.End))
+		for i, line := range c.Lines {
+			if i > 0 {
+				bw.WriteString("<br/>")
+			}
+			if err := xml.EscapeText(bw, []byte(line)); err != nil {
+				return err
+			}
+		}
+		bw.WriteString("</p>\n")
+	}
+	bw.WriteString("    </div>\n  </body>\n</tt>\n")
+	return bw.Flush()
+}
@@ -0,0 +1,85 @@
+package subtitle
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormatVTTTimestamp writes d as a WebVTT timestamp, 00:01:02.345.
+func FormatVTTTimestamp(d time.Duration) string {
+	return strings.Replace(FormatTimestamp(d), ",", ".", 1)
+}
+
+// ParseVTT reads WebVTT cues. NOTE, STYLE and REGION blocks are skipped,
+// cue settings after the end time are dropped and the text is kept as it
+// is, tags and all.
+func ParseVTT(r io.Reader) ([]Cue, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	lines := splitLines(string(content))
+	if len(lines) == 0 || !strings.HasPrefix(lines[0], "WEBVTT") {
+		return nil, syntaxError(1, "missing the WEBVTT header")
+	}
+	// the header runs to the first blank line
+	i := 1
+	for i < len(lines) && !isBlank(lines[i]) {
+		i++
+	}
+	var cues []Cue
+	for i < len(lines) {
+		if isBlank(lines[i]) {
+			i++
+			continue
+		}
+		block := i
+		for i < len(lines) && !isBlank(lines[i]) {
+			i++
+		}
+		first := strings.TrimSpace(lines[block])
+		if first == "NOTE" || strings.HasPrefix(first, "NOTE ") || first == "STYLE" || first == "REGION" {
+			continue
+		}
+		timing := block
+		if _, _, ok := parseTiming(lines[timing]); !ok {
+			timing++
+		}
+		if timing >= i {
+			return nil, syntaxError(block+1, "expected a cue timing, got %q", lines[block])
+		}
+		cue := Cue{Index: len(cues) + 1}
+		var ok bool
+		if cue.Start, cue.End, ok = parseTiming(lines[timing]); !ok {
+			return nil, syntaxError(timing+1, "expected a cue timing, got %q", lines[timing])
+		}
+		if timing > block {
+			if n, err := strconv.Atoi(strings.TrimSpace(lines[block])); err == nil {
+				cue.Index = n
+			}
+		}
+		for _, line := range lines[timing+1 : i] {
+			cue.Lines = append(cue.Lines, strings.TrimRight(line, " \t"))
+		}
+		cues = append(cues, cue)
+	}
+	return cues, nil
+}
+
+// WriteVTT writes cues as WebVTT, numbering them from 1.
+func WriteVTT(w io.Writer, cues []Cue) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("WEBVTT\n\n")
+	for i, c := range cues {
+		fmt.Fprintf(bw, "%d\n%s --> %s\n", i+1, FormatVTTTimestamp(c.Start), FormatVTTTimestamp(c.End))
+		for _, line := range c.Lines {
+			fmt.Fprintf(bw, "%s\n", line)
+		}
+		bw.WriteString("\n")
+	}
+	return bw.Flush()
+}
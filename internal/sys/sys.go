@@ -1,6 +1,7 @@
 package sys
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
 	"io/ioutil"
@@ -10,6 +11,7 @@ import (
 	"sort"
 	"strings"
 	"time"
+	"twsati/internal/plan"
 )
 
 type SortOrder uint
@@ -21,15 +23,42 @@ const (
 	NameDesc
 )
 
-func CheckErr(err error) {
-	if err != nil {
-		log.Panicf("%T: %s", err, err)
-		// panic(err)
-		// log.Fatal(err)
+var ErrUnknownSortOrder = errors.New("unknown sort order")
+
+// Ops changes the local filesystem, or only records the changes when
+// Plan is set. The zero value changes files directly.
+type Ops struct {
+	Plan *plan.Plan
+}
+
+func (o Ops) Rename(fromPath, toPath string) error {
+	if o.Plan.Record("fs", "rename", fromPath, "to "+toPath) {
+		return nil
+	}
+	return os.Rename(fromPath, toPath)
+}
+
+func (o Ops) MkdirAll(path string) error {
+	if o.Plan.Record("fs", "mkdir", path, "") {
+		return nil
 	}
+	return os.MkdirAll(path, os.ModePerm)
 }
 
-func CascadeRename(fromPath, toPath string) {
+func (o Ops) WriteFile(path string, content []byte) error {
+	if o.Plan.Record("fs", "write", path, fmt.Sprintf("%d bytes", len(content))) {
+		return nil
+	}
+	return os.WriteFile(path, content, 0660)
+}
+
+func CascadeRename(fromPath, toPath string) error {
+	return Ops{}.CascadeRename(fromPath, toPath)
+}
+
+// CascadeRename renames fromPath to toPath, first moving an existing
+// toPath aside with a timestamp suffix.
+func (o Ops) CascadeRename(fromPath, toPath string) error {
 	if _, err := os.Stat(toPath); !os.IsNotExist(err) {
 		//file exist
 		// fmt.Println("exist", fromPath, ":", toPath)
@@ -40,24 +69,33 @@ func CascadeRename(fromPath, toPath string) {
 		fileBase := strings.TrimSuffix(fileName, fileExt)
 		newFileName := fmt.Sprintf("%s%s%s", fileBase, suffix, fileExt)
 		newPath := filepath.Join(filepath.Dir(toPath), newFileName)
-		CascadeRename(toPath, newPath)
+		if err := o.CascadeRename(toPath, newPath); err != nil {
+			return err
+		}
+	}
+	if o.Plan.Record("fs", "rename", fromPath, "to "+toPath) {
+		return nil
 	}
 	time.Sleep(2 * time.Second)
 	fmt.Println(fromPath, "->", toPath)
-	err := os.Rename(fromPath, toPath)
-	CheckErr(err)
-	currentTime := time.Now().Local()
-	err = os.Chtimes(toPath, currentTime, currentTime)
-	if err != nil {
-		CheckErr(err)
+	if err := os.Rename(fromPath, toPath); err != nil {
+		return err
 	}
+	currentTime := time.Now().Local()
+	return os.Chtimes(toPath, currentTime, currentTime)
+}
 
+func NormalizeDir(root string, f fs.FileInfo) error {
+	return Ops{}.NormalizeDir(root, f)
 }
 
-func NormalizeDir(root string, f fs.FileInfo) {
+func (o Ops) NormalizeDir(root string, f fs.FileInfo) error {
 	baseName := f.Name()
 	basePath := filepath.Join(root, baseName)
-	baseContents := ListFilesSorted(filepath.Join(root, f.Name()), TimeDesc)
+	baseContents, err := ListFilesSorted(filepath.Join(root, f.Name()), TimeDesc)
+	if err != nil {
+		return err
+	}
 
 	var txtFs []string
 	var srtFs []string
@@ -94,27 +132,33 @@ func NormalizeDir(root string, f fs.FileInfo) {
 		return false
 	}
 
-	renameGroupF := func(files []string, ext string) {
+	renameGroupF := func(files []string, ext string) error {
 		if !changedF(files, ext) {
-			return
+			return nil
 		}
 		fmt.Println("changeset: ", files)
 
 		oldPath := filepath.Join(basePath, files[0])
 		newPath := filepath.Join(basePath, baseName+ext)
-		CascadeRename(oldPath, newPath)
+		return o.CascadeRename(oldPath, newPath)
 
 	}
 
-	renameGroupF(txtFs, ".txt")
-	renameGroupF(srtFs, ".srt")
-	renameGroupF(mp4Fs, ".mp4")
-	renameGroupF(mp3Fs, ".mp3")
-
+	for _, group := range []struct {
+		files []string
+		ext   string
+	}{{txtFs, ".txt"}, {srtFs, ".srt"}, {mp4Fs, ".mp4"}, {mp3Fs, ".mp3"}} {
+		if err := renameGroupF(group.files, group.ext); err != nil {
+			return err
+		}
+	}
+	return nil
 }
-func ListFilesSorted(path string, order SortOrder) []fs.FileInfo {
+func ListFilesSorted(path string, order SortOrder) ([]fs.FileInfo, error) {
 	files, err := ioutil.ReadDir(path)
-	CheckErr(err)
+	if err != nil {
+		return nil, err
+	}
 	var orderFunc func(int, int) bool
 
 	nameAsc := func(i, j int) bool {
@@ -133,8 +177,8 @@ func ListFilesSorted(path string, order SortOrder) []fs.FileInfo {
 	} else if order == TimeAsc {
 		orderFunc = timeAsc
 	} else {
-		log.Panic("unknown sort order")
+		return nil, ErrUnknownSortOrder
 	}
 	sort.Slice(files, orderFunc)
-	return files
+	return files, nil
 }
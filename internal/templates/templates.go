@@ -0,0 +1,108 @@
+// Package templates renders YouTube titles and descriptions from
+// text/template sources, with helpers for the formats our clips use.
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// TitleLimit is the longest title YouTube accepts, in characters.
+	TitleLimit = 100
+	// DescriptionLimit is the longest description YouTube accepts, in bytes.
+	DescriptionLimit = 5000
+)
+
+// Funcs are the helpers available to every template.
+var Funcs = template.FuncMap{
+	"zhDate":    ZhDate,
+	"timeRange": TimeRange,
+	"truncate":  Truncate,
+	"trim":      strings.TrimSpace,
+}
+
+// ZhDate formats t as 2006年01月02日.
+func ZhDate(t time.Time) string {
+	return t.Format("2006年01月02日")
+}
+
+// TimeRange formats a clip's start and end offsets as 37'34" ~ 38'51".
+func TimeRange(smin, ssec, emin, esec int) string {
+	return fmt.Sprintf("%02d'%02d\" ~ %02d'%02d\"", smin, ssec, emin, esec)
+}
+
+// Truncate shortens s to at most n characters, ending it with … when
+// something was cut.
+func Truncate(n int, s string) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	if n <= 0 {
+		return ""
+	}
+	runes := []rune(s)
+	return string(runes[:n-1]) + "…"
+}
+
+// truncateBytes cuts s to at most n bytes without splitting a character.
+func truncateBytes(n int, s string) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// Set is the title and description template of one series.
+type Set struct {
+	Name        string
+	title       *template.Template
+	description *template.Template
+}
+
+// Parse compiles the title and description sources of series name.
+func Parse(name, title, description string) (*Set, error) {
+	t, err := template.New(name + " title").Funcs(Funcs).Option("missingkey=error").Parse(title)
+	if err != nil {
+		return nil, err
+	}
+	d, err := template.New(name + " description").Funcs(Funcs).Option("missingkey=error").Parse(description)
+	if err != nil {
+		return nil, err
+	}
+	return &Set{Name: name, title: t, description: d}, nil
+}
+
+// Title renders the title for data, on one line and within TitleLimit.
+func (s *Set) Title(data interface{}) (string, error) {
+	str, err := execute(s.title, data)
+	if err != nil {
+		return "", err
+	}
+	str = strings.Join(strings.Fields(str), " ")
+	return Truncate(TitleLimit, str), nil
+}
+
+// Description renders the description for data within DescriptionLimit.
+func (s *Set) Description(data interface{}) (string, error) {
+	str, err := execute(s.description, data)
+	if err != nil {
+		return "", err
+	}
+	return truncateBytes(DescriptionLimit, str), nil
+}
+
+func execute(t *template.Template, data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
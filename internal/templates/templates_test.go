@@ -0,0 +1,65 @@
+package templates
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestHelpers(t *testing.T) {
+	if got := ZhDate(time.Date(2023, 1, 4, 0, 0, 0, 0, time.UTC)); got != "2023年01月04日" {
+		t.Errorf("ZhDate = %q", got)
+	}
+	if got := TimeRange(37, 4, 38, 51); got != `37'04" ~ 38'51"` {
+		t.Errorf("TimeRange = %q", got)
+	}
+	cases := []struct {
+		n       int
+		in, out string
+	}{
+		{5, "生命中別投降", "生命中別…"},
+		{6, "生命中別投降", "生命中別投降"},
+		{0, "abc", ""},
+	}
+	for _, c := range cases {
+		if got := Truncate(c.n, c.in); got != c.out {
+			t.Errorf("Truncate(%d, %q) = %q, want %q", c.n, c.in, got, c.out)
+		}
+	}
+}
+
+func TestSetLimits(t *testing.T) {
+	set, err := Parse("test", "{{.Title}}\n{{.Title}}", "{{.Body}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := map[string]string{"Title": strings.Repeat("長", 80), "Body": strings.Repeat("字", 2000)}
+	title, err := set.Title(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if utf8.RuneCountInString(title) != TitleLimit || strings.Contains(title, "\n") {
+		t.Errorf("title has %d characters: %q", utf8.RuneCountInString(title), title)
+	}
+	desc, err := set.Description(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(desc) > DescriptionLimit || !utf8.ValidString(desc) {
+		t.Errorf("description is %d bytes, valid utf8 %v", len(desc), utf8.ValidString(desc))
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	if _, err := Parse("bad", "{{.Title", ""); err == nil {
+		t.Error("expected a parse error")
+	}
+	set, err := Parse("missing", "{{.Nope}}", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := set.Title(map[string]string{}); err == nil {
+		t.Error("expected an error for a missing key")
+	}
+}